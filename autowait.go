@@ -0,0 +1,100 @@
+package selenium
+
+import (
+	"errors"
+	"time"
+)
+
+// autoWaitInterval is the time between actionability checks.
+const autoWaitInterval = 50 * time.Millisecond
+
+// AutoWait returns an option that makes the Click, SendKeys, Clear and Submit
+// methods of elements wait, for up to budget, until the element is
+// actionable. An element is actionable when it is attached to the document,
+// displayed, enabled, has the same bounding rectangle in two consecutive
+// animation frames, and is the target of a hit test at its center. Elements
+// outside the viewport are scrolled into view before the hit test.
+//
+// If the element does not become actionable in time, the method returns a
+// *TimeoutError whose message gives the last reason the element was not
+// actionable.
+func AutoWait(budget time.Duration) RemoteOption {
+	return func(wd *remoteWD) error {
+		if budget <= 0 {
+			return errors.New("selenium: the auto-wait budget must be positive")
+		}
+		wd.autoWait = budget
+		return nil
+	}
+}
+
+// actionabilityScript is an asynchronous script that calls back with the
+// reason its element argument is not actionable, or with an empty string if
+// it is.
+const actionabilityScript = `
+var e = arguments[0], done = arguments[arguments.length - 1];
+if (!e.isConnected) {
+	done('element is not attached to the document');
+	return;
+}
+var r = e.getBoundingClientRect();
+if (r.bottom < 0 || r.right < 0 || r.top > window.innerHeight || r.left > window.innerWidth) {
+	e.scrollIntoView({block: 'center', inline: 'center'});
+}
+window.requestAnimationFrame(function() {
+	var first = e.getBoundingClientRect();
+	window.requestAnimationFrame(function() {
+		var r = e.getBoundingClientRect();
+		if (r.left !== first.left || r.top !== first.top || r.width !== first.width || r.height !== first.height) {
+			done('element is moving');
+			return;
+		}
+		var hit = document.elementFromPoint(r.left + r.width / 2, r.top + r.height / 2);
+		while (hit && hit.shadowRoot && hit !== e) {
+			var inner = hit.shadowRoot.elementFromPoint(r.left + r.width / 2, r.top + r.height / 2);
+			if (!inner || inner === hit) {
+				break;
+			}
+			hit = inner;
+		}
+		if (!hit || (hit !== e && !e.contains(hit))) {
+			var desc = hit ? hit.tagName.toLowerCase() + (hit.id ? '#' + hit.id : '') : 'nothing';
+			done('element is obscured by ' + desc + ' at its center');
+			return;
+		}
+		done('');
+	});
+});
+`
+
+// waitActionable waits until the element is actionable, if the AutoWait
+// option was given.
+func (elem *remoteWE) waitActionable() error {
+	wd := elem.parent
+	if wd.autoWait <= 0 {
+		return nil
+	}
+
+	var reason string
+	err := NewWaiter(WaitTimeout(wd.autoWait), WaitInterval(autoWaitInterval)).Until(wd, func(WebDriver) (bool, error) {
+		if displayed, err := elem.IsDisplayed(); err != nil || !displayed {
+			reason = "element is not displayed"
+			return false, err
+		}
+		if enabled, err := elem.IsEnabled(); err != nil || !enabled {
+			reason = "element is not enabled"
+			return false, err
+		}
+		result, err := wd.ExecuteScriptAsync(actionabilityScript, []interface{}{elem})
+		if err != nil {
+			return false, err
+		}
+		reason, _ = result.(string)
+		return reason == "", nil
+	})
+	var te *TimeoutError
+	if errors.As(err, &te) {
+		te.Message = reason
+	}
+	return err
+}
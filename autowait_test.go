@@ -0,0 +1,80 @@
+package selenium
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// animatedButton is a button that is moving for the first few
+// actionability checks.
+type animatedButton struct {
+	moving  int
+	checks  int
+	clicked bool
+}
+
+// serve makes d reply to the commands on the button.
+func (b *animatedButton) serve(d *fakeDriver) {
+	d.reply("GET", "/displayed", true)
+	d.reply("GET", "/enabled", true)
+	d.handle("POST", "/execute/async", func(*fakeRequest) (interface{}, error) {
+		b.checks++
+		if b.checks <= b.moving {
+			return "element is moving", nil
+		}
+		return "", nil
+	})
+	d.handle("POST", "/click", func(*fakeRequest) (interface{}, error) {
+		b.clicked = true
+		return nil, nil
+	})
+}
+
+func TestAutoWait(t *testing.T) {
+	s := &animatedButton{moving: 2}
+	d := newFakeDriver(t)
+	s.serve(d)
+
+	wd := d.driver("")
+	if err := AutoWait(time.Second)(wd); err != nil {
+		t.Fatalf("AutoWait() returned error: %v", err)
+	}
+	button := &remoteWE{parent: wd, id: "button"}
+	if err := button.Click(); err != nil {
+		t.Fatalf("button.Click() returned error: %v", err)
+	}
+	if !s.clicked {
+		t.Errorf("button was not clicked")
+	}
+	if s.checks != 3 {
+		t.Errorf("button was checked %d times, want 3", s.checks)
+	}
+}
+
+func TestAutoWaitTimeout(t *testing.T) {
+	s := &animatedButton{moving: 1 << 30}
+	d := newFakeDriver(t)
+	s.serve(d)
+
+	wd := d.driver("")
+	if err := AutoWait(100 * time.Millisecond)(wd); err != nil {
+		t.Fatalf("AutoWait() returned error: %v", err)
+	}
+	button := &remoteWE{parent: wd, id: "button"}
+	err := button.Click()
+	var te *TimeoutError
+	if !errors.As(err, &te) {
+		t.Fatalf("button.Click() returned error %v, want a *TimeoutError", err)
+	}
+	if te.Message != "element is moving" {
+		t.Errorf("TimeoutError.Message = %q, want %q", te.Message, "element is moving")
+	}
+	if s.clicked {
+		t.Errorf("button was clicked while moving")
+	}
+
+	if err := AutoWait(0)(wd); err == nil {
+		t.Errorf("AutoWait(0) did not return an error")
+	}
+}
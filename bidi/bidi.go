@@ -0,0 +1,140 @@
+// Package bidi implements a client of the WebDriver BiDi protocol, which
+// exchanges commands and events with the browser over a WebSocket.
+//
+// A session is enabled for BiDi by setting the webSocketUrl capability with
+// AddCapability before creating it, and is connected to with Connect:
+//
+//	caps := selenium.Capabilities{"browserName": "firefox"}
+//	bidi.AddCapability(caps)
+//	wd, err := selenium.NewRemote(caps, "")
+//	...
+//	conn, err := bidi.Connect(wd)
+//	...
+//	defer conn.Close()
+//	conn.OnLoad(func(info bidi.NavigationInfo) { fmt.Println(info.URL) })
+//	err = conn.Subscribe(ctx, []string{bidi.EventLoad}, nil)
+//
+// The protocol is specified at https://w3c.github.io/webdriver-bidi/.
+package bidi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/tebeka/selenium"
+	"github.com/tebeka/selenium/internal/wsrpc"
+)
+
+// Capability is the capability that makes the server return the URL of the
+// BiDi connection of a session.
+const Capability = "webSocketUrl"
+
+// AddCapability requests the BiDi connection of the sessions created with
+// caps.
+func AddCapability(caps selenium.Capabilities) {
+	caps[Capability] = true
+}
+
+// ErrClosed is returned by the commands sent on a closed connection.
+var ErrClosed = errors.New("bidi: the connection is closed")
+
+// Error is an error returned by the remote end in reply to a command.
+type Error struct {
+	// Err is the error code, such as "no such frame".
+	Err string `json:"error"`
+	// Message is a detailed, human-readable message specific to the failure.
+	Message string `json:"message"`
+	// Stacktrace may contain the remote stacktrace where the error occurred.
+	Stacktrace string `json:"stacktrace"`
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Err, e.Message)
+}
+
+// Conn is a BiDi connection. It is safe for concurrent use.
+type Conn struct {
+	rpc *wsrpc.Conn
+}
+
+// Dial connects to the BiDi WebSocket at url.
+func Dial(url string) (*Conn, error) {
+	rpc, err := wsrpc.Dial(url, ErrClosed)
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{rpc: rpc}, nil
+}
+
+// Connect connects to the BiDi WebSocket of the session of wd, which must
+// have been created with the capability added by AddCapability.
+func Connect(wd selenium.WebDriver) (*Conn, error) {
+	url := selenium.WebSocketURL(wd)
+	if url == "" {
+		return nil, fmt.Errorf("%w: the session has no BiDi connection; the %s capability must be set", selenium.ErrUnsupported, Capability)
+	}
+	return Dial(url)
+}
+
+// Send sends the command method with the given parameters, which are encoded
+// as JSON and may be nil, and waits for its reply. If result is not nil, the
+// result of the command is decoded into it. Errors returned by the remote end
+// are of type *Error.
+func (c *Conn) Send(ctx context.Context, method string, params, result interface{}) error {
+	msg, err := c.rpc.Send(ctx, method, params)
+	if err != nil {
+		return err
+	}
+	var reply struct {
+		Type string `json:"type"`
+		Error
+	}
+	if err := json.Unmarshal(msg.Raw, &reply); err != nil {
+		return err
+	}
+	if reply.Err != "" || reply.Type == "error" {
+		return &reply.Error
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(msg.Result, result)
+}
+
+// On registers f to be called with the parameters of each event named
+// method, such as "browsingContext.load", and returns a function that
+// unregisters it. Events are only received after subscribing to them with
+// Subscribe. The handlers are called one at a time, in the order the events
+// arrive.
+func (c *Conn) On(method string, f func(params json.RawMessage)) (remove func()) {
+	return c.rpc.On(method, f)
+}
+
+// Close closes the connection. Commands waiting for their reply return
+// ErrClosed.
+func (c *Conn) Close() error {
+	return c.rpc.Close()
+}
+
+// Subscribe enables the events with the given names, or of the given modules
+// such as "browsingContext", in the browsing contexts with the given IDs and
+// their descendants, or in all of them if contexts is empty.
+func (c *Conn) Subscribe(ctx context.Context, events, contexts []string) error {
+	return c.Send(ctx, "session.subscribe", subscription(events, contexts), nil)
+}
+
+// Unsubscribe disables events enabled by Subscribe.
+func (c *Conn) Unsubscribe(ctx context.Context, events, contexts []string) error {
+	return c.Send(ctx, "session.unsubscribe", subscription(events, contexts), nil)
+}
+
+func subscription(events, contexts []string) map[string]interface{} {
+	params := map[string]interface{}{"events": events}
+	if len(contexts) > 0 {
+		params["contexts"] = contexts
+	}
+	return params
+}
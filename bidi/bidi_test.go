@@ -0,0 +1,253 @@
+package bidi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tebeka/selenium"
+	"golang.org/x/net/websocket"
+)
+
+// server is a local stand-in for the BiDi endpoint of a browser.
+type server struct {
+	t *testing.T
+
+	mu       sync.Mutex
+	commands []string
+}
+
+type command struct {
+	ID     int64                  `json:"id"`
+	Method string                 `json:"method"`
+	Params map[string]interface{} `json:"params"`
+}
+
+func (s *server) handle(ws *websocket.Conn) {
+	send := func(v interface{}) {
+		if err := websocket.JSON.Send(ws, v); err != nil {
+			s.t.Errorf("Sending %v returned error: %v", v, err)
+		}
+	}
+	for {
+		var cmd command
+		if err := websocket.JSON.Receive(ws, &cmd); err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.commands = append(s.commands, cmd.Method)
+		s.mu.Unlock()
+
+		success := func(result interface{}) {
+			send(map[string]interface{}{"type": "success", "id": cmd.ID, "result": result})
+		}
+		switch cmd.Method {
+		case "session.subscribe":
+			success(map[string]interface{}{})
+			send(map[string]interface{}{
+				"type":   "event",
+				"method": EventLoad,
+				"params": map[string]interface{}{"context": "c1", "navigation": "n1", "timestamp": 42, "url": "http://example.com/"},
+			})
+		case "browsingContext.getTree":
+			success(map[string]interface{}{"contexts": []interface{}{
+				map[string]interface{}{
+					"context":  "c1",
+					"url":      "about:blank",
+					"children": []interface{}{map[string]interface{}{"context": "f1", "url": "about:blank", "parent": "c1"}},
+				},
+			}})
+		case "browsingContext.navigate":
+			success(map[string]interface{}{"navigation": "n2", "url": cmd.Params["url"]})
+		case "test.hang":
+		default:
+			send(map[string]interface{}{"type": "error", "id": cmd.ID, "error": "unknown command", "message": cmd.Method})
+		}
+	}
+}
+
+func (s *server) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+func newServer(t *testing.T) (*server, string, func()) {
+	s := &server{t: t}
+	hs := httptest.NewServer(websocket.Handler(s.handle))
+	return s, "ws" + strings.TrimPrefix(hs.URL, "http"), hs.Close
+}
+
+func TestCommands(t *testing.T) {
+	s, url, stop := newServer(t)
+	defer stop()
+	c, err := Dial(url)
+	if err != nil {
+		t.Fatalf("Dial(%q) returned error: %v", url, err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+
+	tree, err := c.GetTree(ctx, "", -1)
+	if err != nil {
+		t.Fatalf("GetTree() returned error: %v", err)
+	}
+	if len(tree) != 1 || tree[0].Context != "c1" || len(tree[0].Children) != 1 || tree[0].Children[0].Parent != "c1" {
+		t.Errorf("GetTree() = %+v, want a context with a frame", tree)
+	}
+
+	nav, err := c.Navigate(ctx, "c1", "http://example.com/", ReadinessComplete)
+	if err != nil {
+		t.Fatalf("Navigate() returned error: %v", err)
+	}
+	if nav.Navigation != "n2" || nav.URL != "http://example.com/" {
+		t.Errorf("Navigate() = %+v", nav)
+	}
+
+	err = c.Activate(ctx, "c1")
+	var e *Error
+	if !errors.As(err, &e) || e.Err != "unknown command" || e.Message != "browsingContext.activate" {
+		t.Errorf("Activate() returned error %v, want an unknown command error", err)
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := c.Send(timeout, "test.hang", nil, nil); err != context.DeadlineExceeded {
+		t.Errorf("Send() of an unanswered command returned error %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	want := []string{"browsingContext.getTree", "browsingContext.navigate", "browsingContext.activate", "test.hang"}
+	if got := s.received(); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("the server received %q, want %q", got, want)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	_, url, stop := newServer(t)
+	defer stop()
+	c, err := Dial(url)
+	if err != nil {
+		t.Fatalf("Dial(%q) returned error: %v", url, err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+
+	loads := make(chan NavigationInfo, 2)
+	c.OnLoad(func(info NavigationInfo) {
+		// Handlers may send commands.
+		if _, err := c.GetTree(ctx, "", 0); err != nil {
+			t.Errorf("GetTree() in an event handler returned error: %v", err)
+		}
+		loads <- info
+	})
+	removed := c.OnLoad(func(NavigationInfo) {
+		t.Errorf("a removed handler was called")
+	})
+	removed()
+
+	if err := c.Subscribe(ctx, []string{EventLoad}, nil); err != nil {
+		t.Fatalf("Subscribe() returned error: %v", err)
+	}
+	select {
+	case info := <-loads:
+		want := NavigationInfo{Context: "c1", Navigation: "n1", Timestamp: 42, URL: "http://example.com/"}
+		if info != want {
+			t.Errorf("the load event is %+v, want %+v", info, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the load event was not dispatched")
+	}
+}
+
+func TestClose(t *testing.T) {
+	_, url, stop := newServer(t)
+	defer stop()
+	c, err := Dial(url)
+	if err != nil {
+		t.Fatalf("Dial(%q) returned error: %v", url, err)
+	}
+
+	errs := make(chan error)
+	go func() { errs <- c.Send(context.Background(), "test.hang", nil, nil) }()
+	time.Sleep(20 * time.Millisecond)
+	c.Close()
+	select {
+	case err := <-errs:
+		if err != ErrClosed {
+			t.Errorf("Send() when the connection is closed returned error %v, want %v", err, ErrClosed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Send() did not return when the connection was closed")
+	}
+	if err := c.Send(context.Background(), "session.status", nil, nil); err != ErrClosed {
+		t.Errorf("Send() on a closed connection returned error %v, want %v", err, ErrClosed)
+	}
+}
+
+type fakeDriver struct {
+	selenium.WebDriver
+	url string
+}
+
+func (wd fakeDriver) WebSocketURL() string { return wd.url }
+
+func TestConnect(t *testing.T) {
+	if _, err := Connect(fakeDriver{}); !errors.Is(err, selenium.ErrUnsupported) {
+		t.Errorf("Connect() to a session without BiDi returned error %v, want ErrUnsupported", err)
+	}
+
+	_, url, stop := newServer(t)
+	defer stop()
+	c, err := Connect(fakeDriver{url: url})
+	if err != nil {
+		t.Fatalf("Connect() returned error: %v", err)
+	}
+	c.Close()
+}
+
+func TestNewRemote(t *testing.T) {
+	_, url, stop := newServer(t)
+	defer stop()
+	var requested interface{}
+	hs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Capabilities struct {
+				AlwaysMatch map[string]interface{}
+			}
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Decoding the new session request returned error: %v", err)
+		}
+		requested = body.Capabilities.AlwaysMatch[Capability]
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"value": map[string]interface{}{
+			"sessionId":    "s",
+			"capabilities": map[string]interface{}{"browserName": "firefox", "webSocketUrl": url},
+		}})
+	}))
+	defer hs.Close()
+
+	caps := selenium.Capabilities{"browserName": "firefox"}
+	AddCapability(caps)
+	wd, err := selenium.NewRemote(caps, hs.URL)
+	if err != nil {
+		t.Fatalf("selenium.NewRemote() returned error: %v", err)
+	}
+	if requested != true {
+		t.Errorf("the %s capability requested is %v, want true", Capability, requested)
+	}
+	if got := selenium.WebSocketURL(wd); got != url {
+		t.Errorf("WebSocketURL() = %q, want %q", got, url)
+	}
+	c, err := Connect(wd)
+	if err != nil {
+		t.Fatalf("Connect() returned error: %v", err)
+	}
+	c.Close()
+}
@@ -0,0 +1,195 @@
+package bidi
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Names of the events of the browsingContext module.
+const (
+	EventContextCreated    = "browsingContext.contextCreated"
+	EventContextDestroyed  = "browsingContext.contextDestroyed"
+	EventNavigationStarted = "browsingContext.navigationStarted"
+	EventFragmentNavigated = "browsingContext.fragmentNavigated"
+	EventDOMContentLoaded  = "browsingContext.domContentLoaded"
+	EventLoad              = "browsingContext.load"
+	EventUserPromptOpened  = "browsingContext.userPromptOpened"
+	EventUserPromptClosed  = "browsingContext.userPromptClosed"
+)
+
+// ContextInfo describes a browsing context, i.e. a tab, window or frame.
+type ContextInfo struct {
+	// Context is the ID of the browsing context.
+	Context string `json:"context"`
+	URL     string `json:"url"`
+	// Parent is the ID of the parent of a frame.
+	Parent   string        `json:"parent,omitempty"`
+	Children []ContextInfo `json:"children"`
+}
+
+// NavigationInfo describes a navigation of a browsing context.
+type NavigationInfo struct {
+	Context    string `json:"context"`
+	Navigation string `json:"navigation"`
+	// Timestamp is the time of the event in milliseconds since the epoch.
+	Timestamp int64  `json:"timestamp"`
+	URL       string `json:"url"`
+}
+
+// ContextType is the type of a browsing context created by CreateContext.
+type ContextType string
+
+// Types of browsing contexts.
+const (
+	Tab    ContextType = "tab"
+	Window ContextType = "window"
+)
+
+// ReadinessState is the state of the document that Navigate and Reload wait
+// for.
+type ReadinessState string
+
+// Readiness states.
+const (
+	// ReadinessNone returns as soon as the navigation starts.
+	ReadinessNone ReadinessState = "none"
+	// ReadinessInteractive waits until the document is parsed.
+	ReadinessInteractive ReadinessState = "interactive"
+	// ReadinessComplete waits until the document and its resources are
+	// loaded.
+	ReadinessComplete ReadinessState = "complete"
+)
+
+// GetTree returns the top-level browsing contexts, or the context with the
+// ID root if it is not empty, with their descendants up to maxDepth levels
+// below them. A negative maxDepth returns all of them.
+func (c *Conn) GetTree(ctx context.Context, root string, maxDepth int) ([]ContextInfo, error) {
+	params := make(map[string]interface{})
+	if root != "" {
+		params["root"] = root
+	}
+	if maxDepth >= 0 {
+		params["maxDepth"] = maxDepth
+	}
+	reply := new(struct {
+		Contexts []ContextInfo `json:"contexts"`
+	})
+	if err := c.Send(ctx, "browsingContext.getTree", params, reply); err != nil {
+		return nil, err
+	}
+	return reply.Contexts, nil
+}
+
+// CreateContext opens a tab or a window and returns the ID of its browsing
+// context.
+func (c *Conn) CreateContext(ctx context.Context, typ ContextType) (string, error) {
+	reply := new(struct {
+		Context string `json:"context"`
+	})
+	if err := c.Send(ctx, "browsingContext.create", map[string]interface{}{"type": typ}, reply); err != nil {
+		return "", err
+	}
+	return reply.Context, nil
+}
+
+// CloseContext closes the top-level browsing context id.
+func (c *Conn) CloseContext(ctx context.Context, id string) error {
+	return c.Send(ctx, "browsingContext.close", map[string]interface{}{"context": id}, nil)
+}
+
+// Activate brings the top-level browsing context id to the foreground.
+func (c *Conn) Activate(ctx context.Context, id string) error {
+	return c.Send(ctx, "browsingContext.activate", map[string]interface{}{"context": id}, nil)
+}
+
+// Navigation is the result of Navigate and Reload.
+type Navigation struct {
+	// Navigation is the ID of the navigation, as found in the NavigationInfo
+	// of its events. It is empty if the document did not change.
+	Navigation string `json:"navigation"`
+	URL        string `json:"url"`
+}
+
+// Navigate navigates the browsing context id to url, and waits until its
+// document reaches the state wait.
+func (c *Conn) Navigate(ctx context.Context, id, url string, wait ReadinessState) (*Navigation, error) {
+	params := map[string]interface{}{"context": id, "url": url}
+	if wait != "" {
+		params["wait"] = wait
+	}
+	nav := new(Navigation)
+	if err := c.Send(ctx, "browsingContext.navigate", params, nav); err != nil {
+		return nil, err
+	}
+	return nav, nil
+}
+
+// Reload reloads the browsing context id, and waits until its document
+// reaches the state wait.
+func (c *Conn) Reload(ctx context.Context, id string, wait ReadinessState) (*Navigation, error) {
+	params := map[string]interface{}{"context": id}
+	if wait != "" {
+		params["wait"] = wait
+	}
+	nav := new(Navigation)
+	if err := c.Send(ctx, "browsingContext.reload", params, nav); err != nil {
+		return nil, err
+	}
+	return nav, nil
+}
+
+// onNavigation registers f for the navigation event method.
+func (c *Conn) onNavigation(method string, f func(NavigationInfo)) func() {
+	return c.On(method, func(params json.RawMessage) {
+		var info NavigationInfo
+		if err := json.Unmarshal(params, &info); err == nil {
+			f(info)
+		}
+	})
+}
+
+// onContext registers f for the browsing context event method.
+func (c *Conn) onContext(method string, f func(ContextInfo)) func() {
+	return c.On(method, func(params json.RawMessage) {
+		var info ContextInfo
+		if err := json.Unmarshal(params, &info); err == nil {
+			f(info)
+		}
+	})
+}
+
+// OnContextCreated registers f to be called when a browsing context is
+// created, and returns a function that unregisters it.
+func (c *Conn) OnContextCreated(f func(ContextInfo)) (remove func()) {
+	return c.onContext(EventContextCreated, f)
+}
+
+// OnContextDestroyed registers f to be called when a browsing context is
+// destroyed, and returns a function that unregisters it.
+func (c *Conn) OnContextDestroyed(f func(ContextInfo)) (remove func()) {
+	return c.onContext(EventContextDestroyed, f)
+}
+
+// OnNavigationStarted registers f to be called when a navigation starts, and
+// returns a function that unregisters it.
+func (c *Conn) OnNavigationStarted(f func(NavigationInfo)) (remove func()) {
+	return c.onNavigation(EventNavigationStarted, f)
+}
+
+// OnFragmentNavigated registers f to be called when the fragment of the URL
+// of a document changes, and returns a function that unregisters it.
+func (c *Conn) OnFragmentNavigated(f func(NavigationInfo)) (remove func()) {
+	return c.onNavigation(EventFragmentNavigated, f)
+}
+
+// OnDOMContentLoaded registers f to be called when a document is parsed, and
+// returns a function that unregisters it.
+func (c *Conn) OnDOMContentLoaded(f func(NavigationInfo)) (remove func()) {
+	return c.onNavigation(EventDOMContentLoaded, f)
+}
+
+// OnLoad registers f to be called when a document and its resources are
+// loaded, and returns a function that unregisters it.
+func (c *Conn) OnLoad(f func(NavigationInfo)) (remove func()) {
+	return c.onNavigation(EventLoad, f)
+}
@@ -0,0 +1,67 @@
+package bidi
+
+import "encoding/json"
+
+// EventLogEntryAdded is the name of the event of the log module, which is
+// emitted for console messages and uncaught JavaScript exceptions.
+const EventLogEntryAdded = "log.entryAdded"
+
+// Types of log entries.
+const (
+	// LogConsole entries are written with the console API.
+	LogConsole = "console"
+	// LogJavaScript entries are uncaught JavaScript exceptions.
+	LogJavaScript = "javascript"
+)
+
+// Levels of log entries.
+const (
+	LogDebug = "debug"
+	LogInfo  = "info"
+	LogWarn  = "warn"
+	LogError = "error"
+)
+
+// LogEntry is a console message or an uncaught JavaScript exception.
+type LogEntry struct {
+	// Type is LogConsole, LogJavaScript or a browser-specific type.
+	Type string `json:"type"`
+	// Level is one of LogDebug, LogInfo, LogWarn and LogError.
+	Level string `json:"level"`
+	Text  string `json:"text"`
+	// Timestamp is the time of the entry in milliseconds since the epoch.
+	Timestamp int64 `json:"timestamp"`
+	// Method is the console method of console entries, such as "log" or
+	// "error".
+	Method string `json:"method,omitempty"`
+	Source struct {
+		Realm string `json:"realm"`
+		// Context is the ID of the browsing context of the entry.
+		Context string `json:"context,omitempty"`
+	} `json:"source"`
+	StackTrace *StackTrace `json:"stackTrace,omitempty"`
+}
+
+// StackTrace is the JavaScript stack of a log entry.
+type StackTrace struct {
+	CallFrames []StackFrame `json:"callFrames"`
+}
+
+// StackFrame is a frame of a StackTrace. Lines and columns are zero-based.
+type StackFrame struct {
+	FunctionName string `json:"functionName"`
+	URL          string `json:"url"`
+	LineNumber   int    `json:"lineNumber"`
+	ColumnNumber int    `json:"columnNumber"`
+}
+
+// OnLogEntry registers f to be called for each log entry, and returns a
+// function that unregisters it.
+func (c *Conn) OnLogEntry(f func(LogEntry)) (remove func()) {
+	return c.On(EventLogEntryAdded, func(params json.RawMessage) {
+		var e LogEntry
+		if err := json.Unmarshal(params, &e); err == nil {
+			f(e)
+		}
+	})
+}
@@ -0,0 +1,184 @@
+package bidi
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+)
+
+// Names of the events of the network module.
+const (
+	EventBeforeRequestSent = "network.beforeRequestSent"
+	EventResponseStarted   = "network.responseStarted"
+	EventResponseCompleted = "network.responseCompleted"
+	EventFetchError        = "network.fetchError"
+)
+
+// Phases of a request in which it can be intercepted.
+const (
+	PhaseBeforeRequestSent = "beforeRequestSent"
+	PhaseResponseStarted   = "responseStarted"
+	PhaseAuthRequired      = "authRequired"
+)
+
+// BytesValue is a string or binary value, such as a header value or a body.
+type BytesValue struct {
+	// Type is "string" or "base64".
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// StringValue returns a BytesValue holding s.
+func StringValue(s string) BytesValue {
+	return BytesValue{Type: "string", Value: s}
+}
+
+// Base64Value returns a BytesValue holding b.
+func Base64Value(b []byte) BytesValue {
+	return BytesValue{Type: "base64", Value: base64.StdEncoding.EncodeToString(b)}
+}
+
+// Bytes returns the value held by v.
+func (v BytesValue) Bytes() ([]byte, error) {
+	if v.Type == "base64" {
+		return base64.StdEncoding.DecodeString(v.Value)
+	}
+	return []byte(v.Value), nil
+}
+
+// Header is an HTTP header.
+type Header struct {
+	Name  string     `json:"name"`
+	Value BytesValue `json:"value"`
+}
+
+// FetchTimingInfo holds the timings of a request, in milliseconds since the
+// time origin of the page.
+type FetchTimingInfo struct {
+	TimeOrigin    float64 `json:"timeOrigin"`
+	RequestTime   float64 `json:"requestTime"`
+	RedirectStart float64 `json:"redirectStart"`
+	RedirectEnd   float64 `json:"redirectEnd"`
+	FetchStart    float64 `json:"fetchStart"`
+	DNSStart      float64 `json:"dnsStart"`
+	DNSEnd        float64 `json:"dnsEnd"`
+	ConnectStart  float64 `json:"connectStart"`
+	ConnectEnd    float64 `json:"connectEnd"`
+	TLSStart      float64 `json:"tlsStart"`
+	RequestStart  float64 `json:"requestStart"`
+	ResponseStart float64 `json:"responseStart"`
+	ResponseEnd   float64 `json:"responseEnd"`
+}
+
+// RequestData describes a request.
+type RequestData struct {
+	// Request is the ID of the request.
+	Request     string          `json:"request"`
+	URL         string          `json:"url"`
+	Method      string          `json:"method"`
+	Headers     []Header        `json:"headers"`
+	HeadersSize int64           `json:"headersSize"`
+	BodySize    *int64          `json:"bodySize"`
+	Timings     FetchTimingInfo `json:"timings"`
+}
+
+// ResponseData describes a response.
+type ResponseData struct {
+	URL           string   `json:"url"`
+	Protocol      string   `json:"protocol"`
+	Status        int      `json:"status"`
+	StatusText    string   `json:"statusText"`
+	FromCache     bool     `json:"fromCache"`
+	Headers       []Header `json:"headers"`
+	MIMEType      string   `json:"mimeType"`
+	BytesReceived int64    `json:"bytesReceived"`
+	HeadersSize   *int64   `json:"headersSize"`
+	BodySize      *int64   `json:"bodySize"`
+}
+
+// NetworkEvent holds the parameters of the events of the network module.
+type NetworkEvent struct {
+	Context    string `json:"context"`
+	Navigation string `json:"navigation"`
+	// IsBlocked is set if the request is paused by an intercept, in which
+	// case it must be resumed with ContinueRequest, ProvideResponse or
+	// FailRequest.
+	IsBlocked     bool        `json:"isBlocked"`
+	Intercepts    []string    `json:"intercepts"`
+	RedirectCount int         `json:"redirectCount"`
+	Request       RequestData `json:"request"`
+	// Timestamp is the time of the event in milliseconds since the epoch.
+	Timestamp int64 `json:"timestamp"`
+	// Response is set in the events that follow the response.
+	Response *ResponseData `json:"response,omitempty"`
+	// ErrorText is set in the network.fetchError event.
+	ErrorText string `json:"errorText,omitempty"`
+}
+
+// OnNetworkEvent registers f to be called for each network event named
+// method, such as EventBeforeRequestSent, and returns a function that
+// unregisters it.
+func (c *Conn) OnNetworkEvent(method string, f func(NetworkEvent)) (remove func()) {
+	return c.On(method, func(params json.RawMessage) {
+		var e NetworkEvent
+		if err := json.Unmarshal(params, &e); err == nil {
+			f(e)
+		}
+	})
+}
+
+// AddIntercept pauses the requests in the given phases, and returns the ID of
+// the intercept. The paused requests are reported by network events whose
+// IsBlocked field is set. If urls is not empty, only the requests to these
+// URLs are paused.
+func (c *Conn) AddIntercept(ctx context.Context, phases, urls []string) (string, error) {
+	params := map[string]interface{}{"phases": phases}
+	if len(urls) > 0 {
+		patterns := make([]map[string]string, len(urls))
+		for i, u := range urls {
+			patterns[i] = map[string]string{"type": "string", "pattern": u}
+		}
+		params["urlPatterns"] = patterns
+	}
+	reply := new(struct {
+		Intercept string `json:"intercept"`
+	})
+	if err := c.Send(ctx, "network.addIntercept", params, reply); err != nil {
+		return "", err
+	}
+	return reply.Intercept, nil
+}
+
+// RemoveIntercept removes the intercept with the given ID.
+func (c *Conn) RemoveIntercept(ctx context.Context, intercept string) error {
+	return c.Send(ctx, "network.removeIntercept", map[string]string{"intercept": intercept}, nil)
+}
+
+// ContinueRequest resumes a paused request. If headers is not nil, they
+// replace the headers of the request.
+func (c *Conn) ContinueRequest(ctx context.Context, request string, headers []Header) error {
+	params := map[string]interface{}{"request": request}
+	if headers != nil {
+		params["headers"] = headers
+	}
+	return c.Send(ctx, "network.continueRequest", params, nil)
+}
+
+// ProvideResponse completes a paused request with the given response instead
+// of sending it.
+func (c *Conn) ProvideResponse(ctx context.Context, request string, status int, headers []Header, body []byte) error {
+	params := map[string]interface{}{
+		"request":    request,
+		"statusCode": status,
+		"body":       Base64Value(body),
+	}
+	if headers != nil {
+		params["headers"] = headers
+	}
+	return c.Send(ctx, "network.provideResponse", params, nil)
+}
+
+// FailRequest fails a paused request with a network error.
+func (c *Conn) FailRequest(ctx context.Context, request string) error {
+	return c.Send(ctx, "network.failRequest", map[string]string{"request": request}, nil)
+}
@@ -0,0 +1,77 @@
+package selenium
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrUnsupported is wrapped by the errors returned by commands that the
+// browser of the session does not support.
+var ErrUnsupported = errors.New("selenium: unsupported by the browser")
+
+// cdpVendor returns the vendor prefix of the commands through which the
+// driver forwards Chrome DevTools Protocol commands, or an empty string if
+// the browser is not based on Chromium.
+func (wd *remoteWD) cdpVendor() string {
+	switch wd.browser {
+	case "chrome", "chromium":
+		return "goog"
+	case "MicrosoftEdge", "msedge":
+		return "ms"
+	}
+	return ""
+}
+
+// executeCDP sends a Chrome DevTools Protocol command through the driver and
+// returns its result.
+func (wd *remoteWD) executeCDP(method string, params interface{}) (json.RawMessage, error) {
+	if params == nil {
+		params = make(map[string]interface{})
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"cmd":    method,
+		"params": params,
+	})
+	if err != nil {
+		return nil, err
+	}
+	url := wd.requestURL("/session/%s/%s/cdp/execute", wd.id, wd.cdpVendor())
+	response, err := wd.execute("POST", url, data)
+	if err != nil {
+		return nil, err
+	}
+	reply := new(struct{ Value json.RawMessage })
+	if err := json.Unmarshal(response, reply); err != nil {
+		return nil, err
+	}
+	return reply.Value, nil
+}
+
+// CDPExecutor sends Chrome DevTools Protocol commands through the driver.
+type CDPExecutor interface {
+	// ExecuteCDP sends a Chrome DevTools Protocol command, such as
+	// "Network.setExtraHTTPHeaders", with the given parameters and returns
+	// its result. The parameters are encoded as JSON and may be nil. It
+	// returns an error wrapping ErrUnsupported if the browser is not based on
+	// Chromium.
+	ExecuteCDP(method string, params interface{}) (json.RawMessage, error)
+}
+
+// ExecuteCDP sends a Chrome DevTools Protocol command through wd, as
+// described by CDPExecutor. It returns an error wrapping ErrUnsupported if
+// wd does not implement CDPExecutor.
+func ExecuteCDP(wd WebDriver, method string, params interface{}) (json.RawMessage, error) {
+	e, ok := wd.(CDPExecutor)
+	if !ok {
+		return nil, fmt.Errorf("%w: the WebDriver %T cannot send Chrome DevTools Protocol commands", ErrUnsupported, wd)
+	}
+	return e.ExecuteCDP(method, params)
+}
+
+func (wd *remoteWD) ExecuteCDP(method string, params interface{}) (json.RawMessage, error) {
+	if wd.cdpVendor() == "" {
+		return nil, fmt.Errorf("%w: Chrome DevTools Protocol commands require a Chromium-based browser, not %q", ErrUnsupported, wd.browser)
+	}
+	return wd.executeCDP(method, params)
+}
@@ -0,0 +1,71 @@
+// Package cdp provides typed helpers for common Chrome DevTools Protocol
+// commands, which are sent with selenium.ExecuteCDP. The helpers
+// return an error wrapping selenium.ErrUnsupported if the browser is not
+// based on Chromium.
+//
+// Events, which ExecuteCDP cannot receive, are received by a Conn to the
+// DevTools WebSocket of the page, as returned by Connect.
+//
+// The Chrome DevTools Protocol is documented at
+// https://chromedevtools.github.io/devtools-protocol/.
+package cdp
+
+import (
+	"encoding/json"
+
+	"github.com/tebeka/selenium"
+)
+
+// SetExtraHTTPHeaders makes the browser send headers with each of its
+// requests, in addition to the headers it sends by default. It replaces the
+// headers set by previous calls; an empty map removes them.
+func SetExtraHTTPHeaders(wd selenium.WebDriver, headers map[string]string) error {
+	// The Network domain must be enabled for the headers to be sent.
+	if _, err := selenium.ExecuteCDP(wd, "Network.enable", nil); err != nil {
+		return err
+	}
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	_, err := selenium.ExecuteCDP(wd, "Network.setExtraHTTPHeaders", map[string]interface{}{
+		"headers": headers,
+	})
+	return err
+}
+
+// SetTimezoneOverride makes the pages see the time zone with the given IANA
+// ID, such as "Europe/Paris". An empty ID restores the time zone of the
+// system.
+func SetTimezoneOverride(wd selenium.WebDriver, timezoneID string) error {
+	_, err := selenium.ExecuteCDP(wd, "Emulation.setTimezoneOverride", map[string]string{
+		"timezoneId": timezoneID,
+	})
+	return err
+}
+
+// AddScriptToEvaluateOnNewDocument makes the browser evaluate the JavaScript
+// source in each frame it creates, before any script of the frame. It
+// returns the identifier of the script, which RemoveScriptToEvaluateOnNewDocument
+// accepts.
+func AddScriptToEvaluateOnNewDocument(wd selenium.WebDriver, source string) (string, error) {
+	result, err := selenium.ExecuteCDP(wd, "Page.addScriptToEvaluateOnNewDocument", map[string]string{
+		"source": source,
+	})
+	if err != nil {
+		return "", err
+	}
+	reply := new(struct{ Identifier string })
+	if err := json.Unmarshal(result, reply); err != nil {
+		return "", err
+	}
+	return reply.Identifier, nil
+}
+
+// RemoveScriptToEvaluateOnNewDocument stops the evaluation of the script
+// with the identifier returned by AddScriptToEvaluateOnNewDocument.
+func RemoveScriptToEvaluateOnNewDocument(wd selenium.WebDriver, identifier string) error {
+	_, err := selenium.ExecuteCDP(wd, "Page.removeScriptToEvaluateOnNewDocument", map[string]string{
+		"identifier": identifier,
+	})
+	return err
+}
@@ -0,0 +1,117 @@
+package cdp
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tebeka/selenium"
+)
+
+type command struct {
+	Method string
+	Params string
+}
+
+// fakeDriver records the CDP commands it receives and replies to them with
+// the results in replies, keyed by method.
+type fakeDriver struct {
+	selenium.WebDriver
+	commands []command
+	replies  map[string]string
+	caps     selenium.Capabilities
+	handle   string
+}
+
+func (wd *fakeDriver) Capabilities() (selenium.Capabilities, error) {
+	return wd.caps, nil
+}
+
+func (wd *fakeDriver) CurrentWindowHandle() (string, error) {
+	return wd.handle, nil
+}
+
+func (wd *fakeDriver) ExecuteCDP(method string, params interface{}) (json.RawMessage, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	wd.commands = append(wd.commands, command{method, string(data)})
+	reply, ok := wd.replies[method]
+	if !ok {
+		reply = "{}"
+	}
+	return json.RawMessage(reply), nil
+}
+
+func TestSetExtraHTTPHeaders(t *testing.T) {
+	wd := &fakeDriver{}
+	if err := SetExtraHTTPHeaders(wd, map[string]string{"X-Test": "1"}); err != nil {
+		t.Fatalf("SetExtraHTTPHeaders() returned error: %v", err)
+	}
+	if err := SetExtraHTTPHeaders(wd, nil); err != nil {
+		t.Fatalf("SetExtraHTTPHeaders() returned error: %v", err)
+	}
+	want := []command{
+		{"Network.enable", "null"},
+		{"Network.setExtraHTTPHeaders", `{"headers":{"X-Test":"1"}}`},
+		{"Network.enable", "null"},
+		{"Network.setExtraHTTPHeaders", `{"headers":{}}`},
+	}
+	if diff := cmp.Diff(want, wd.commands); diff != "" {
+		t.Errorf("SetExtraHTTPHeaders() sent different commands (-want +got):\n%s", diff)
+	}
+}
+
+func TestSetTimezoneOverride(t *testing.T) {
+	wd := &fakeDriver{}
+	if err := SetTimezoneOverride(wd, "Europe/Paris"); err != nil {
+		t.Fatalf("SetTimezoneOverride() returned error: %v", err)
+	}
+	want := []command{{"Emulation.setTimezoneOverride", `{"timezoneId":"Europe/Paris"}`}}
+	if diff := cmp.Diff(want, wd.commands); diff != "" {
+		t.Errorf("SetTimezoneOverride() sent different commands (-want +got):\n%s", diff)
+	}
+}
+
+func TestScriptToEvaluateOnNewDocument(t *testing.T) {
+	wd := &fakeDriver{replies: map[string]string{
+		"Page.addScriptToEvaluateOnNewDocument": `{"identifier":"7"}`,
+	}}
+	id, err := AddScriptToEvaluateOnNewDocument(wd, "window.x = 1;")
+	if err != nil {
+		t.Fatalf("AddScriptToEvaluateOnNewDocument() returned error: %v", err)
+	}
+	if id != "7" {
+		t.Errorf("AddScriptToEvaluateOnNewDocument() = %q, want %q", id, "7")
+	}
+	if err := RemoveScriptToEvaluateOnNewDocument(wd, id); err != nil {
+		t.Fatalf("RemoveScriptToEvaluateOnNewDocument() returned error: %v", err)
+	}
+	want := []command{
+		{"Page.addScriptToEvaluateOnNewDocument", `{"source":"window.x = 1;"}`},
+		{"Page.removeScriptToEvaluateOnNewDocument", `{"identifier":"7"}`},
+	}
+	if diff := cmp.Diff(want, wd.commands); diff != "" {
+		t.Errorf("the commands sent differ (-want +got):\n%s", diff)
+	}
+}
+
+// unsupportedDriver is a session of a browser that is not based on Chromium.
+type unsupportedDriver struct {
+	selenium.WebDriver
+}
+
+func (unsupportedDriver) ExecuteCDP(string, interface{}) (json.RawMessage, error) {
+	return nil, selenium.ErrUnsupported
+}
+
+func TestUnsupported(t *testing.T) {
+	if err := SetTimezoneOverride(unsupportedDriver{}, "UTC"); !errors.Is(err, selenium.ErrUnsupported) {
+		t.Errorf("SetTimezoneOverride() returned error %v, want ErrUnsupported", err)
+	}
+	if _, err := AddScriptToEvaluateOnNewDocument(unsupportedDriver{}, ""); !errors.Is(err, selenium.ErrUnsupported) {
+		t.Errorf("AddScriptToEvaluateOnNewDocument() returned error %v, want ErrUnsupported", err)
+	}
+}
@@ -0,0 +1,160 @@
+package cdp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/tebeka/selenium"
+	"github.com/tebeka/selenium/internal/wsrpc"
+)
+
+// ErrClosed is returned by the commands sent on a closed connection.
+var ErrClosed = errors.New("cdp: the connection is closed")
+
+// Error is an error returned by the browser in reply to a command.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data,omitempty"`
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	if e.Data != "" {
+		return fmt.Sprintf("%s (%d): %s", e.Message, e.Code, e.Data)
+	}
+	return fmt.Sprintf("%s (%d)", e.Message, e.Code)
+}
+
+// Conn is a connection to the DevTools WebSocket of a page. Unlike
+// selenium.ExecuteCDP, it receives the events of the page. It is safe for
+// concurrent use.
+type Conn struct {
+	rpc *wsrpc.Conn
+}
+
+// Dial connects to the DevTools WebSocket at url.
+func Dial(url string) (*Conn, error) {
+	rpc, err := wsrpc.Dial(url, ErrClosed)
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{rpc: rpc}, nil
+}
+
+// debuggerAddressKeys are the keys of the vendor-specific capabilities in
+// which the driver returns the address of the DevTools server.
+var debuggerAddressKeys = []string{"goog:chromeOptions", "ms:edgeOptions"}
+
+// windowHandlePrefix is the prefix ChromeDriver adds to the IDs of the
+// DevTools targets to make the handles of their windows.
+const windowHandlePrefix = "CDwindow-"
+
+// Connect connects to the DevTools WebSocket of the current window of the
+// session of wd, which must be of a Chromium-based browser whose driver
+// returns the debuggerAddress capability, as ChromeDriver does. The browser
+// must run on a host reachable from the test.
+func Connect(wd selenium.WebDriver) (*Conn, error) {
+	addr, err := debuggerAddress(wd)
+	if err != nil {
+		return nil, err
+	}
+	handle, err := wd.CurrentWindowHandle()
+	if err != nil {
+		return nil, err
+	}
+	return dialTarget(addr, strings.TrimPrefix(handle, windowHandlePrefix))
+}
+
+// ConnectTarget is like Connect, but connects to the DevTools target with the
+// given ID, such as the handle of a window returned by ChromeDriver without
+// its "CDwindow-" prefix.
+func ConnectTarget(wd selenium.WebDriver, targetID string) (*Conn, error) {
+	addr, err := debuggerAddress(wd)
+	if err != nil {
+		return nil, err
+	}
+	return dialTarget(addr, targetID)
+}
+
+// debuggerAddress returns the address of the DevTools server of the browser
+// of wd.
+func debuggerAddress(wd selenium.WebDriver) (string, error) {
+	caps, err := wd.Capabilities()
+	if err != nil {
+		return "", err
+	}
+	for _, key := range debuggerAddressKeys {
+		if opts, ok := caps[key].(map[string]interface{}); ok {
+			if addr, _ := opts["debuggerAddress"].(string); addr != "" {
+				return addr, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%w: the session has no DevTools address", selenium.ErrUnsupported)
+}
+
+// dialTarget connects to the target with the given ID of the DevTools server
+// at addr.
+func dialTarget(addr, targetID string) (*Conn, error) {
+	resp, err := http.Get("http://" + addr + "/json/list")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var targets []struct {
+		ID                   string
+		WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&targets); err != nil {
+		return nil, fmt.Errorf("decoding the DevTools targets: %v", err)
+	}
+	for _, t := range targets {
+		if t.ID == targetID && t.WebSocketDebuggerURL != "" {
+			return Dial(t.WebSocketDebuggerURL)
+		}
+	}
+	return nil, fmt.Errorf("the browser has no DevTools target %q to connect to", targetID)
+}
+
+// Execute sends the command method with the given parameters, which are
+// encoded as JSON and may be nil, and waits for its reply. If result is not
+// nil, the result of the command is decoded into it. Errors returned by the
+// browser are of type *Error.
+func (c *Conn) Execute(ctx context.Context, method string, params, result interface{}) error {
+	msg, err := c.rpc.Send(ctx, method, params)
+	if err != nil {
+		return err
+	}
+	var reply struct {
+		Error *Error `json:"error"`
+	}
+	if err := json.Unmarshal(msg.Raw, &reply); err != nil {
+		return err
+	}
+	if reply.Error != nil {
+		return reply.Error
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(msg.Result, result)
+}
+
+// On registers f to be called with the parameters of each event named
+// method, such as "Fetch.requestPaused", and returns a function that
+// unregisters it. Most events are only sent after enabling their domain. The
+// handlers are called one at a time, in the order the events arrive.
+func (c *Conn) On(method string, f func(params json.RawMessage)) (remove func()) {
+	return c.rpc.On(method, f)
+}
+
+// Close closes the connection. Commands waiting for their reply return
+// ErrClosed.
+func (c *Conn) Close() error {
+	return c.rpc.Close()
+}
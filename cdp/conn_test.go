@@ -0,0 +1,151 @@
+package cdp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tebeka/selenium"
+	"golang.org/x/net/websocket"
+)
+
+// newDevTools starts a local stand-in for the DevTools server of a browser,
+// and returns its address.
+func newDevTools(t *testing.T) (addr string, stop func()) {
+	mux := http.NewServeMux()
+	hs := httptest.NewServer(mux)
+	addr = strings.TrimPrefix(hs.URL, "http://")
+	mux.HandleFunc("/json/list", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `[
+			{"id": "W1", "type": "service_worker", "webSocketDebuggerUrl": "ws://%[1]s/devtools/worker/1"},
+			{"id": "P0", "type": "page", "webSocketDebuggerUrl": "ws://%[1]s/devtools/page/0"},
+			{"id": "P1", "type": "page", "webSocketDebuggerUrl": "ws://%[1]s/devtools/page/1"}
+		]`, addr)
+	})
+	mux.Handle("/devtools/page/1", websocket.Handler(func(ws *websocket.Conn) {
+		send := func(v interface{}) {
+			if err := websocket.JSON.Send(ws, v); err != nil {
+				t.Errorf("Sending %v returned error: %v", v, err)
+			}
+		}
+		for {
+			var cmd struct {
+				ID     int64
+				Method string
+				Params map[string]interface{}
+			}
+			if err := websocket.JSON.Receive(ws, &cmd); err != nil {
+				return
+			}
+			switch cmd.Method {
+			case "Runtime.evaluate":
+				send(map[string]interface{}{"id": cmd.ID, "result": map[string]interface{}{"expression": cmd.Params["expression"]}})
+			case "Page.enable":
+				send(map[string]interface{}{"id": cmd.ID, "result": map[string]interface{}{}})
+				send(map[string]interface{}{"method": "Page.loadEventFired", "params": map[string]interface{}{"timestamp": 42.5}})
+			case "Test.hang":
+			default:
+				send(map[string]interface{}{"id": cmd.ID, "error": map[string]interface{}{"code": -32601, "message": "'" + cmd.Method + "' wasn't found"}})
+			}
+		}
+	}))
+	return addr, hs.Close
+}
+
+func TestConn(t *testing.T) {
+	addr, stop := newDevTools(t)
+	defer stop()
+	wd := &fakeDriver{
+		caps: selenium.Capabilities{
+			"goog:chromeOptions": map[string]interface{}{"debuggerAddress": addr},
+		},
+		handle: "CDwindow-P1",
+	}
+	c, err := Connect(wd)
+	if err != nil {
+		t.Fatalf("Connect() returned error: %v", err)
+	}
+	ctx := context.Background()
+
+	loaded := make(chan float64, 1)
+	c.On("Page.loadEventFired", func(params json.RawMessage) {
+		var e struct{ Timestamp float64 }
+		json.Unmarshal(params, &e)
+		loaded <- e.Timestamp
+	})
+	if err := c.Execute(ctx, "Page.enable", nil, nil); err != nil {
+		t.Fatalf("Execute(Page.enable) returned error: %v", err)
+	}
+	select {
+	case ts := <-loaded:
+		if ts != 42.5 {
+			t.Errorf("the event has timestamp %v, want 42.5", ts)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the event handler was not called")
+	}
+
+	var result struct{ Expression string }
+	if err := c.Execute(ctx, "Runtime.evaluate", map[string]string{"expression": "1+1"}, &result); err != nil {
+		t.Fatalf("Execute(Runtime.evaluate) returned error: %v", err)
+	}
+	if result.Expression != "1+1" {
+		t.Errorf("Execute(Runtime.evaluate) returned %+v", result)
+	}
+
+	err = c.Execute(ctx, "Nope.nope", nil, nil)
+	var e *Error
+	if !errors.As(err, &e) || e.Code != -32601 {
+		t.Errorf("Execute(Nope.nope) returned error %v, want an *Error with code -32601", err)
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := c.Execute(timeout, "Test.hang", nil, nil); err != context.DeadlineExceeded {
+		t.Errorf("Execute() of an unanswered command returned error %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Errorf("Close() returned error: %v", err)
+	}
+	if err := c.Execute(ctx, "Page.enable", nil, nil); err != ErrClosed {
+		t.Errorf("Execute() after Close() returned error %v, want %v", err, ErrClosed)
+	}
+}
+
+func TestConnectTarget(t *testing.T) {
+	addr, stop := newDevTools(t)
+	defer stop()
+	wd := &fakeDriver{caps: selenium.Capabilities{
+		"ms:edgeOptions": map[string]interface{}{"debuggerAddress": addr},
+	}}
+	c, err := ConnectTarget(wd, "P1")
+	if err != nil {
+		t.Fatalf("ConnectTarget() returned error: %v", err)
+	}
+	if err := c.Execute(context.Background(), "Runtime.evaluate", map[string]string{"expression": "1"}, nil); err != nil {
+		t.Errorf("Execute() returned error: %v", err)
+	}
+	c.Close()
+
+	if _, err := ConnectTarget(wd, "W2"); err == nil {
+		t.Errorf("ConnectTarget() to a missing target returned no error")
+	}
+	wd.handle = "CDwindow-P2"
+	if _, err := Connect(wd); err == nil {
+		t.Errorf("Connect() with the handle of a missing target returned no error")
+	}
+}
+
+func TestConnectUnsupported(t *testing.T) {
+	wd := &fakeDriver{caps: selenium.Capabilities{"browserName": "firefox"}}
+	if _, err := Connect(wd); !errors.Is(err, selenium.ErrUnsupported) {
+		t.Errorf("Connect() returned error %v, want %v", err, selenium.ErrUnsupported)
+	}
+}
@@ -0,0 +1,55 @@
+package selenium
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExecuteCDP(t *testing.T) {
+	d := newFakeDriver(t)
+	var body struct {
+		Cmd    string
+		Params map[string]interface{}
+	}
+	d.handle("POST", "/ms/cdp/execute", func(r *fakeRequest) (interface{}, error) {
+		r.decode(t, &body)
+		return map[string]string{"identifier": "1"}, nil
+	})
+
+	wd := d.driver("MicrosoftEdge")
+	result, err := wd.ExecuteCDP("Page.addScriptToEvaluateOnNewDocument", map[string]string{"source": "1"})
+	if err != nil {
+		t.Fatalf("ExecuteCDP() returned error: %v", err)
+	}
+	if got, want := string(result), `{"identifier":"1"}`; got != want {
+		t.Errorf("ExecuteCDP() = %s, want %s", got, want)
+	}
+	if got, want := d.received(), []string{"POST /ms/cdp/execute"}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("ExecuteCDP() sent the commands %q, want %q", got, want)
+	}
+	if body.Cmd != "Page.addScriptToEvaluateOnNewDocument" || body.Params["source"] != "1" {
+		t.Errorf("ExecuteCDP() sent %+v", body)
+	}
+
+	body.Params = nil
+	if _, err := wd.ExecuteCDP("Browser.getVersion", nil); err != nil {
+		t.Fatalf("ExecuteCDP() returned error: %v", err)
+	}
+	if body.Params == nil || len(body.Params) != 0 {
+		t.Errorf("ExecuteCDP() with nil parameters sent params %v, want an empty object", body.Params)
+	}
+
+	wd.browser = "firefox"
+	n := len(d.received())
+	if _, err := wd.ExecuteCDP("Browser.getVersion", nil); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("ExecuteCDP() on Firefox returned error %v, want ErrUnsupported", err)
+	}
+	if got := d.received(); len(got) != n {
+		t.Errorf("ExecuteCDP() on Firefox sent the command %q", got[n:])
+	}
+
+	// Other WebDrivers cannot send the commands.
+	if _, err := ExecuteCDP(struct{ WebDriver }{wd}, "Browser.getVersion", nil); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("ExecuteCDP() with another WebDriver returned error %v, want ErrUnsupported", err)
+	}
+}
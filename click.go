@@ -0,0 +1,145 @@
+package selenium
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Obstruction describes an element that covers the center of another
+// element.
+type Obstruction struct {
+	TagName string
+	ID      string
+	Classes []string
+	// OuterHTML is the start of the element's outer HTML.
+	OuterHTML string
+	// Location and Size are the element's bounding rectangle, relative to the
+	// viewport.
+	Location Point
+	Size     Size
+}
+
+// String returns a CSS-like description of the element, e.g.
+// "div#overlay.modal.open".
+func (o *Obstruction) String() string {
+	s := o.TagName
+	if o.ID != "" {
+		s += "#" + o.ID
+	}
+	for _, c := range o.Classes {
+		s += "." + c
+	}
+	return s
+}
+
+// ClickInterceptedError is returned by the Click method of an element when
+// the click would be received by another element.
+type ClickInterceptedError struct {
+	// Err is the error returned by the WebDriver server.
+	Err *Error
+	// Obstruction is the element covering the center of the clicked element.
+	Obstruction *Obstruction
+}
+
+func (e *ClickInterceptedError) Error() string {
+	o := e.Obstruction
+	return fmt.Sprintf("%s: obstructed by %s at (%d, %d) with size %dx%d: %s",
+		e.Err, o, o.Location.X, o.Location.Y, o.Size.Width, o.Size.Height, o.OuterHTML)
+}
+
+// Unwrap returns the error returned by the WebDriver server.
+func (e *ClickInterceptedError) Unwrap() error { return e.Err }
+
+// RetryInterceptedClicks returns an option that makes the Click method of
+// elements handle an "element click intercepted" error by scrolling the
+// element to the center of the viewport and clicking it again, once. This
+// helps when the element is covered by a sticky header or footer.
+func RetryInterceptedClicks() RemoteOption {
+	return func(wd *remoteWD) error {
+		wd.retryIntercepted = true
+		return nil
+	}
+}
+
+const scrollToCenterScript = `arguments[0].scrollIntoView({block: 'center', inline: 'center'});`
+
+// maxOuterHTML is the number of characters of the outer HTML of an
+// obstruction to report.
+const maxOuterHTML = 200
+
+// obstructionScript returns a description of the element at the center of
+// its element argument, or null if there is no such element or it is the
+// argument or one of its descendants.
+var obstructionScript = fmt.Sprintf(`
+var e = arguments[0], r = e.getBoundingClientRect();
+var hit = document.elementFromPoint(r.left + r.width / 2, r.top + r.height / 2);
+if (!hit || hit === e || e.contains(hit)) {
+	return null;
+}
+var h = hit.getBoundingClientRect(), html = hit.outerHTML;
+return {
+	tagName: hit.tagName.toLowerCase(),
+	id: hit.id,
+	classes: Array.prototype.slice.call(hit.classList),
+	outerHTML: html.length > %d ? html.slice(0, %d) + '...' : html,
+	x: h.left, y: h.top, width: h.width, height: h.height
+};
+`, maxOuterHTML, maxOuterHTML)
+
+// click clicks the element, handling intercepted clicks as described by
+// RetryInterceptedClicks and ClickInterceptedError.
+func (elem *remoteWE) click() error {
+	const urlTemplate = "/session/%%s/element/%s/click"
+	err := elem.voidCommand(urlTemplate, nil)
+	if !HasErrorCode(err, CodeElementClickIntercepted) {
+		return err
+	}
+	wd := elem.parent
+	if wd.retryIntercepted {
+		if _, serr := wd.ExecuteScript(scrollToCenterScript, []interface{}{elem}); serr == nil {
+			err = elem.voidCommand(urlTemplate, nil)
+			if !HasErrorCode(err, CodeElementClickIntercepted) {
+				return err
+			}
+		}
+	}
+	return elem.diagnoseIntercepted(err)
+}
+
+// diagnoseIntercepted returns a *ClickInterceptedError for err if the
+// obstructing element can be found, and err otherwise.
+func (elem *remoteWE) diagnoseIntercepted(err error) error {
+	e, ok := err.(*Error)
+	if !ok {
+		return err
+	}
+	response, serr := elem.parent.ExecuteScriptRaw(obstructionScript, []interface{}{elem})
+	if serr != nil {
+		return err
+	}
+	reply := new(struct {
+		Value *struct {
+			TagName   string
+			ID        string
+			Classes   []string
+			OuterHTML string
+			rect
+		}
+	})
+	if jerr := json.Unmarshal(response, reply); jerr != nil || reply.Value == nil {
+		return err
+	}
+	v := reply.Value
+	return &ClickInterceptedError{
+		Err: e,
+		Obstruction: &Obstruction{
+			TagName:   v.TagName,
+			ID:        v.ID,
+			Classes:   v.Classes,
+			OuterHTML: strings.TrimSpace(v.OuterHTML),
+			Location:  Point{round(v.X), round(v.Y)},
+			Size:      Size{round(v.Width), round(v.Height)},
+		},
+	}
+}
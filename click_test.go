@@ -0,0 +1,98 @@
+package selenium
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// overlaidButton is a button covered by an overlay until it is scrolled to
+// the center of the viewport.
+type overlaidButton struct {
+	scrolled bool
+	clicks   int
+}
+
+// serve makes d reply to the commands on the button.
+func (b *overlaidButton) serve(d *fakeDriver) {
+	d.handle("POST", "/click", func(*fakeRequest) (interface{}, error) {
+		b.clicks++
+		if b.scrolled {
+			return nil, nil
+		}
+		return nil, &Error{
+			Err:      CodeElementClickIntercepted,
+			Message:  "Element <button> is not clickable at point (10, 10)",
+			HTTPCode: http.StatusBadRequest,
+		}
+	})
+	d.handle("POST", "/execute/sync", func(r *fakeRequest) (interface{}, error) {
+		if strings.Contains(string(r.Body), "scrollIntoView") {
+			b.scrolled = true
+			return nil, nil
+		}
+		return map[string]interface{}{
+			"tagName":   "div",
+			"id":        "banner",
+			"classes":   []string{"sticky", "top"},
+			"outerHTML": `<div id="banner" class="sticky top">Cookies!</div>`,
+			"x":         0,
+			"y":         0.4,
+			"width":     800,
+			"height":    49.6,
+		}, nil
+	})
+}
+
+func TestClickIntercepted(t *testing.T) {
+	s := &overlaidButton{}
+	d := newFakeDriver(t)
+	s.serve(d)
+
+	wd := d.driver("")
+	button := &remoteWE{parent: wd, id: "button"}
+	err := button.Click()
+	var ce *ClickInterceptedError
+	if !errors.As(err, &ce) {
+		t.Fatalf("button.Click() returned error %v, want a *ClickInterceptedError", err)
+	}
+	if !HasErrorCode(err, CodeElementClickIntercepted) {
+		t.Errorf("HasErrorCode(%v, %q) = false, want true", err, CodeElementClickIntercepted)
+	}
+	want := &Obstruction{
+		TagName:   "div",
+		ID:        "banner",
+		Classes:   []string{"sticky", "top"},
+		OuterHTML: `<div id="banner" class="sticky top">Cookies!</div>`,
+		Size:      Size{800, 50},
+	}
+	if !reflect.DeepEqual(ce.Obstruction, want) {
+		t.Errorf("Obstruction = %+v, want %+v", ce.Obstruction, want)
+	}
+	if got := ce.Obstruction.String(); got != "div#banner.sticky.top" {
+		t.Errorf("Obstruction.String() = %q, want %q", got, "div#banner.sticky.top")
+	}
+	if s.scrolled {
+		t.Errorf("button was scrolled without the RetryInterceptedClicks option")
+	}
+}
+
+func TestRetryInterceptedClicks(t *testing.T) {
+	s := &overlaidButton{}
+	d := newFakeDriver(t)
+	s.serve(d)
+
+	wd := d.driver("")
+	if err := RetryInterceptedClicks()(wd); err != nil {
+		t.Fatalf("RetryInterceptedClicks() returned error: %v", err)
+	}
+	button := &remoteWE{parent: wd, id: "button"}
+	if err := button.Click(); err != nil {
+		t.Fatalf("button.Click() returned error: %v", err)
+	}
+	if s.clicks != 2 {
+		t.Errorf("button was clicked %d times, want 2", s.clicks)
+	}
+}
@@ -0,0 +1,249 @@
+// Package conditions provides common conditions to be used with the Wait
+// methods of selenium.WebDriver.
+package conditions
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/tebeka/selenium"
+)
+
+// legacyNoAlertOpen is the error string returned by legacy servers when no
+// alert is open.
+const legacyNoAlertOpen = "no alert open"
+
+// ElementCondition is a condition that, once satisfied, also yields the
+// element that it examined.
+type ElementCondition func(wd selenium.WebDriver) (selenium.WebElement, bool, error)
+
+// Condition converts the ElementCondition into a selenium.Condition. If elem
+// is not nil, the located element is stored in it once the condition is
+// satisfied.
+func (c ElementCondition) Condition(elem *selenium.WebElement) selenium.Condition {
+	return func(wd selenium.WebDriver) (bool, error) {
+		e, ok, err := c(wd)
+		if err != nil || !ok {
+			return false, err
+		}
+		if elem != nil {
+			*elem = e
+		}
+		return true, nil
+	}
+}
+
+// ElementLocated returns a condition that is satisfied once an element
+// matching the given locator is present in the DOM.
+func ElementLocated(by, value string) ElementCondition {
+	return func(wd selenium.WebDriver) (selenium.WebElement, bool, error) {
+		elem, err := wd.FindElement(by, value)
+		if selenium.HasErrorCode(err, selenium.CodeNoSuchElement) {
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		return elem, true, nil
+	}
+}
+
+// elementCheck returns a condition that locates an element and is satisfied
+// once check returns true for it. Elements that become stale between the
+// lookup and the check are treated as not yet satisfying the condition.
+func elementCheck(by, value string, check func(selenium.WebElement) (bool, error)) ElementCondition {
+	located := ElementLocated(by, value)
+	return func(wd selenium.WebDriver) (selenium.WebElement, bool, error) {
+		elem, ok, err := located(wd)
+		if err != nil || !ok {
+			return nil, false, err
+		}
+		ok, err = check(elem)
+		if selenium.HasErrorCode(err, selenium.CodeStaleElementReference) {
+			return nil, false, nil
+		}
+		if err != nil || !ok {
+			return nil, false, err
+		}
+		return elem, true, nil
+	}
+}
+
+// ElementVisible returns a condition that is satisfied once an element
+// matching the given locator is present and displayed.
+func ElementVisible(by, value string) ElementCondition {
+	return elementCheck(by, value, func(elem selenium.WebElement) (bool, error) {
+		return elem.IsDisplayed()
+	})
+}
+
+// ElementClickable returns a condition that is satisfied once an element
+// matching the given locator is displayed and enabled.
+func ElementClickable(by, value string) ElementCondition {
+	return elementCheck(by, value, func(elem selenium.WebElement) (bool, error) {
+		displayed, err := elem.IsDisplayed()
+		if err != nil || !displayed {
+			return false, err
+		}
+		return elem.IsEnabled()
+	})
+}
+
+// TextPresentInElement returns a condition that is satisfied once the text of
+// the element matching the given locator contains text.
+func TextPresentInElement(by, value, text string) ElementCondition {
+	return elementCheck(by, value, func(elem selenium.WebElement) (bool, error) {
+		t, err := elem.Text()
+		if err != nil {
+			return false, err
+		}
+		return strings.Contains(t, text), nil
+	})
+}
+
+// AttributeContains returns a condition that is satisfied once the named
+// attribute of the element matching the given locator contains substr.
+func AttributeContains(by, value, name, substr string) ElementCondition {
+	return elementCheck(by, value, func(elem selenium.WebElement) (bool, error) {
+		attr, err := elem.GetAttribute(name)
+		if err != nil {
+			return false, err
+		}
+		return strings.Contains(attr, substr), nil
+	})
+}
+
+// TitleIs returns a condition that is satisfied once the page title is equal
+// to title.
+func TitleIs(title string) selenium.Condition {
+	return func(wd selenium.WebDriver) (bool, error) {
+		t, err := wd.Title()
+		if err != nil {
+			return false, err
+		}
+		return t == title, nil
+	}
+}
+
+// TitleContains returns a condition that is satisfied once the page title
+// contains substr.
+func TitleContains(substr string) selenium.Condition {
+	return func(wd selenium.WebDriver) (bool, error) {
+		t, err := wd.Title()
+		if err != nil {
+			return false, err
+		}
+		return strings.Contains(t, substr), nil
+	}
+}
+
+// URLMatches returns a condition that is satisfied once the current URL
+// matches re.
+func URLMatches(re *regexp.Regexp) selenium.Condition {
+	return func(wd selenium.WebDriver) (bool, error) {
+		u, err := wd.CurrentURL()
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(u), nil
+	}
+}
+
+// AlertPresent returns a condition that is satisfied once an alert is open.
+func AlertPresent() selenium.Condition {
+	return func(wd selenium.WebDriver) (bool, error) {
+		_, err := wd.AlertText()
+		if selenium.HasErrorCode(err, selenium.CodeNoSuchAlert, legacyNoAlertOpen) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+}
+
+// NumberOfWindowsToBe returns a condition that is satisfied once exactly n
+// windows are open.
+func NumberOfWindowsToBe(n int) selenium.Condition {
+	return func(wd selenium.WebDriver) (bool, error) {
+		handles, err := wd.WindowHandles()
+		if err != nil {
+			return false, err
+		}
+		return len(handles) == n, nil
+	}
+}
+
+// FrameAvailableAndSwitch returns a condition that is satisfied once the
+// given frame can be switched to. The frame parameter is interpreted as in
+// WebDriver.SwitchFrame. When the condition is satisfied, the driver has
+// switched to the frame.
+func FrameAvailableAndSwitch(frame interface{}) selenium.Condition {
+	return func(wd selenium.WebDriver) (bool, error) {
+		err := wd.SwitchFrame(frame)
+		if selenium.HasErrorCode(err, selenium.CodeNoSuchFrame, selenium.CodeNoSuchElement) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+}
+
+// StalenessOf returns a condition that is satisfied once elem is no longer
+// attached to the DOM.
+func StalenessOf(elem selenium.WebElement) selenium.Condition {
+	return func(selenium.WebDriver) (bool, error) {
+		_, err := elem.IsEnabled()
+		if selenium.HasErrorCode(err, selenium.CodeStaleElementReference, selenium.CodeNoSuchElement) {
+			return true, nil
+		}
+		return false, err
+	}
+}
+
+// And returns a condition that is satisfied once all of the conditions are
+// satisfied. The conditions are evaluated in order and evaluation stops at
+// the first condition that is not satisfied.
+func And(conditions ...selenium.Condition) selenium.Condition {
+	return func(wd selenium.WebDriver) (bool, error) {
+		for _, c := range conditions {
+			ok, err := c(wd)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+}
+
+// Or returns a condition that is satisfied once any of the conditions is
+// satisfied. The conditions are evaluated in order and evaluation stops at
+// the first condition that is satisfied.
+func Or(conditions ...selenium.Condition) selenium.Condition {
+	return func(wd selenium.WebDriver) (bool, error) {
+		for _, c := range conditions {
+			ok, err := c(wd)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+// Not returns a condition that is satisfied when condition is not.
+func Not(condition selenium.Condition) selenium.Condition {
+	return func(wd selenium.WebDriver) (bool, error) {
+		ok, err := condition(wd)
+		if err != nil {
+			return false, err
+		}
+		return !ok, nil
+	}
+}
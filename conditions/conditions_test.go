@@ -0,0 +1,261 @@
+package conditions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tebeka/selenium"
+)
+
+// fakeDriver implements the parts of selenium.WebDriver used by the
+// conditions. Calling any other method panics.
+type fakeDriver struct {
+	selenium.WebDriver
+
+	title, url string
+	alert      bool
+	windows    []string
+	frames     map[string]bool
+	elems      map[string]*fakeElement
+}
+
+func (wd *fakeDriver) Title() (string, error)           { return wd.title, nil }
+func (wd *fakeDriver) CurrentURL() (string, error)      { return wd.url, nil }
+func (wd *fakeDriver) WindowHandles() ([]string, error) { return wd.windows, nil }
+
+func (wd *fakeDriver) AlertText() (string, error) {
+	if !wd.alert {
+		return "", &selenium.Error{Err: "no such alert"}
+	}
+	return "alert", nil
+}
+
+func (wd *fakeDriver) SwitchFrame(frame interface{}) error {
+	if !wd.frames[frame.(string)] {
+		return &selenium.Error{Err: "no such frame"}
+	}
+	return nil
+}
+
+func (wd *fakeDriver) FindElement(by, value string) (selenium.WebElement, error) {
+	e, ok := wd.elems[by+"="+value]
+	if !ok {
+		return nil, &selenium.Error{Err: "no such element"}
+	}
+	return e, nil
+}
+
+type fakeElement struct {
+	selenium.WebElement
+
+	displayed, enabled, stale bool
+	text                      string
+	attrs                     map[string]string
+}
+
+var errStale = &selenium.Error{Err: "stale element reference"}
+
+func (e *fakeElement) IsDisplayed() (bool, error) {
+	if e.stale {
+		return false, errStale
+	}
+	return e.displayed, nil
+}
+
+func (e *fakeElement) IsEnabled() (bool, error) {
+	if e.stale {
+		return false, errStale
+	}
+	return e.enabled, nil
+}
+
+func (e *fakeElement) Text() (string, error) {
+	if e.stale {
+		return "", errStale
+	}
+	return e.text, nil
+}
+
+func (e *fakeElement) GetAttribute(name string) (string, error) {
+	return e.attrs[name], nil
+}
+
+func TestElementConditions(t *testing.T) {
+	hidden := &fakeElement{enabled: true}
+	disabled := &fakeElement{displayed: true}
+	ready := &fakeElement{
+		displayed: true,
+		enabled:   true,
+		text:      "Hello, WebDriver",
+		attrs:     map[string]string{"class": "button primary"},
+	}
+	stale := &fakeElement{displayed: true, enabled: true, stale: true}
+	wd := &fakeDriver{
+		elems: map[string]*fakeElement{
+			"id=hidden":   hidden,
+			"id=disabled": disabled,
+			"id=ready":    ready,
+			"id=stale":    stale,
+		},
+	}
+
+	for _, tc := range []struct {
+		desc string
+		cond ElementCondition
+		want selenium.WebElement
+	}{
+		{"ElementLocated missing", ElementLocated(selenium.ByID, "missing"), nil},
+		{"ElementLocated present", ElementLocated(selenium.ByID, "hidden"), hidden},
+		{"ElementVisible hidden", ElementVisible(selenium.ByID, "hidden"), nil},
+		{"ElementVisible displayed", ElementVisible(selenium.ByID, "disabled"), disabled},
+		{"ElementVisible stale", ElementVisible(selenium.ByID, "stale"), nil},
+		{"ElementClickable disabled", ElementClickable(selenium.ByID, "disabled"), nil},
+		{"ElementClickable ready", ElementClickable(selenium.ByID, "ready"), ready},
+		{"TextPresentInElement absent", TextPresentInElement(selenium.ByID, "ready", "Goodbye"), nil},
+		{"TextPresentInElement present", TextPresentInElement(selenium.ByID, "ready", "WebDriver"), ready},
+		{"AttributeContains absent", AttributeContains(selenium.ByID, "ready", "class", "secondary"), nil},
+		{"AttributeContains present", AttributeContains(selenium.ByID, "ready", "class", "primary"), ready},
+	} {
+		var got selenium.WebElement
+		ok, err := tc.cond.Condition(&got)(wd)
+		if err != nil {
+			t.Errorf("%s: returned error: %v", tc.desc, err)
+			continue
+		}
+		if ok != (tc.want != nil) {
+			t.Errorf("%s: returned %t, want %t", tc.desc, ok, tc.want != nil)
+		}
+		if got != tc.want {
+			t.Errorf("%s: stored element %v, want %v", tc.desc, got, tc.want)
+		}
+	}
+}
+
+func TestDriverConditions(t *testing.T) {
+	wd := &fakeDriver{
+		title:   "Go Selenium Test Suite",
+		url:     "http://localhost:8080/search?q=golang",
+		windows: []string{"first", "second"},
+		frames:  map[string]bool{"iframeID": true},
+	}
+
+	for _, tc := range []struct {
+		desc string
+		cond selenium.Condition
+		want bool
+	}{
+		{"TitleIs equal", TitleIs("Go Selenium Test Suite"), true},
+		{"TitleIs different", TitleIs("Go Selenium"), false},
+		{"TitleContains", TitleContains("Selenium"), true},
+		{"URLMatches match", URLMatches(regexp.MustCompile(`/search\?q=\w+$`)), true},
+		{"URLMatches no match", URLMatches(regexp.MustCompile(`/other`)), false},
+		{"AlertPresent", AlertPresent(), false},
+		{"NumberOfWindowsToBe equal", NumberOfWindowsToBe(2), true},
+		{"NumberOfWindowsToBe different", NumberOfWindowsToBe(1), false},
+		{"FrameAvailableAndSwitch present", FrameAvailableAndSwitch("iframeID"), true},
+		{"FrameAvailableAndSwitch missing", FrameAvailableAndSwitch("missing"), false},
+		{"StalenessOf attached", StalenessOf(&fakeElement{}), false},
+		{"StalenessOf stale", StalenessOf(&fakeElement{stale: true}), true},
+		{"And", And(TitleContains("Go"), NumberOfWindowsToBe(2)), true},
+		{"And short-circuits", And(TitleContains("Python"), NumberOfWindowsToBe(2)), false},
+		{"Or", Or(TitleContains("Python"), NumberOfWindowsToBe(2)), true},
+		{"Or none", Or(TitleContains("Python"), NumberOfWindowsToBe(3)), false},
+		{"Not", Not(TitleContains("Python")), true},
+	} {
+		got, err := tc.cond(wd)
+		if err != nil {
+			t.Errorf("%s: returned error: %v", tc.desc, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: returned %t, want %t", tc.desc, got, tc.want)
+		}
+	}
+}
+
+func TestConditionErrors(t *testing.T) {
+	wantErr := errors.New("connection refused")
+	failing := func(selenium.WebDriver) (bool, error) { return false, wantErr }
+	wd := &fakeDriver{title: "title"}
+
+	for _, tc := range []struct {
+		desc string
+		cond selenium.Condition
+	}{
+		{"And", And(TitleIs("title"), failing)},
+		{"Or", Or(TitleIs("other"), failing)},
+		{"Not", Not(failing)},
+	} {
+		if _, err := tc.cond(wd); err != wantErr {
+			t.Errorf("%s: returned error %v, want %v", tc.desc, err, wantErr)
+		}
+	}
+}
+
+// rerenderingServer is a W3C WebDriver server whose page has a single
+// element, which is replaced by a new one, matched by the same locator, when
+// the page is re-rendered.
+type rerenderingServer struct {
+	mu         sync.Mutex
+	generation int
+}
+
+func (s *rerenderingServer) rerender() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.generation++
+}
+
+func (s *rerenderingServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	current := fmt.Sprintf("e%d", s.generation)
+	s.mu.Unlock()
+
+	status, value := http.StatusOK, interface{}(nil)
+	switch r.URL.Path {
+	case "/session":
+		value = map[string]interface{}{"sessionId": "s", "capabilities": map[string]interface{}{}}
+	case "/session/s/element":
+		value = map[string]string{"element-6066-11e4-a52e-4f735466cecf": current}
+	case "/session/s/element/" + current + "/enabled":
+		value = true
+	default:
+		status = http.StatusNotFound
+		value = map[string]string{"error": selenium.CodeStaleElementReference, "message": r.URL.Path}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"value": value})
+}
+
+func TestStalenessOfSelfHealing(t *testing.T) {
+	s := new(rerenderingServer)
+	hs := httptest.NewServer(s)
+	defer hs.Close()
+
+	wd, err := selenium.NewRemote(selenium.Capabilities{}, hs.URL, selenium.SelfHealing(nil))
+	if err != nil {
+		t.Fatalf("NewRemote() returned error: %v", err)
+	}
+	elem, err := wd.FindElement(selenium.ByID, "item")
+	if err != nil {
+		t.Fatalf("FindElement() returned error: %v", err)
+	}
+	if ok, err := StalenessOf(elem)(wd); err != nil || ok {
+		t.Fatalf("StalenessOf() of an attached element = %t, %v, want false, nil", ok, err)
+	}
+
+	// The locator still matches after the re-render, but the element found
+	// before it is stale.
+	s.rerender()
+	if err := wd.WaitWithTimeoutAndInterval(StalenessOf(elem), time.Second, 10*time.Millisecond); err != nil {
+		t.Errorf("waiting for StalenessOf() with self-healing returned error: %v", err)
+	}
+}
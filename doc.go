@@ -9,5 +9,14 @@ browsers will be used and how. To avoid needing to manage these dependencies,
 use a cloud-based browser testing environment, like Sauce Labs, BrowserStack
 or similar. Otherwise, use the methods provided by this API to specify the
 paths to the dependencies, which will have to be downloaded separately.
+
+Features added after the WebDriver and WebElement interfaces were published,
+such as LocatorFinder, ImageScreenshotter and CDPExecutor, are provided by
+separate interfaces that the WebDrivers and WebElements of this package
+implement, so that other implementations of WebDriver and WebElement keep
+compiling. Each of them comes with functions, such as FindElementBy,
+ScreenshotImage and ExecuteCDP, that accept any WebDriver or WebElement and
+return an error wrapping ErrUnsupported, or fall back to a generic
+implementation, when it does not implement the interface.
 */
 package selenium
@@ -0,0 +1,138 @@
+package selenium
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRequest is a command received by a fakeDriver.
+type fakeRequest struct {
+	Method string
+	// Path is the path of the command relative to the session, such as
+	// "/element/e/click".
+	Path string
+	Body []byte
+}
+
+// decode decodes the JSON body of the command into v.
+func (r *fakeRequest) decode(t *testing.T, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		t.Errorf("Decoding the body of %s %s returned error: %v", r.Method, r.Path, err)
+	}
+}
+
+// fakeHandler returns the value of the reply to a command, or an error. An
+// *Error is sent with its HTTPCode as the HTTP status, or 500 if it is zero.
+type fakeHandler func(r *fakeRequest) (interface{}, error)
+
+type fakeRoute struct {
+	method, suffix string
+	handler        fakeHandler
+}
+
+// fakeDriver is a fake W3C WebDriver server for the session "s". Commands
+// are replied to by the handler of the first route they match, and with an
+// unknown command error if they match none. It is safe for concurrent use.
+type fakeDriver struct {
+	t  *testing.T
+	hs *httptest.Server
+
+	mu       sync.Mutex
+	routes   []fakeRoute
+	commands []string
+}
+
+// newFakeDriver starts a fakeDriver, which is closed when the test ends.
+func newFakeDriver(t *testing.T) *fakeDriver {
+	d := &fakeDriver{t: t}
+	d.hs = httptest.NewServer(http.HandlerFunc(d.serveHTTP))
+	t.Cleanup(d.hs.Close)
+	return d
+}
+
+// handle routes the commands whose path ends with suffix, and whose method
+// is method or any method if it is empty, to h.
+func (d *fakeDriver) handle(method, suffix string, h fakeHandler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.routes = append(d.routes, fakeRoute{method, suffix, h})
+}
+
+// reply routes the commands as for handle to a handler that replies with
+// value.
+func (d *fakeDriver) reply(method, suffix string, value interface{}) {
+	d.handle(method, suffix, func(*fakeRequest) (interface{}, error) { return value, nil })
+}
+
+// received returns the commands received so far, as "METHOD path" with the
+// path relative to the session.
+func (d *fakeDriver) received() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.commands...)
+}
+
+// driver returns a W3C remoteWD for the session of the fakeDriver, for the
+// browser named browser.
+func (d *fakeDriver) driver(browser string) *remoteWD {
+	return &remoteWD{id: "s", urlPrefix: d.hs.URL, w3cCompatible: true, browser: browser}
+}
+
+func (d *fakeDriver) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		d.t.Errorf("Reading the body of %s %s returned error: %v", r.Method, r.URL.Path, err)
+	}
+	req := &fakeRequest{Method: r.Method, Path: strings.TrimPrefix(r.URL.Path, "/session/s"), Body: body}
+
+	d.mu.Lock()
+	d.commands = append(d.commands, req.Method+" "+req.Path)
+	var h fakeHandler
+	for _, route := range d.routes {
+		if (route.method == "" || route.method == req.Method) && strings.HasSuffix(req.Path, route.suffix) {
+			h = route.handler
+			break
+		}
+	}
+	d.mu.Unlock()
+
+	if h == nil {
+		writeReply(w, http.StatusNotFound, &Error{Err: CodeUnknownCommand, Message: r.URL.Path})
+		return
+	}
+	value, err := h(req)
+	if err == nil {
+		writeReply(w, http.StatusOK, value)
+		return
+	}
+	e, ok := err.(*Error)
+	if !ok {
+		e = &Error{Err: "unknown error", Message: err.Error()}
+	}
+	status := e.HTTPCode
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	writeReply(w, status, e)
+}
+
+func writeReply(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", jsonContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"value": value})
+}
+
+// elementRefs returns the W3C references to the elements with the given IDs.
+func elementRefs(ids ...string) []map[string]string {
+	var elems []map[string]string
+	for _, id := range ids {
+		elems = append(elems, map[string]string{webElementIdentifier: id})
+	}
+	return elems
+}
@@ -0,0 +1,223 @@
+package selenium
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io/ioutil"
+	"math"
+)
+
+// FullPageScreenshotter takes screenshots of the whole page.
+type FullPageScreenshotter interface {
+	// FullPageScreenshot takes a screenshot, as PNG, of the whole page,
+	// including the parts outside of the viewport.
+	FullPageScreenshot() ([]byte, error)
+}
+
+// FullPageScreenshot takes a screenshot, as PNG, of the whole page of wd,
+// including the parts outside of the viewport. Firefox and Chromium-based
+// browsers capture the page natively. With other browsers, or if wd does not
+// implement FullPageScreenshotter, the page is scrolled through and
+// screenshots of the viewport are stitched together. Fixed and sticky
+// elements are hidden after the first of them.
+func FullPageScreenshot(wd WebDriver) ([]byte, error) {
+	if s, ok := wd.(FullPageScreenshotter); ok {
+		return s.FullPageScreenshot()
+	}
+	return stitchedScreenshot(wd)
+}
+
+func (wd *remoteWD) FullPageScreenshot() ([]byte, error) {
+	var (
+		buf []byte
+		err error
+	)
+	switch {
+	case wd.browser == "firefox":
+		buf, err = wd.mozFullPageScreenshot()
+	case wd.cdpVendor() != "":
+		buf, err = wd.cdpFullPageScreenshot()
+	default:
+		return stitchedScreenshot(wd)
+	}
+	if HasErrorCode(err, CodeUnknownCommand, "unknown method") {
+		return stitchedScreenshot(wd)
+	}
+	return buf, err
+}
+
+func decodeBase64(data string) ([]byte, error) {
+	decoder := base64.NewDecoder(base64.StdEncoding, bytes.NewBufferString(data))
+	return ioutil.ReadAll(decoder)
+}
+
+// mozFullPageScreenshot uses the full-page screenshot command of geckodriver.
+func (wd *remoteWD) mozFullPageScreenshot() ([]byte, error) {
+	data, err := wd.stringCommand("/session/%s/moz/screenshot/full")
+	if err != nil {
+		return nil, err
+	}
+	return decodeBase64(data)
+}
+
+// cdpFullPageScreenshot captures the page beyond the viewport using the
+// Chrome DevTools Protocol.
+func (wd *remoteWD) cdpFullPageScreenshot() ([]byte, error) {
+	response, err := wd.executeCDP("Page.getLayoutMetrics", nil)
+	if err != nil {
+		return nil, err
+	}
+	type size struct{ Width, Height float64 }
+	metrics := new(struct {
+		// CSSContentSize is reported by Chrome 92 and later, in CSS pixels.
+		CSSContentSize *size
+		ContentSize    size
+	})
+	if err := json.Unmarshal(response, metrics); err != nil {
+		return nil, err
+	}
+	content := metrics.ContentSize
+	if metrics.CSSContentSize != nil {
+		content = *metrics.CSSContentSize
+	}
+
+	response, err = wd.executeCDP("Page.captureScreenshot", map[string]interface{}{
+		"format":                "png",
+		"captureBeyondViewport": true,
+		"clip": map[string]interface{}{
+			"x":      0,
+			"y":      0,
+			"width":  content.Width,
+			"height": content.Height,
+			"scale":  1,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	screenshot := new(struct{ Data string })
+	if err := json.Unmarshal(response, screenshot); err != nil {
+		return nil, err
+	}
+	return decodeBase64(screenshot.Data)
+}
+
+// pageMetricsScript returns the size of the page and the viewport, in CSS
+// pixels, the scroll position and the device pixel ratio.
+const pageMetricsScript = `
+var d = document.documentElement, b = document.body;
+return {
+	width: Math.max(d.scrollWidth, b ? b.scrollWidth : 0),
+	height: Math.max(d.scrollHeight, b ? b.scrollHeight : 0),
+	viewportWidth: d.clientWidth,
+	viewportHeight: d.clientHeight,
+	x: window.pageXOffset,
+	y: window.pageYOffset,
+	ratio: window.devicePixelRatio || 1
+};
+`
+
+// scrollScript scrolls to a position and returns the actual scroll position,
+// which differs at the end of the page.
+const scrollScript = `
+window.scrollTo(arguments[0], arguments[1]);
+return [window.pageXOffset, window.pageYOffset];
+`
+
+// hideFixedScript hides the fixed and sticky elements, so that they only
+// appear in the first segment of a stitched screenshot.
+const hideFixedScript = `
+document.querySelectorAll('body *').forEach(function(e) {
+	var p = window.getComputedStyle(e).position;
+	if ((p === 'fixed' || p === 'sticky') && !e.hasAttribute('data-selenium-visibility')) {
+		e.setAttribute('data-selenium-visibility', e.style.visibility);
+		e.style.visibility = 'hidden';
+	}
+});
+`
+
+// showFixedScript restores the elements hidden by hideFixedScript.
+const showFixedScript = `
+document.querySelectorAll('[data-selenium-visibility]').forEach(function(e) {
+	e.style.visibility = e.getAttribute('data-selenium-visibility');
+	e.removeAttribute('data-selenium-visibility');
+});
+`
+
+// stitchedScreenshot scrolls through the page of wd and stitches together
+// screenshots of the viewport.
+func stitchedScreenshot(wd WebDriver) (buf []byte, err error) {
+	response, err := wd.ExecuteScriptRaw(pageMetricsScript, nil)
+	if err != nil {
+		return nil, err
+	}
+	reply := new(struct {
+		Value struct {
+			Width, Height                 float64
+			ViewportWidth, ViewportHeight float64
+			X, Y                          float64
+			Ratio                         float64
+		}
+	})
+	if err := json.Unmarshal(response, reply); err != nil {
+		return nil, err
+	}
+	m := reply.Value
+	if m.ViewportWidth <= 0 || m.ViewportHeight <= 0 {
+		return nil, fmt.Errorf("invalid viewport size %vx%v", m.ViewportWidth, m.ViewportHeight)
+	}
+
+	defer func() {
+		if _, serr := wd.ExecuteScript(showFixedScript, nil); serr != nil && err == nil {
+			err = serr
+		}
+		if _, serr := wd.ExecuteScript(scrollScript, []interface{}{m.X, m.Y}); serr != nil && err == nil {
+			err = serr
+		}
+	}()
+
+	scale := func(v float64) int { return int(math.Round(v * m.Ratio)) }
+	viewport := image.Rect(0, 0, scale(m.ViewportWidth), scale(m.ViewportHeight))
+	page := image.NewRGBA(image.Rect(0, 0, scale(m.Width), scale(m.Height)))
+	first := true
+	for y := 0.0; y < m.Height; y += m.ViewportHeight {
+		for x := 0.0; x < m.Width; x += m.ViewportWidth {
+			response, err := wd.ExecuteScriptRaw(scrollScript, []interface{}{x, y})
+			if err != nil {
+				return nil, err
+			}
+			pos := new(struct{ Value []float64 })
+			if err := json.Unmarshal(response, pos); err != nil {
+				return nil, err
+			}
+			if len(pos.Value) != 2 {
+				return nil, fmt.Errorf("invalid scroll position %v", pos.Value)
+			}
+
+			img, err := ScreenshotImage(wd, nil)
+			if err != nil {
+				return nil, err
+			}
+			at := image.Pt(scale(pos.Value[0]), scale(pos.Value[1]))
+			draw.Draw(page, viewport.Add(at), img, img.Bounds().Min, draw.Src)
+
+			if first {
+				if _, err := wd.ExecuteScript(hideFixedScript, nil); err != nil {
+					return nil, err
+				}
+				first = false
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	if err := png.Encode(&out, page); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
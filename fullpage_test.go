@@ -0,0 +1,156 @@
+package selenium
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// scrollingPage is a page of 10×25 CSS pixels in a viewport of 10×10. The
+// screenshots of the viewport have a gray level equal to the row of the page
+// each pixel is on.
+type scrollingPage struct {
+	y       int
+	scripts []string
+	// cdp is the reply to CDP commands, keyed by method. If nil, CDP commands
+	// are not supported.
+	cdp map[string]interface{}
+}
+
+func encodePNG(t *testing.T, img image.Image) string {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Errorf("png.Encode() returned error: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+// serve makes d reply to the commands on the page.
+func (p *scrollingPage) serve(t *testing.T, d *fakeDriver) {
+	if p.cdp != nil {
+		d.handle("POST", "/goog/cdp/execute", func(r *fakeRequest) (interface{}, error) {
+			var body struct{ Cmd string }
+			r.decode(t, &body)
+			return p.cdp[body.Cmd], nil
+		})
+	}
+	d.handle("GET", "/screenshot", func(*fakeRequest) (interface{}, error) {
+		img := image.NewGray(image.Rect(0, 0, 10, 10))
+		for y := 0; y < 10; y++ {
+			for x := 0; x < 10; x++ {
+				img.SetGray(x, y, color.Gray{uint8(p.y + y)})
+			}
+		}
+		return encodePNG(t, img), nil
+	})
+	d.handle("POST", "/execute/sync", func(r *fakeRequest) (interface{}, error) {
+		var body struct {
+			Script string
+			Args   []float64
+		}
+		r.decode(t, &body)
+		switch {
+		case strings.Contains(body.Script, "scrollWidth"):
+			p.scripts = append(p.scripts, "metrics")
+			return map[string]interface{}{
+				"width": 10, "height": 25, "viewportWidth": 10, "viewportHeight": 10, "x": 0, "y": 3, "ratio": 1,
+			}, nil
+		case strings.Contains(body.Script, "scrollTo"):
+			p.y = int(body.Args[1])
+			if p.y > 15 {
+				p.y = 15
+			}
+			p.scripts = append(p.scripts, "scroll")
+			return []int{0, p.y}, nil
+		case strings.Contains(body.Script, "style.visibility = 'hidden'"):
+			p.scripts = append(p.scripts, "hide")
+		case strings.Contains(body.Script, "removeAttribute"):
+			p.scripts = append(p.scripts, "show")
+		}
+		return nil, nil
+	})
+}
+
+func TestStitchedScreenshot(t *testing.T) {
+	for _, browser := range []string{"htmlunit", "firefox", "chrome"} {
+		s := &scrollingPage{}
+		d := newFakeDriver(t)
+		s.serve(t, d)
+		wd := d.driver(browser)
+		buf, err := wd.FullPageScreenshot()
+		if err != nil {
+			t.Errorf("%s: FullPageScreenshot() returned error: %v", browser, err)
+			continue
+		}
+		img, err := png.Decode(bytes.NewReader(buf))
+		if err != nil {
+			t.Errorf("%s: png.Decode() returned error: %v", browser, err)
+			continue
+		}
+		if got, want := img.Bounds(), image.Rect(0, 0, 10, 25); got != want {
+			t.Errorf("%s: FullPageScreenshot() bounds = %v, want %v", browser, got, want)
+			continue
+		}
+		for y := 0; y < 25; y++ {
+			if got := color.GrayModel.Convert(img.At(5, y)).(color.Gray).Y; int(got) != y {
+				t.Errorf("%s: FullPageScreenshot() gray level of row %d = %d, want %d", browser, y, got, y)
+			}
+		}
+		want := []string{"metrics", "scroll", "hide", "scroll", "scroll", "show", "scroll"}
+		if !reflect.DeepEqual(s.scripts, want) {
+			t.Errorf("%s: executed scripts %v, want %v", browser, s.scripts, want)
+		}
+		if s.y != 3 {
+			t.Errorf("%s: scroll position after FullPageScreenshot() = %d, want 3", browser, s.y)
+		}
+	}
+}
+
+func TestCDPFullPageScreenshot(t *testing.T) {
+	page := encodePNG(t, image.NewGray(image.Rect(0, 0, 10, 25)))
+	s := &scrollingPage{cdp: map[string]interface{}{
+		"Page.getLayoutMetrics": map[string]interface{}{
+			"contentSize":    map[string]int{"width": 20, "height": 50},
+			"cssContentSize": map[string]int{"width": 10, "height": 25},
+		},
+		"Page.captureScreenshot": map[string]string{"data": page},
+	}}
+	d := newFakeDriver(t)
+	s.serve(t, d)
+
+	wd := d.driver("chrome")
+	buf, err := wd.FullPageScreenshot()
+	if err != nil {
+		t.Fatalf("FullPageScreenshot() returned error: %v", err)
+	}
+	if got := base64.StdEncoding.EncodeToString(buf); got != page {
+		t.Errorf("FullPageScreenshot() did not return the CDP screenshot")
+	}
+	if len(s.scripts) != 0 {
+		t.Errorf("FullPageScreenshot() executed scripts %v, want none", s.scripts)
+	}
+}
+
+func TestFullPageScreenshotOtherDriver(t *testing.T) {
+	s := &scrollingPage{}
+	d := newFakeDriver(t)
+	s.serve(t, d)
+	// Other WebDrivers are supported by stitching screenshots.
+	wd := struct{ WebDriver }{d.driver("firefox")}
+	buf, err := FullPageScreenshot(wd)
+	if err != nil {
+		t.Fatalf("FullPageScreenshot() returned error: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatalf("png.Decode() returned error: %v", err)
+	}
+	if got, want := img.Bounds(), image.Rect(0, 0, 10, 25); got != want {
+		t.Errorf("FullPageScreenshot() bounds = %v, want %v", got, want)
+	}
+}
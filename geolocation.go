@@ -0,0 +1,185 @@
+package selenium
+
+import (
+	"errors"
+	"fmt"
+)
+
+// GeolocationEmulator makes the browser report a given position.
+type GeolocationEmulator interface {
+	// SetGeolocation makes the browser report the given position, in
+	// degrees, with the given accuracy, in meters. The pages need the
+	// "geolocation" permission, which SetPermission grants.
+	//
+	// On Chromium-based browsers, the position is set through the Chrome
+	// DevTools Protocol. Servers that do not forward its commands are sent
+	// the legacy location command of ChromeDriver instead, which ignores the
+	// accuracy.
+	//
+	// On other browsers, the position is reported by a script that replaces
+	// navigator.geolocation in the current document, and in the documents
+	// loaded afterwards by Get, Refresh, Back and Forward. The script runs
+	// after the scripts of the page, which may already have read the
+	// position, and is not run in the documents loaded by the page itself,
+	// such as by clicking a link or submitting a form. The navigations do
+	// not fail if the script cannot be run: the script is skipped while an
+	// alert is open, so that the alert is not dismissed, and the error is
+	// returned by GeolocationError.
+	SetGeolocation(latitude, longitude, accuracy float64) error
+	// GeolocationError returns the error that prevented the script set by
+	// SetGeolocation from running after the latest navigation, or nil if it
+	// ran or is not needed.
+	GeolocationError() error
+}
+
+// SetGeolocation makes the browser of wd report the given position, as
+// described by GeolocationEmulator. It returns an error wrapping
+// ErrUnsupported if wd does not implement GeolocationEmulator.
+func SetGeolocation(wd WebDriver, latitude, longitude, accuracy float64) error {
+	e, ok := wd.(GeolocationEmulator)
+	if !ok {
+		return fmt.Errorf("%w: the WebDriver %T cannot emulate a geolocation", ErrUnsupported, wd)
+	}
+	return e.SetGeolocation(latitude, longitude, accuracy)
+}
+
+// GeolocationError returns the error of the latest injection of the
+// position set by SetGeolocation, as described by GeolocationEmulator, or
+// nil if wd does not implement GeolocationEmulator.
+func GeolocationError(wd WebDriver) error {
+	e, ok := wd.(GeolocationEmulator)
+	if !ok {
+		return nil
+	}
+	return e.GeolocationError()
+}
+
+// PermissionSetter sets the permissions of the pages.
+type PermissionSetter interface {
+	// SetPermission sets the state of the permission with the given name,
+	// such as "geolocation", to PermissionGranted, PermissionDenied or
+	// PermissionPrompt, as defined by the W3C Permissions specification. It
+	// returns an error wrapping ErrUnsupported if the driver does not
+	// implement it.
+	SetPermission(name, state string) error
+}
+
+// SetPermission sets the state of a permission in the browser of wd, as
+// described by PermissionSetter. It returns an error wrapping
+// ErrUnsupported if wd does not implement PermissionSetter.
+func SetPermission(wd WebDriver, name, state string) error {
+	s, ok := wd.(PermissionSetter)
+	if !ok {
+		return fmt.Errorf("%w: the WebDriver %T cannot set permissions", ErrUnsupported, wd)
+	}
+	return s.SetPermission(name, state)
+}
+
+// geolocation is a position emulated by an injected script.
+type geolocation struct {
+	latitude, longitude, accuracy float64
+}
+
+// geolocationScript overrides navigator.geolocation in the current document
+// with an implementation that reports the position given as arguments.
+const geolocationScript = `
+var coords = {
+	latitude: arguments[0],
+	longitude: arguments[1],
+	accuracy: arguments[2],
+	altitude: null,
+	altitudeAccuracy: null,
+	heading: null,
+	speed: null
+};
+var report = function(success) {
+	setTimeout(function() { success({coords: coords, timestamp: Date.now()}); }, 0);
+};
+var watches = 0;
+Object.defineProperty(navigator, 'geolocation', {
+	configurable: true,
+	value: {
+		getCurrentPosition: function(success) { report(success); },
+		watchPosition: function(success) { report(success); return ++watches; },
+		clearWatch: function() {}
+	}
+});
+`
+
+// errAlertOpen is recorded instead of running geolocationScript while an
+// alert is open, since running it would dismiss the alert.
+var errAlertOpen = errors.New("the geolocation script was not run because an alert is open")
+
+// runGeolocationScript runs geolocationScript in the current document, unless
+// an alert is open.
+func (wd *remoteWD) runGeolocationScript() error {
+	_, err := wd.AlertText()
+	if err == nil {
+		return errAlertOpen
+	}
+	if !HasErrorCode(err, CodeNoSuchAlert) {
+		return err
+	}
+	g := wd.geolocation
+	_, err = wd.ExecuteScriptRaw(geolocationScript, []interface{}{g.latitude, g.longitude, g.accuracy})
+	return err
+}
+
+// injectGeolocation runs geolocationScript in a newly loaded document if
+// SetGeolocation emulates a position with it, and records its error for
+// GeolocationError.
+func (wd *remoteWD) injectGeolocation() {
+	if wd.geolocation == nil {
+		return
+	}
+	wd.geolocationErr = wd.runGeolocationScript()
+}
+
+func (wd *remoteWD) SetGeolocation(latitude, longitude, accuracy float64) error {
+	if wd.cdpVendor() == "" {
+		wd.geolocation = &geolocation{latitude, longitude, accuracy}
+		wd.geolocationErr = wd.runGeolocationScript()
+		return wd.geolocationErr
+	}
+
+	_, err := wd.executeCDP("Emulation.setGeolocationOverride", map[string]float64{
+		"latitude":  latitude,
+		"longitude": longitude,
+		"accuracy":  accuracy,
+	})
+	if !HasErrorCode(err, CodeUnknownCommand) {
+		return err
+	}
+	// The server does not forward Chrome DevTools Protocol commands. The
+	// location command of ChromeDriver has a fixed accuracy, so accuracy is
+	// ignored.
+	return wd.voidCommand("/session/%s/location", map[string]interface{}{
+		"location": map[string]float64{
+			"latitude":  latitude,
+			"longitude": longitude,
+			"altitude":  0,
+		},
+	})
+}
+
+func (wd *remoteWD) GeolocationError() error {
+	return wd.geolocationErr
+}
+
+// Permission states, as accepted by SetPermission.
+const (
+	PermissionGranted = "granted"
+	PermissionDenied  = "denied"
+	PermissionPrompt  = "prompt"
+)
+
+func (wd *remoteWD) SetPermission(name, state string) error {
+	err := wd.voidCommand("/session/%s/permissions", map[string]interface{}{
+		"descriptor": map[string]string{"name": name},
+		"state":      state,
+	})
+	if HasErrorCode(err, CodeUnknownCommand) {
+		return fmt.Errorf("%w: the driver does not implement the Permissions extension: %v", ErrUnsupported, err)
+	}
+	return err
+}
@@ -0,0 +1,223 @@
+package selenium
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// bodyRecorder is a fakeDriver that replies with null to every command but
+// those made to fail, and records the bodies of the POST commands.
+type bodyRecorder struct {
+	*fakeDriver
+
+	mu     sync.Mutex
+	errors map[string]*Error
+	bodies []map[string]interface{}
+}
+
+func newBodyRecorder(t *testing.T) *bodyRecorder {
+	d := &bodyRecorder{fakeDriver: newFakeDriver(t), errors: make(map[string]*Error)}
+	d.handle("", "", func(r *fakeRequest) (interface{}, error) {
+		var body map[string]interface{}
+		if r.Method == "POST" {
+			r.decode(t, &body)
+		}
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.bodies = append(d.bodies, body)
+		if err, ok := d.errors[r.Path]; ok {
+			return nil, err
+		}
+		return nil, nil
+	})
+	return d
+}
+
+// setError makes the driver reply to the commands with the given path,
+// relative to the session, with err, or with null if err is nil.
+func (d *bodyRecorder) setError(path string, err *Error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err == nil {
+		delete(d.errors, path)
+		return
+	}
+	d.errors[path] = err
+}
+
+// setUnknown makes the driver reply to the commands with the given path with
+// an unknown command error.
+func (d *bodyRecorder) setUnknown(path string) {
+	d.setError(path, &Error{Err: CodeUnknownCommand, Message: path, HTTPCode: http.StatusNotFound})
+}
+
+// noAlert makes the driver reply that no alert is open.
+func (d *bodyRecorder) noAlert() {
+	d.setError("/alert/text", &Error{Err: CodeNoSuchAlert, Message: "no such alert", HTTPCode: http.StatusNotFound})
+}
+
+// receivedBodies returns the bodies of the commands received so far, in
+// order.
+func (d *bodyRecorder) receivedBodies() []map[string]interface{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]map[string]interface{}(nil), d.bodies...)
+}
+
+func TestSetGeolocationChrome(t *testing.T) {
+	d := newBodyRecorder(t)
+	wd := d.driver("chrome")
+
+	if err := SetGeolocation(wd, 48.85, 2.35, 10); err != nil {
+		t.Fatalf("SetGeolocation() returned error: %v", err)
+	}
+	// A server that does not forward CDP commands.
+	d.setUnknown("/goog/cdp/execute")
+	if err := SetGeolocation(wd, 35.68, 139.69, 10); err != nil {
+		t.Fatalf("SetGeolocation() returned error: %v", err)
+	}
+
+	wantCommands := []string{"POST /goog/cdp/execute", "POST /goog/cdp/execute", "POST /location"}
+	if diff := cmp.Diff(wantCommands, d.received()); diff != "" {
+		t.Fatalf("SetGeolocation() sent commands with diff (-want +got):\n%s", diff)
+	}
+	wantBodies := []map[string]interface{}{
+		{"cmd": "Emulation.setGeolocationOverride", "params": map[string]interface{}{"latitude": 48.85, "longitude": 2.35, "accuracy": 10.0}},
+		{"cmd": "Emulation.setGeolocationOverride", "params": map[string]interface{}{"latitude": 35.68, "longitude": 139.69, "accuracy": 10.0}},
+		{"location": map[string]interface{}{"latitude": 35.68, "longitude": 139.69, "altitude": 0.0}},
+	}
+	if diff := cmp.Diff(wantBodies, d.receivedBodies()); diff != "" {
+		t.Errorf("SetGeolocation() sent bodies with diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestSetGeolocationScript(t *testing.T) {
+	d := newBodyRecorder(t)
+	d.noAlert()
+	wd := d.driver("firefox")
+
+	if err := wd.Get("http://example.com/"); err != nil {
+		t.Fatalf("Get() returned error: %v", err)
+	}
+	if err := SetGeolocation(wd, 48.85, 2.35, 10); err != nil {
+		t.Fatalf("SetGeolocation() returned error: %v", err)
+	}
+	if err := wd.Get("http://example.com/map"); err != nil {
+		t.Fatalf("Get() returned error: %v", err)
+	}
+	if err := wd.Refresh(); err != nil {
+		t.Fatalf("Refresh() returned error: %v", err)
+	}
+	if err := wd.Back(); err != nil {
+		t.Fatalf("Back() returned error: %v", err)
+	}
+	if err := wd.Forward(); err != nil {
+		t.Fatalf("Forward() returned error: %v", err)
+	}
+	if err := GeolocationError(wd); err != nil {
+		t.Errorf("GeolocationError() = %v, want nil", err)
+	}
+
+	want := []string{
+		"POST /url",
+		"GET /alert/text", "POST /execute/sync",
+		"POST /url",
+		"GET /alert/text", "POST /execute/sync",
+		"POST /refresh",
+		"GET /alert/text", "POST /execute/sync",
+		"POST /back",
+		"GET /alert/text", "POST /execute/sync",
+		"POST /forward",
+		"GET /alert/text", "POST /execute/sync",
+	}
+	if diff := cmp.Diff(want, d.received()); diff != "" {
+		t.Fatalf("the driver received commands with diff (-want +got):\n%s", diff)
+	}
+	bodies := d.receivedBodies()
+	for i, c := range want {
+		if c != "POST /execute/sync" {
+			continue
+		}
+		if bodies[i]["script"] != geolocationScript {
+			t.Errorf("command %d executed script %q", i, bodies[i]["script"])
+		}
+		if diff := cmp.Diff([]interface{}{48.85, 2.35, 10.0}, bodies[i]["args"]); diff != "" {
+			t.Errorf("command %d sent arguments with diff (-want +got):\n%s", i, diff)
+		}
+	}
+
+	// Other WebDrivers cannot emulate a geolocation.
+	if err := SetGeolocation(struct{ WebDriver }{wd}, 48.85, 2.35, 10); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("SetGeolocation() with another WebDriver returned error %v, want ErrUnsupported", err)
+	}
+}
+
+func TestSetGeolocationScriptAlert(t *testing.T) {
+	d := newBodyRecorder(t)
+	d.noAlert()
+	wd := d.driver("firefox")
+	if err := SetGeolocation(wd, 48.85, 2.35, 10); err != nil {
+		t.Fatalf("SetGeolocation() returned error: %v", err)
+	}
+
+	// The page shows an alert when it loads: the navigation succeeds and the
+	// alert is left open.
+	d.setError("/alert/text", nil)
+	n := len(d.received())
+	if err := wd.Get("http://example.com/alert"); err != nil {
+		t.Fatalf("Get() of a page with an alert returned error: %v", err)
+	}
+	if got, want := d.received()[n:], []string{"POST /url", "GET /alert/text"}; !cmp.Equal(got, want) {
+		t.Errorf("Get() of a page with an alert sent %q, want %q", got, want)
+	}
+	if err := GeolocationError(wd); err == nil {
+		t.Errorf("GeolocationError() after an alert returned nil")
+	}
+
+	// A script that fails does not fail the navigation either.
+	d.noAlert()
+	scriptErr := &Error{Err: "javascript error", Message: "boom", HTTPCode: http.StatusInternalServerError}
+	d.setError("/execute/sync", scriptErr)
+	if err := wd.Refresh(); err != nil {
+		t.Fatalf("Refresh() returned error: %v", err)
+	}
+	if err := GeolocationError(wd); !HasErrorCode(err, "javascript error") {
+		t.Errorf("GeolocationError() = %v, want the error of the script", err)
+	}
+
+	d.setError("/execute/sync", nil)
+	if err := wd.Back(); err != nil {
+		t.Fatalf("Back() returned error: %v", err)
+	}
+	if err := GeolocationError(wd); err != nil {
+		t.Errorf("GeolocationError() after a successful injection = %v, want nil", err)
+	}
+}
+
+func TestSetPermission(t *testing.T) {
+	d := newBodyRecorder(t)
+	wd := d.driver("chrome")
+
+	if err := SetPermission(wd, "geolocation", PermissionGranted); err != nil {
+		t.Fatalf("SetPermission() returned error: %v", err)
+	}
+	want := map[string]interface{}{"descriptor": map[string]interface{}{"name": "geolocation"}, "state": "granted"}
+	if diff := cmp.Diff(want, d.receivedBodies()[0]); diff != "" {
+		t.Errorf("SetPermission() sent diff (-want +got):\n%s", diff)
+	}
+	if want := "POST /permissions"; d.received()[0] != want {
+		t.Errorf("SetPermission() sent %q, want %q", d.received()[0], want)
+	}
+
+	d.setUnknown("/permissions")
+	if err := SetPermission(wd, "notifications", PermissionDenied); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("SetPermission() on a driver without permissions returned error %v, want ErrUnsupported", err)
+	}
+	if err := SetPermission(struct{ WebDriver }{wd}, "notifications", PermissionDenied); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("SetPermission() with another WebDriver returned error %v, want ErrUnsupported", err)
+	}
+}
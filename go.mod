@@ -1,6 +1,6 @@
 module github.com/tebeka/selenium
 
-go 1.12
+go 1.14
 
 require (
 	cloud.google.com/go v0.41.0
@@ -12,5 +12,6 @@ require (
 	github.com/google/go-cmp v0.3.0
 	github.com/google/go-github/v27 v27.0.4
 	github.com/mediabuyerbot/go-crx3 v1.3.1
+	golang.org/x/net v0.0.0-20190620200207-3b0461eec859
 	google.golang.org/api v0.7.0
-)
\ No newline at end of file
+)
@@ -0,0 +1,202 @@
+package har
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/tebeka/selenium/bidi"
+)
+
+// bidiEvents are the network events that make up the entries of an archive.
+var bidiEvents = []string{bidi.EventBeforeRequestSent, bidi.EventResponseCompleted, bidi.EventFetchError}
+
+// FromBiDi builds an archive from the network events of WebDriver BiDi named
+// bidi.EventBeforeRequestSent, bidi.EventResponseCompleted and
+// bidi.EventFetchError, in the order they were received. Requests that have
+// neither completed nor failed are omitted.
+func FromBiDi(events []bidi.NetworkEvent) *HAR {
+	// requests holds the sent requests in order; each redirect has the ID of
+	// the request it follows, and a higher redirect count.
+	type key struct {
+		id       string
+		redirect int
+	}
+	type request struct {
+		sent, end *bidi.NetworkEvent
+	}
+	var order []key
+	requests := make(map[key]*request)
+	for i := range events {
+		e := &events[i]
+		k := key{e.Request.Request, e.RedirectCount}
+		r, ok := requests[k]
+		if !ok {
+			r = new(request)
+			requests[k] = r
+			order = append(order, k)
+		}
+		// The sent request is taken from the first event, for the events that
+		// precede the subscription to bidi.EventBeforeRequestSent.
+		if r.sent == nil {
+			r.sent = e
+		}
+		if e.Response != nil || e.ErrorText != "" {
+			r.end = e
+		}
+	}
+
+	var entries []Entry
+	for _, k := range order {
+		if r := requests[k]; r.end != nil {
+			entries = append(entries, bidiEntry(r.sent, r.end))
+		}
+	}
+	return newHAR(entries)
+}
+
+// bidiHeaders converts headers, skipping the values that cannot be decoded.
+func bidiHeaders(headers []bidi.Header) []NameValue {
+	list := []NameValue{}
+	for _, h := range headers {
+		if v, err := h.Value.Bytes(); err == nil {
+			list = append(list, NameValue{h.Name, string(v)})
+		}
+	}
+	return list
+}
+
+func bidiEntry(sent, end *bidi.NetworkEvent) Entry {
+	req := end.Request
+	e := Entry{
+		StartedDateTime: time.Unix(0, sent.Timestamp*int64(time.Millisecond)).UTC(),
+		Request: Request{
+			Method:      req.Method,
+			URL:         req.URL,
+			Headers:     bidiHeaders(req.Headers),
+			QueryString: queryString(req.URL),
+			HeadersSize: req.HeadersSize,
+		},
+		Response: Response{
+			Cookies:     []Cookie{},
+			Headers:     []NameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+			Error:       end.ErrorText,
+		},
+	}
+	if e.Request.HeadersSize == 0 {
+		e.Request.HeadersSize = -1
+	}
+	e.Request.Cookies = requestCookies(e.Request.Headers)
+	if req.BodySize != nil {
+		e.Request.BodySize = *req.BodySize
+	}
+
+	if resp := end.Response; resp != nil {
+		e.Request.HTTPVersion = httpVersion(resp.Protocol)
+		e.Response.Status = resp.Status
+		e.Response.StatusText = resp.StatusText
+		e.Response.HTTPVersion = e.Request.HTTPVersion
+		e.Response.Headers = bidiHeaders(resp.Headers)
+		e.Response.Cookies = responseCookies(e.Response.Headers)
+		e.Response.RedirectURL = header(e.Response.Headers, "Location")
+		e.Response.Content.MIMEType = resp.MIMEType
+		if resp.HeadersSize != nil {
+			e.Response.HeadersSize = *resp.HeadersSize
+		}
+		if resp.BodySize != nil {
+			e.Response.BodySize = *resp.BodySize
+			e.Response.Content.Size = *resp.BodySize
+		}
+		e.Response.TransferSize = resp.BytesReceived
+	}
+
+	e.Timings = bidiTimings(req.Timings)
+	e.Time = e.Timings.total()
+	return e
+}
+
+// bidiTimings returns the timings of a request, whose fetch timing info are
+// in milliseconds since the time origin, and zero for the phases the request
+// skipped.
+func bidiTimings(ti bidi.FetchTimingInfo) Timings {
+	t := Timings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1}
+	if ti.DNSStart > 0 {
+		t.DNS = math.Max(0, ti.DNSEnd-ti.DNSStart)
+	}
+	if ti.ConnectStart > 0 {
+		t.Connect = math.Max(0, ti.ConnectEnd-ti.ConnectStart)
+	}
+	if ti.TLSStart > 0 {
+		t.SSL = math.Max(0, ti.ConnectEnd-ti.TLSStart)
+	}
+	if ti.FetchStart > 0 {
+		// The request is blocked until the first of its phases starts.
+		blockedEnd := ti.RequestStart
+		for _, start := range []float64{ti.ConnectStart, ti.DNSStart} {
+			if start > 0 {
+				blockedEnd = start
+			}
+		}
+		t.Blocked = math.Max(0, blockedEnd-ti.FetchStart)
+	}
+	if ti.RequestStart > 0 && ti.ResponseStart > 0 {
+		t.Wait = math.Max(0, ti.ResponseStart-ti.RequestStart)
+	}
+	if ti.ResponseStart > 0 && ti.ResponseEnd > 0 {
+		t.Receive = math.Max(0, ti.ResponseEnd-ti.ResponseStart)
+	}
+	return t
+}
+
+// Recorder records the network events of WebDriver BiDi to build an archive.
+// It is safe for concurrent use.
+type Recorder struct {
+	conn    *bidi.Conn
+	removes []func()
+
+	mu     sync.Mutex
+	events []bidi.NetworkEvent
+}
+
+// Record starts recording the network events of conn, for all the browsing
+// contexts. The recording must be stopped with Stop.
+func Record(ctx context.Context, conn *bidi.Conn) (*Recorder, error) {
+	r := &Recorder{conn: conn}
+	for _, method := range bidiEvents {
+		r.removes = append(r.removes, conn.OnNetworkEvent(method, r.add))
+	}
+	if err := conn.Subscribe(ctx, bidiEvents, nil); err != nil {
+		r.remove()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Recorder) add(e bidi.NetworkEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *Recorder) remove() {
+	for _, remove := range r.removes {
+		remove()
+	}
+}
+
+// HAR returns an archive of the requests completed so far.
+func (r *Recorder) HAR() *HAR {
+	r.mu.Lock()
+	events := append([]bidi.NetworkEvent(nil), r.events...)
+	r.mu.Unlock()
+	return FromBiDi(events)
+}
+
+// Stop stops the recording. The archive remains available from HAR.
+func (r *Recorder) Stop(ctx context.Context) error {
+	r.remove()
+	return r.conn.Unsubscribe(ctx, bidiEvents, nil)
+}
@@ -0,0 +1,340 @@
+// Package har exports the network activity of a browser session as an HTTP
+// Archive (HAR) 1.2, built from the Chrome performance log or from the
+// network events of WebDriver BiDi.
+//
+// The HAR format is documented at
+// http://www.softwareishard.com/blog/har-12-spec/.
+package har
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Version is the version of the HAR format of the archives.
+const Version = "1.2"
+
+// HAR is an HTTP Archive.
+type HAR struct {
+	Log Log `json:"log"`
+}
+
+// Log is the root of the archive.
+type Log struct {
+	Version string  `json:"version"`
+	Creator Creator `json:"creator"`
+	Entries []Entry `json:"entries"`
+}
+
+// Creator describes the application that created the archive.
+type Creator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Entry is a request and its response.
+type Entry struct {
+	StartedDateTime time.Time `json:"startedDateTime"`
+	// Time is the total time of the request in milliseconds, the sum of its
+	// timings.
+	Time            float64  `json:"time"`
+	Request         Request  `json:"request"`
+	Response        Response `json:"response"`
+	Cache           struct{} `json:"cache"`
+	Timings         Timings  `json:"timings"`
+	ServerIPAddress string   `json:"serverIPAddress,omitempty"`
+	Connection      string   `json:"connection,omitempty"`
+}
+
+// NameValue is a header or a query string parameter.
+type NameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Cookie is a cookie sent with a request or set by a response.
+type Cookie struct {
+	Name     string     `json:"name"`
+	Value    string     `json:"value"`
+	Path     string     `json:"path,omitempty"`
+	Domain   string     `json:"domain,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	HTTPOnly bool       `json:"httpOnly,omitempty"`
+	Secure   bool       `json:"secure,omitempty"`
+}
+
+// PostData is the body of a request.
+type PostData struct {
+	MIMEType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// Request describes a request. Sizes are in bytes, and -1 if unknown.
+type Request struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []Cookie    `json:"cookies"`
+	Headers     []NameValue `json:"headers"`
+	QueryString []NameValue `json:"queryString"`
+	PostData    *PostData   `json:"postData,omitempty"`
+	HeadersSize int64       `json:"headersSize"`
+	BodySize    int64       `json:"bodySize"`
+}
+
+// Response describes a response. Sizes are in bytes, and -1 if unknown. The
+// response of a failed request has status 0 and the error in Error.
+type Response struct {
+	Status      int         `json:"status"`
+	StatusText  string      `json:"statusText"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []Cookie    `json:"cookies"`
+	Headers     []NameValue `json:"headers"`
+	Content     Content     `json:"content"`
+	RedirectURL string      `json:"redirectURL"`
+	HeadersSize int64       `json:"headersSize"`
+	BodySize    int64       `json:"bodySize"`
+	// TransferSize is the number of bytes received, headers included.
+	TransferSize int64  `json:"_transferSize,omitempty"`
+	Error        string `json:"_error,omitempty"`
+}
+
+// Content describes the body of a response.
+type Content struct {
+	// Size is the length of the body after decoding.
+	Size     int64  `json:"size"`
+	MIMEType string `json:"mimeType"`
+}
+
+// Timings are the durations of the phases of a request in milliseconds.
+// Blocked, DNS, Connect and SSL are -1 for the phases the request skipped.
+// Connect includes SSL.
+type Timings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// total returns the sum of the timings.
+func (t Timings) total() float64 {
+	total := t.Send + t.Wait + t.Receive
+	for _, d := range []float64{t.Blocked, t.DNS, t.Connect} {
+		if d > 0 {
+			total += d
+		}
+	}
+	return total
+}
+
+// newHAR returns an archive holding entries.
+func newHAR(entries []Entry) *HAR {
+	if entries == nil {
+		entries = []Entry{}
+	}
+	return &HAR{Log: Log{
+		Version: Version,
+		Creator: Creator{Name: "github.com/tebeka/selenium/har"},
+		Entries: entries,
+	}}
+}
+
+// RedactedHeaders are the headers whose values are redacted by Write, in
+// canonical form.
+var RedactedHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization", "Set-Cookie"}
+
+// Redacted replaces the redacted values.
+const Redacted = "[REDACTED]"
+
+// WriteOptions configures Write.
+type WriteOptions struct {
+	// NoRedaction, if set, writes the values of cookies and of
+	// RedactedHeaders, which are otherwise replaced with Redacted.
+	NoRedaction bool
+	// RedactHeaders are headers to redact in addition to RedactedHeaders.
+	RedactHeaders []string
+	// Indent, if set, indents the JSON with two spaces.
+	Indent bool
+}
+
+// Write writes the archive to w as JSON. The options may be nil.
+func (h *HAR) Write(w io.Writer, opts *WriteOptions) error {
+	if opts == nil {
+		opts = new(WriteOptions)
+	}
+	out := h
+	if !opts.NoRedaction {
+		out = h.redact(append(append([]string(nil), RedactedHeaders...), opts.RedactHeaders...))
+	}
+	enc := json.NewEncoder(w)
+	if opts.Indent {
+		enc.SetIndent("", "  ")
+	}
+	return enc.Encode(out)
+}
+
+// redact returns a copy of the archive in which the values of the cookies
+// and of the given headers are replaced with Redacted.
+func (h *HAR) redact(headers []string) *HAR {
+	redacted := make(map[string]bool, len(headers))
+	for _, name := range headers {
+		redacted[http.CanonicalHeaderKey(name)] = true
+	}
+	redactHeaders := func(list []NameValue) []NameValue {
+		out := make([]NameValue, len(list))
+		for i, nv := range list {
+			if redacted[http.CanonicalHeaderKey(nv.Name)] {
+				nv.Value = Redacted
+			}
+			out[i] = nv
+		}
+		return out
+	}
+	redactCookies := func(list []Cookie) []Cookie {
+		out := make([]Cookie, len(list))
+		for i, c := range list {
+			c.Value = Redacted
+			out[i] = c
+		}
+		return out
+	}
+
+	c := *h
+	c.Log.Entries = make([]Entry, len(h.Log.Entries))
+	for i, e := range h.Log.Entries {
+		e.Request.Headers = redactHeaders(e.Request.Headers)
+		e.Request.Cookies = redactCookies(e.Request.Cookies)
+		e.Response.Headers = redactHeaders(e.Response.Headers)
+		e.Response.Cookies = redactCookies(e.Response.Cookies)
+		c.Log.Entries[i] = e
+	}
+	return &c
+}
+
+// MarshalJSON implements the json.Marshaler interface. It writes the start
+// time of the entry in ISO 8601 format with milliseconds, as the HAR format
+// requires.
+func (e Entry) MarshalJSON() ([]byte, error) {
+	type entry Entry
+	return json.Marshal(struct {
+		StartedDateTime string `json:"startedDateTime"`
+		entry
+	}{e.StartedDateTime.Format("2006-01-02T15:04:05.000Z07:00"), entry(e)})
+}
+
+// headerList converts headers to a list sorted by name, splitting the values
+// joined with newlines.
+func headerList(headers map[string]string) []NameValue {
+	list := []NameValue{}
+	for name, values := range headers {
+		for _, v := range strings.Split(values, "\n") {
+			list = append(list, NameValue{name, v})
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return strings.ToLower(list[i].Name) < strings.ToLower(list[j].Name)
+	})
+	return list
+}
+
+// header returns the values of the header name in list, joined with
+// newlines.
+func header(list []NameValue, name string) string {
+	var values []string
+	for _, nv := range list {
+		if strings.EqualFold(nv.Name, name) {
+			values = append(values, nv.Value)
+		}
+	}
+	return strings.Join(values, "\n")
+}
+
+// queryString returns the parameters of the query of rawURL, in order.
+func queryString(rawURL string) []NameValue {
+	list := []NameValue{}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.RawQuery == "" {
+		return list
+	}
+	for _, param := range strings.Split(u.RawQuery, "&") {
+		if param == "" {
+			continue
+		}
+		name, value := param, ""
+		if i := strings.Index(param, "="); i >= 0 {
+			name, value = param[:i], param[i+1:]
+		}
+		if n, err := url.QueryUnescape(name); err == nil {
+			name = n
+		}
+		if v, err := url.QueryUnescape(value); err == nil {
+			value = v
+		}
+		list = append(list, NameValue{name, value})
+	}
+	return list
+}
+
+// requestCookies returns the cookies of the Cookie headers in list.
+func requestCookies(list []NameValue) []Cookie {
+	r := &http.Request{Header: make(http.Header)}
+	for _, nv := range list {
+		if strings.EqualFold(nv.Name, "Cookie") {
+			r.Header.Add("Cookie", nv.Value)
+		}
+	}
+	cookies := []Cookie{}
+	for _, c := range r.Cookies() {
+		cookies = append(cookies, Cookie{Name: c.Name, Value: c.Value})
+	}
+	return cookies
+}
+
+// responseCookies returns the cookies of the Set-Cookie headers in list.
+func responseCookies(list []NameValue) []Cookie {
+	r := &http.Response{Header: make(http.Header)}
+	for _, nv := range list {
+		if strings.EqualFold(nv.Name, "Set-Cookie") {
+			r.Header.Add("Set-Cookie", nv.Value)
+		}
+	}
+	cookies := []Cookie{}
+	for _, c := range r.Cookies() {
+		cookie := Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Domain:   c.Domain,
+			HTTPOnly: c.HttpOnly,
+			Secure:   c.Secure,
+		}
+		if !c.Expires.IsZero() {
+			expires := c.Expires.UTC()
+			cookie.Expires = &expires
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies
+}
+
+// httpVersion returns the HTTP version of the ALPN protocol ID or name
+// protocol, such as "h2", in the format of the archive.
+func httpVersion(protocol string) string {
+	switch strings.ToLower(protocol) {
+	case "":
+		return ""
+	case "h2", "http/2", "http/2.0":
+		return "HTTP/2.0"
+	case "h3", "http/3", "http/3.0":
+		return "HTTP/3.0"
+	}
+	return strings.ToUpper(protocol)
+}
@@ -0,0 +1,318 @@
+package har
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tebeka/selenium/bidi"
+	"github.com/tebeka/selenium/log"
+	"github.com/tebeka/selenium/perflog"
+	"golang.org/x/net/websocket"
+)
+
+func perfLogMessage(t *testing.T, method string, params interface{}) log.Message {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{
+		"message": map[string]interface{}{"method": method, "params": params},
+	})
+	if err != nil {
+		t.Fatalf("json.Marshal() returned error: %v", err)
+	}
+	return log.Message{Message: string(data)}
+}
+
+func TestFromPerfLog(t *testing.T) {
+	var l perflog.Log
+	err := l.Add([]log.Message{
+		perfLogMessage(t, perflog.EventRequestWillBeSent, map[string]interface{}{
+			"requestId": "1",
+			"request": map[string]interface{}{
+				"url":         "http://example.com/search?q=a%20b&page=2",
+				"method":      "POST",
+				"headers":     map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+				"postData":    "x=1",
+				"hasPostData": true,
+			},
+			"timestamp": 10.0,
+			"wallTime":  1600000000.5,
+		}),
+		perfLogMessage(t, perflog.EventResponseReceived, map[string]interface{}{
+			"requestId": "1",
+			"timestamp": 10.1,
+			"response": map[string]interface{}{
+				"url":        "http://example.com/search?q=a%20b&page=2",
+				"status":     200,
+				"statusText": "OK",
+				"headers":    map[string]string{"Content-Type": "text/html", "Set-Cookie": "sid=secret; Path=/; HttpOnly\nlang=en"},
+				"requestHeaders": map[string]string{
+					"Content-Type":  "application/x-www-form-urlencoded",
+					"Cookie":        "sid=secret; theme=dark",
+					"Authorization": "Bearer token",
+				},
+				"mimeType":        "text/html",
+				"protocol":        "h2",
+				"remoteIPAddress": "127.0.0.1",
+				"connectionId":    7,
+				"timing": map[string]interface{}{
+					"requestTime": 10.002, "proxyStart": -1, "proxyEnd": -1,
+					"dnsStart": 1, "dnsEnd": 3, "connectStart": 3, "connectEnd": 10,
+					"sslStart": 5, "sslEnd": 10, "sendStart": 11, "sendEnd": 12,
+					"receiveHeadersEnd": 50,
+				},
+			},
+		}),
+		perfLogMessage(t, perflog.EventDataReceived, map[string]interface{}{"requestId": "1", "timestamp": 10.11, "dataLength": 1000}),
+		perfLogMessage(t, perflog.EventLoadingFinished, map[string]interface{}{"requestId": "1", "timestamp": 10.122, "encodedDataLength": 600}),
+		perfLogMessage(t, perflog.EventRequestWillBeSent, map[string]interface{}{
+			"requestId": "2",
+			"request":   map[string]interface{}{"url": "http://example.com/pending", "method": "GET"},
+			"timestamp": 10.2,
+		}),
+	})
+	if err != nil {
+		t.Fatalf("Add() returned error: %v", err)
+	}
+
+	h := FromPerfLog(&l)
+	if len(h.Log.Entries) != 1 {
+		t.Fatalf("FromPerfLog() returned %d entries, want 1", len(h.Log.Entries))
+	}
+	e := h.Log.Entries[0]
+	want := Entry{
+		StartedDateTime: time.Unix(1600000000, 5e8).UTC(),
+		Time:            121,
+		Request: Request{
+			Method:      "POST",
+			URL:         "http://example.com/search?q=a%20b&page=2",
+			HTTPVersion: "HTTP/2.0",
+			Cookies:     []Cookie{{Name: "sid", Value: "secret"}, {Name: "theme", Value: "dark"}},
+			Headers: []NameValue{
+				{"Authorization", "Bearer token"},
+				{"Content-Type", "application/x-www-form-urlencoded"},
+				{"Cookie", "sid=secret; theme=dark"},
+			},
+			QueryString: []NameValue{{"q", "a b"}, {"page", "2"}},
+			PostData:    &PostData{MIMEType: "application/x-www-form-urlencoded", Text: "x=1"},
+			HeadersSize: -1,
+			BodySize:    3,
+		},
+		Response: Response{
+			Status:      200,
+			StatusText:  "OK",
+			HTTPVersion: "HTTP/2.0",
+			Cookies:     []Cookie{{Name: "sid", Value: "secret", Path: "/", HTTPOnly: true}, {Name: "lang", Value: "en"}},
+			Headers: []NameValue{
+				{"Content-Type", "text/html"},
+				{"Set-Cookie", "sid=secret; Path=/; HttpOnly"},
+				{"Set-Cookie", "lang=en"},
+			},
+			Content:      Content{Size: 1000, MIMEType: "text/html"},
+			HeadersSize:  -1,
+			BodySize:     -1,
+			TransferSize: 600,
+		},
+		Timings:         Timings{Blocked: 3, DNS: 2, Connect: 7, SSL: 5, Send: 1, Wait: 38, Receive: 70},
+		ServerIPAddress: "127.0.0.1",
+		Connection:      "7",
+	}
+	approx := cmp.Comparer(func(a, b float64) bool { return a-b < 1e-6 && b-a < 1e-6 })
+	if diff := cmp.Diff(want, e, approx); diff != "" {
+		t.Errorf("FromPerfLog() returned entry with diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestFromBiDi(t *testing.T) {
+	size := func(n int64) *int64 { return &n }
+	sent := bidi.NetworkEvent{
+		Timestamp: 1600000000123,
+		Request: bidi.RequestData{
+			Request: "r1",
+			URL:     "https://example.com/old",
+			Method:  "GET",
+			Headers: []bidi.Header{{Name: "Cookie", Value: bidi.StringValue("a=1")}},
+		},
+	}
+	redirected := sent
+	redirected.Response = &bidi.ResponseData{
+		Status:  302,
+		Headers: []bidi.Header{{Name: "Location", Value: bidi.StringValue("/new")}},
+	}
+	next := bidi.NetworkEvent{
+		Timestamp:     1600000000200,
+		RedirectCount: 1,
+		Request: bidi.RequestData{
+			Request:     "r1",
+			URL:         "https://example.com/new",
+			Method:      "GET",
+			HeadersSize: 40,
+			Timings: bidi.FetchTimingInfo{
+				FetchStart: 100, DNSStart: 101, DNSEnd: 103, ConnectStart: 103, TLSStart: 105, ConnectEnd: 110,
+				RequestStart: 111, ResponseStart: 150, ResponseEnd: 170,
+			},
+		},
+	}
+	completed := next
+	completed.Response = &bidi.ResponseData{
+		Protocol:      "http/1.1",
+		Status:        200,
+		StatusText:    "OK",
+		MIMEType:      "text/plain",
+		Headers:       []bidi.Header{{Name: "Content-Type", Value: bidi.Base64Value([]byte("text/plain"))}},
+		BytesReceived: 120,
+		HeadersSize:   size(100),
+		BodySize:      size(20),
+	}
+	failed := bidi.NetworkEvent{
+		Timestamp: 1600000000300,
+		Request:   bidi.RequestData{Request: "r2", URL: "https://example.com/x.js", Method: "GET"},
+		ErrorText: "NS_ERROR_FAILURE",
+	}
+	pending := bidi.NetworkEvent{Request: bidi.RequestData{Request: "r3", URL: "https://example.com/slow"}}
+
+	h := FromBiDi([]bidi.NetworkEvent{sent, redirected, next, failed, pending, completed})
+	if len(h.Log.Entries) != 3 {
+		t.Fatalf("FromBiDi() returned %d entries, want 3", len(h.Log.Entries))
+	}
+	redirect, page, script := h.Log.Entries[0], h.Log.Entries[1], h.Log.Entries[2]
+
+	if redirect.Response.Status != 302 || redirect.Response.RedirectURL != "/new" ||
+		!redirect.StartedDateTime.Equal(time.Unix(1600000000, 123e6)) {
+		t.Errorf("the redirect entry is %+v", redirect)
+	}
+	if diff := cmp.Diff([]Cookie{{Name: "a", Value: "1"}}, redirect.Request.Cookies); diff != "" {
+		t.Errorf("the redirect entry has cookies with diff (-want +got):\n%s", diff)
+	}
+	if page.Response.Status != 200 || page.Response.HTTPVersion != "HTTP/1.1" || page.Request.HeadersSize != 40 ||
+		page.Response.HeadersSize != 100 || page.Response.BodySize != 20 || page.Response.Content.Size != 20 ||
+		page.Response.TransferSize != 120 {
+		t.Errorf("the page entry is %+v", page)
+	}
+	if diff := cmp.Diff([]NameValue{{"Content-Type", "text/plain"}}, page.Response.Headers); diff != "" {
+		t.Errorf("the page entry has response headers with diff (-want +got):\n%s", diff)
+	}
+	wantTimings := Timings{Blocked: 1, DNS: 2, Connect: 7, SSL: 5, Send: 0, Wait: 39, Receive: 20}
+	if page.Timings != wantTimings || page.Time != 69 {
+		t.Errorf("the page entry has timings %+v and time %v, want %+v and 69", page.Timings, page.Time, wantTimings)
+	}
+	if script.Response.Status != 0 || script.Response.Error != "NS_ERROR_FAILURE" {
+		t.Errorf("the failed entry is %+v", script)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	h := newHAR([]Entry{{
+		StartedDateTime: time.Date(2020, 9, 13, 12, 26, 40, 5e8, time.UTC),
+		Request: Request{
+			Cookies: []Cookie{{Name: "sid", Value: "secret"}},
+			Headers: []NameValue{{"authorization", "Bearer token"}, {"Cookie", "sid=secret"}, {"X-Api-Key", "key"}, {"Accept", "*/*"}},
+		},
+		Response: Response{
+			Cookies: []Cookie{{Name: "sid", Value: "new"}},
+			Headers: []NameValue{{"Set-Cookie", "sid=new"}},
+		},
+	}})
+
+	var b bytes.Buffer
+	if err := h.Write(&b, &WriteOptions{RedactHeaders: []string{"x-api-key"}}); err != nil {
+		t.Fatalf("Write() returned error: %v", err)
+	}
+	for _, secret := range []string{"secret", "token", "key", "new"} {
+		if strings.Contains(b.String(), secret) {
+			t.Errorf("Write() wrote %q:\n%s", secret, b.String())
+		}
+	}
+	var got HAR
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatalf("json.Unmarshal() of the archive returned error: %v", err)
+	}
+	if got.Log.Version != "1.2" || len(got.Log.Entries) != 1 {
+		t.Fatalf("Write() wrote %+v", got)
+	}
+	e := got.Log.Entries[0]
+	if !strings.Contains(b.String(), `"startedDateTime":"2020-09-13T12:26:40.500Z"`) {
+		t.Errorf("Write() wrote the start time in an unexpected format:\n%s", b.String())
+	}
+	wantHeaders := []NameValue{{"authorization", Redacted}, {"Cookie", Redacted}, {"X-Api-Key", Redacted}, {"Accept", "*/*"}}
+	if diff := cmp.Diff(wantHeaders, e.Request.Headers); diff != "" {
+		t.Errorf("Write() wrote request headers with diff (-want +got):\n%s", diff)
+	}
+	if e.Request.Cookies[0].Value != Redacted || e.Response.Cookies[0].Value != Redacted || e.Response.Headers[0].Value != Redacted {
+		t.Errorf("Write() did not redact the cookies: %+v", e)
+	}
+	if h.Log.Entries[0].Request.Cookies[0].Value != "secret" {
+		t.Errorf("Write() modified the archive")
+	}
+
+	b.Reset()
+	if err := h.Write(&b, &WriteOptions{NoRedaction: true}); err != nil {
+		t.Fatalf("Write() returned error: %v", err)
+	}
+	if !strings.Contains(b.String(), "Bearer token") {
+		t.Errorf("Write() with NoRedaction redacted the headers:\n%s", b.String())
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	received := make(chan string, 10)
+	hs := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		for {
+			var cmd struct {
+				ID     int64
+				Method string
+			}
+			if err := websocket.JSON.Receive(ws, &cmd); err != nil {
+				return
+			}
+			received <- cmd.Method
+			websocket.JSON.Send(ws, map[string]interface{}{"type": "success", "id": cmd.ID, "result": map[string]interface{}{}})
+			if cmd.Method != "session.subscribe" {
+				continue
+			}
+			request := map[string]interface{}{"request": "r1", "url": "http://example.com/", "method": "GET"}
+			websocket.JSON.Send(ws, map[string]interface{}{
+				"type":   "event",
+				"method": bidi.EventBeforeRequestSent,
+				"params": map[string]interface{}{"request": request, "timestamp": 1000},
+			})
+			websocket.JSON.Send(ws, map[string]interface{}{
+				"type":   "event",
+				"method": bidi.EventResponseCompleted,
+				"params": map[string]interface{}{"request": request, "timestamp": 1010, "response": map[string]interface{}{"status": 204}},
+			})
+		}
+	}))
+	defer hs.Close()
+	conn, err := bidi.Dial("ws" + strings.TrimPrefix(hs.URL, "http"))
+	if err != nil {
+		t.Fatalf("bidi.Dial() returned error: %v", err)
+	}
+	defer conn.Close()
+
+	ctx := context.Background()
+	r, err := Record(ctx, conn)
+	if err != nil {
+		t.Fatalf("Record() returned error: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(r.HAR().Log.Entries) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := r.Stop(ctx); err != nil {
+		t.Fatalf("Stop() returned error: %v", err)
+	}
+	entries := r.HAR().Log.Entries
+	if len(entries) != 1 || entries[0].Response.Status != 204 || entries[0].Request.URL != "http://example.com/" {
+		t.Errorf("HAR() returned entries %+v, want the completed request", entries)
+	}
+	for _, want := range []string{"session.subscribe", "session.unsubscribe"} {
+		if got := <-received; got != want {
+			t.Errorf("the browser received %q, want %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package har
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/tebeka/selenium/perflog"
+)
+
+// FromPerfLog builds an archive from the requests of the Chrome performance
+// log l, which must include the events of the Network domain. Requests that
+// have neither received their response nor failed are omitted.
+func FromPerfLog(l *perflog.Log) *HAR {
+	var entries []Entry
+	for _, r := range l.Requests() {
+		if r.Response == nil && r.Failed == nil {
+			continue
+		}
+		entries = append(entries, perfLogEntry(r))
+	}
+	return newHAR(entries)
+}
+
+// wallTime converts a time in seconds since the epoch.
+func wallTime(seconds float64) time.Time {
+	sec, frac := math.Modf(seconds)
+	return time.Unix(int64(sec), int64(frac*1e9)).UTC()
+}
+
+func perfLogEntry(r *perflog.NetworkRequest) Entry {
+	sent := r.Sent
+	e := Entry{
+		StartedDateTime: wallTime(sent.WallTime),
+		Request: Request{
+			Method:      sent.Request.Method,
+			URL:         sent.Request.URL + sent.Request.URLFragment,
+			QueryString: queryString(sent.Request.URL),
+			HeadersSize: -1,
+		},
+		Response: Response{
+			Cookies:     []Cookie{},
+			Headers:     []NameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+	}
+	headers := sent.Request.Headers
+	if r.Response != nil && len(r.Response.Response.RequestHeaders) > 0 {
+		// The headers actually sent, which include the cookies.
+		headers = r.Response.Response.RequestHeaders
+	}
+	e.Request.Headers = headerList(headers)
+	e.Request.Cookies = requestCookies(e.Request.Headers)
+	if sent.Request.HasPostData || sent.Request.PostData != "" {
+		e.Request.PostData = &PostData{
+			MIMEType: header(e.Request.Headers, "Content-Type"),
+			Text:     sent.Request.PostData,
+		}
+		e.Request.BodySize = int64(len(sent.Request.PostData))
+	}
+
+	if r.Failed != nil {
+		e.Response.Error = r.Failed.ErrorText
+	}
+	if r.Response != nil {
+		resp := r.Response.Response
+		e.Request.HTTPVersion = httpVersion(resp.Protocol)
+		e.Response.Status = resp.Status
+		e.Response.StatusText = resp.StatusText
+		e.Response.HTTPVersion = e.Request.HTTPVersion
+		e.Response.Headers = headerList(resp.Headers)
+		e.Response.Cookies = responseCookies(e.Response.Headers)
+		e.Response.RedirectURL = header(e.Response.Headers, "Location")
+		e.Response.Content = Content{Size: r.DataLength, MIMEType: resp.MIMEType}
+		e.ServerIPAddress = resp.RemoteIPAddress
+		if resp.ConnectionID != 0 {
+			e.Connection = strconv.FormatFloat(resp.ConnectionID, 'f', -1, 64)
+		}
+	}
+	if r.Finished != nil {
+		e.Response.TransferSize = int64(r.Finished.EncodedDataLength)
+	}
+
+	e.Timings = perfLogTimings(r)
+	e.Time = e.Timings.total()
+	return e
+}
+
+// perfLogTimings returns the timings of r, from the timing of its response if
+// any, and from the timestamps of its events otherwise.
+func perfLogTimings(r *perflog.NetworkRequest) Timings {
+	t := Timings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1}
+	// end is the time at which the request ended, in seconds.
+	end := r.Sent.Timestamp
+	switch {
+	case r.Finished != nil:
+		end = r.Finished.Timestamp
+	case r.Failed != nil:
+		end = r.Failed.Timestamp
+	case r.Response != nil:
+		end = r.Response.Timestamp
+	}
+	ms := func(d perflog.MonotonicTime) float64 { return math.Max(0, float64(d)*1000) }
+
+	if r.Response == nil || r.Response.Response.Timing == nil {
+		if r.Response != nil {
+			t.Wait = ms(r.Response.Timestamp - r.Sent.Timestamp)
+			t.Receive = ms(end - r.Response.Timestamp)
+		} else {
+			t.Wait = ms(end - r.Sent.Timestamp)
+		}
+		return t
+	}
+
+	timing := r.Response.Response.Timing
+	// The phases are in milliseconds since timing.RequestTime. The request is
+	// blocked until the first of them starts.
+	blockedEnd := timing.SendStart
+	for _, start := range []float64{timing.ConnectStart, timing.DNSStart} {
+		if start >= 0 {
+			blockedEnd = start
+		}
+	}
+	t.Blocked = ms(timing.RequestTime-r.Sent.Timestamp) + math.Max(0, blockedEnd)
+	if timing.DNSStart >= 0 {
+		t.DNS = timing.DNSEnd - timing.DNSStart
+	}
+	if timing.ConnectStart >= 0 {
+		t.Connect = timing.ConnectEnd - timing.ConnectStart
+	}
+	if timing.SSLStart >= 0 {
+		t.SSL = timing.SSLEnd - timing.SSLStart
+	}
+	t.Send = math.Max(0, timing.SendEnd-timing.SendStart)
+	t.Wait = math.Max(0, timing.ReceiveHeadersEnd-timing.SendEnd)
+	t.Receive = math.Max(0, ms(end-timing.RequestTime)-timing.ReceiveHeadersEnd)
+	return t
+}
@@ -0,0 +1,130 @@
+package selenium
+
+import "fmt"
+
+// HealEvent describes an attempt to re-locate an element whose reference
+// became stale, e.g. because the page re-rendered it.
+type HealEvent struct {
+	// Locator is how the element was found, relative to its parent element if
+	// it was found within one.
+	Locator Locator
+	// Index is the position of the element among those found by Locator, or
+	// -1 if it was found by FindElement.
+	Index int
+	// Err is the error that prevented the element from being re-located, or
+	// nil if the element was healed.
+	Err error
+}
+
+// SelfHealing returns an option that makes the elements found by the
+// WebDriver, and by those elements, remember how they were found. When Click,
+// SendKeys, Clear, Submit, Text, GetAttribute, GetProperty, FindElement or
+// FindElements fails on such an element with a stale element reference
+// error, the element, and if needed the elements it was found within, are
+// located again and the command is retried once. Other queries, such as
+// IsEnabled and IsDisplayed, report stale elements, so that waiting for an
+// element to become stale still works.
+//
+// hook, if not nil, is called after each attempt to heal an element.
+func SelfHealing(hook func(HealEvent)) RemoteOption {
+	return func(wd *remoteWD) error {
+		wd.healing = true
+		wd.healHook = hook
+		return nil
+	}
+}
+
+// elementOrigin records how an element was found.
+type elementOrigin struct {
+	// root is the element within which the element was found, or nil if it was
+	// found in the current browsing context.
+	root    *remoteWE
+	locator Locator
+	index   int
+}
+
+// track records the origin of elem if self-healing is enabled.
+func (wd *remoteWD) track(elem WebElement, root *remoteWE, by, value string, index int) {
+	if !wd.healing {
+		return
+	}
+	if e, ok := elem.(*remoteWE); ok {
+		e.origin = &elementOrigin{
+			root:    root,
+			locator: Locator{By: by, Value: value},
+			index:   index,
+		}
+	}
+}
+
+// do calls f and, if it fails because the element is stale and the element's
+// origin is known, re-locates the element and calls f again. It wraps the
+// commands healed by SelfHealing.
+func (elem *remoteWE) do(f func() error) error {
+	err := f()
+	if elem.origin == nil || !HasErrorCode(err, CodeStaleElementReference) {
+		return err
+	}
+	healErr := elem.relocate()
+	if hook := elem.parent.healHook; hook != nil {
+		hook(HealEvent{
+			Locator: elem.origin.locator,
+			Index:   elem.origin.index,
+			Err:     healErr,
+		})
+	}
+	if healErr != nil {
+		return err
+	}
+	return f()
+}
+
+// doString is like do, for commands that return a string.
+func (elem *remoteWE) doString(f func() (string, error)) (string, error) {
+	var s string
+	err := elem.do(func() error {
+		var err error
+		s, err = f()
+		return err
+	})
+	return s, err
+}
+
+// relocate finds the element again and updates its ID.
+func (elem *remoteWE) relocate() error {
+	o := elem.origin
+	var root ElementFinder = elem.parent
+	if o.root != nil {
+		root = o.root
+	}
+
+	var found WebElement
+	if o.index < 0 {
+		var err error
+		if found, err = root.FindElement(o.locator.By, o.locator.Value); err != nil {
+			return err
+		}
+	} else {
+		elems, err := root.FindElements(o.locator.By, o.locator.Value)
+		if err != nil {
+			return err
+		}
+		if o.index >= len(elems) {
+			return &Error{
+				Err:     CodeNoSuchElement,
+				Message: fmt.Sprintf("%d elements found by %s, want at least %d", len(elems), o.locator, o.index+1),
+			}
+		}
+		found = elems[o.index]
+	}
+
+	e, ok := found.(*remoteWE)
+	if !ok {
+		return fmt.Errorf("unexpected element type %T", found)
+	}
+	id := e.ref()
+	elem.idMu.Lock()
+	elem.id = id
+	elem.idMu.Unlock()
+	return nil
+}
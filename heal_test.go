@@ -0,0 +1,145 @@
+package selenium
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// renderedPage is a page with a form containing two inputs. Each render of
+// the page creates new element IDs, tagged with the render generation, and
+// commands on elements of earlier generations fail with a stale element
+// reference error.
+type renderedPage struct {
+	generation int
+	clicked    []string
+}
+
+// serve makes d reply to the commands on the page.
+func (p *renderedPage) serve(d *fakeDriver) {
+	d.handle("", "", func(r *fakeRequest) (interface{}, error) {
+		parts := strings.Split(strings.TrimPrefix(r.Path, "/"), "/")
+		if len(parts) == 1 && parts[0] == "element" {
+			return elementRefs(fmt.Sprintf("form.%d", p.generation))[0], nil
+		}
+		if len(parts) != 3 || parts[0] != "element" {
+			return nil, &Error{Err: CodeUnknownCommand, HTTPCode: http.StatusNotFound}
+		}
+
+		id := parts[1]
+		if gen := strings.Split(id, ".")[1]; gen != fmt.Sprint(p.generation) {
+			return nil, &Error{Err: CodeStaleElementReference, Message: id, HTTPCode: http.StatusNotFound}
+		}
+		switch parts[2] {
+		case "elements":
+			return elementRefs(fmt.Sprintf("input.%d.0", p.generation), fmt.Sprintf("input.%d.1", p.generation)), nil
+		case "click":
+			p.clicked = append(p.clicked, id)
+			return nil, nil
+		case "text":
+			return id, nil
+		}
+		return nil, &Error{Err: CodeUnknownCommand, HTTPCode: http.StatusNotFound}
+	})
+}
+
+func TestSelfHealing(t *testing.T) {
+	s := &renderedPage{}
+	d := newFakeDriver(t)
+	s.serve(d)
+
+	var events []HealEvent
+	wd := d.driver("")
+	if err := SelfHealing(func(e HealEvent) { events = append(events, e) })(wd); err != nil {
+		t.Fatalf("SelfHealing() returned error: %v", err)
+	}
+
+	form, err := wd.FindElement(ByCSSSelector, "form")
+	if err != nil {
+		t.Fatalf("wd.FindElement() returned error: %v", err)
+	}
+	inputs, err := form.FindElements(ByTagName, "input")
+	if err != nil {
+		t.Fatalf("form.FindElements() returned error: %v", err)
+	}
+	if len(inputs) != 2 {
+		t.Fatalf("len(form.FindElements()) = %d, want 2", len(inputs))
+	}
+
+	// Re-rendering the page makes both the form and the inputs stale.
+	s.generation++
+	if err := inputs[1].Click(); err != nil {
+		t.Fatalf("inputs[1].Click() returned error: %v", err)
+	}
+	if got, want := s.clicked, []string{"input.1.1"}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("clicked elements = %v, want %v", got, want)
+	}
+	if got, err := form.Text(); err != nil || got != "form.1" {
+		t.Errorf("form.Text() = %q, %v, want %q, nil", got, err, "form.1")
+	}
+	// Queries such as IsEnabled report stale elements instead of healing
+	// them.
+	if _, err := inputs[0].IsEnabled(); !HasErrorCode(err, CodeStaleElementReference) {
+		t.Errorf("inputs[0].IsEnabled() returned error %v, want %q", err, CodeStaleElementReference)
+	}
+
+	// The form was healed while healing the input, and on its own.
+	want := []HealEvent{
+		{Locator: By.CSS("form"), Index: -1},
+		{Locator: By.TagName("input"), Index: 1},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d heal events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, e := range events {
+		if e != want[i] {
+			t.Errorf("heal event %d = %+v, want %+v", i, e, want[i])
+		}
+	}
+}
+
+func TestSelfHealingDisabled(t *testing.T) {
+	s := &renderedPage{}
+	d := newFakeDriver(t)
+	s.serve(d)
+
+	wd := d.driver("")
+	form, err := wd.FindElement(ByCSSSelector, "form")
+	if err != nil {
+		t.Fatalf("wd.FindElement() returned error: %v", err)
+	}
+	s.generation++
+	if err := form.Click(); !HasErrorCode(err, CodeStaleElementReference) {
+		t.Errorf("form.Click() returned error %v, want %q", err, CodeStaleElementReference)
+	}
+}
+
+func TestSelfHealingConcurrent(t *testing.T) {
+	s := &renderedPage{}
+	d := newFakeDriver(t)
+	s.serve(d)
+
+	wd := d.driver("")
+	if err := SelfHealing(nil)(wd); err != nil {
+		t.Fatalf("SelfHealing() returned error: %v", err)
+	}
+	form, err := wd.FindElement(ByCSSSelector, "form")
+	if err != nil {
+		t.Fatalf("wd.FindElement() returned error: %v", err)
+	}
+
+	s.generation++
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got, err := form.Text(); err != nil || got != "form.1" {
+				t.Errorf("form.Text() = %q, %v, want %q, nil", got, err, "form.1")
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -4,8 +4,10 @@
 package seleniumtest
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"image/png"
 	"math"
 	"net"
 	"net/http"
@@ -25,9 +27,11 @@ import (
 	"github.com/blang/semver"
 	"github.com/google/go-cmp/cmp"
 	"github.com/tebeka/selenium"
+	"github.com/tebeka/selenium/cdp"
 	"github.com/tebeka/selenium/chrome"
 	"github.com/tebeka/selenium/firefox"
 	"github.com/tebeka/selenium/log"
+	"github.com/tebeka/selenium/perflog"
 	"github.com/tebeka/selenium/sauce"
 )
 
@@ -138,6 +142,8 @@ func RunCommonTests(t *testing.T, c Config) {
 	t.Run("PageSource", runTest(testPageSource, c))
 	t.Run("FindElement", runTest(testFindElement, c))
 	t.Run("FindElements", runTest(testFindElements, c))
+	t.Run("FindElementBy", runTest(testFindElementBy, c))
+	t.Run("RelativeLocator", runTest(testRelativeLocator, c))
 	t.Run("SendKeys", runTest(testSendKeys, c))
 	t.Run("Click", runTest(testClick, c))
 	t.Run("GetCookies", runTest(testGetCookies, c))
@@ -567,6 +573,71 @@ func testFindElements(t *testing.T, c Config) {
 	evaluateElement(t, wd, elems[0])
 }
 
+func testFindElementBy(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	l := selenium.By.TagName("form").Descendant(selenium.By.Name("submit"))
+	elems, err := selenium.FindElementsBy(wd, l)
+	if err != nil {
+		t.Fatalf("FindElementsBy(%s) returned error: %v", l, err)
+	}
+	if len(elems) != 1 {
+		t.Fatalf("len(FindElementsBy(%s)) = %d, want 1", l, len(elems))
+	}
+	elem, err := selenium.FindElementBy(wd, l)
+	if err != nil {
+		t.Fatalf("FindElementBy(%s) returned error: %v", l, err)
+	}
+	evaluateElement(t, wd, elem)
+}
+
+func testRelativeLocator(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	q, err := selenium.FindElementBy(wd, selenium.By.Name("q"))
+	if err != nil {
+		t.Fatalf("FindElementBy(name=q) returned error: %v", err)
+	}
+
+	for _, tc := range []struct {
+		l    selenium.RelativeLocator
+		want string
+	}{
+		{selenium.Relative(selenium.By.TagName("input")).ToRightOf(q), "submit"},
+		{selenium.Relative(selenium.By.TagName("input")).Below(q), "chuk"},
+	} {
+		elem, err := tc.l.FindElement(wd)
+		if err != nil {
+			t.Errorf("%s: FindElement() returned error: %v", tc.l, err)
+			continue
+		}
+		id, err := elem.GetAttribute("id")
+		if err != nil {
+			t.Errorf("%s: elem.GetAttribute(\"id\") returned error: %v", tc.l, err)
+			continue
+		}
+		if id != tc.want {
+			t.Errorf("%s: found element with ID %q, want %q", tc.l, id, tc.want)
+		}
+	}
+
+	elems, err := selenium.Relative(selenium.By.TagName("input")).Above(q).FindElements(wd)
+	if err != nil {
+		t.Fatalf("FindElements() returned error: %v", err)
+	}
+	if len(elems) != 0 {
+		t.Errorf("found %d inputs above q, want 0", len(elems))
+	}
+}
+
 func testSendKeys(t *testing.T, c Config) {
 	wd := newRemote(t, newTestCapabilities(t, c), c)
 	defer quitRemote(t, wd)
@@ -962,6 +1033,27 @@ func testScreenshot(t *testing.T, c Config) {
 	if len(data) == 0 {
 		t.Fatal("Empty reply")
 	}
+
+	elem, err := wd.FindElement(selenium.ByName, "q")
+	if err != nil {
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByName, "q", err)
+	}
+	const maxDimension = 100
+	img, err := selenium.ScreenshotImage(wd, &selenium.ScreenshotOptions{Element: elem, MaxDimension: maxDimension})
+	if err != nil {
+		t.Fatalf("ScreenshotImage() returned error: %v", err)
+	}
+	if b := img.Bounds(); b.Empty() || b.Dx() > maxDimension || b.Dy() > maxDimension {
+		t.Errorf("ScreenshotImage() bounds = %v, want a non-empty image at most %d pixels wide and high", b, maxDimension)
+	}
+
+	data, err = selenium.FullPageScreenshot(wd)
+	if err != nil {
+		t.Fatalf("FullPageScreenshot() returned error: %v", err)
+	}
+	if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+		t.Errorf("Decoding the full-page screenshot returned error: %v", err)
+	}
 }
 
 func testLog(t *testing.T, c Config) {
@@ -1019,6 +1111,9 @@ func testLog(t *testing.T, c Config) {
 					t.Errorf("Message has timestamp %s > 1 hour ago: %v", l.Timestamp, l)
 				}
 			}
+			if _, err := perflog.Parse(logs); err != nil {
+				t.Errorf("perflog.Parse() returned error: %v", err)
+			}
 		}
 	}
 }
@@ -1297,7 +1392,7 @@ func testProxy(t *testing.T, c Config) {
 			default:
 			}
 			if err != nil {
-				t.Fatalf("s.ListenAndServe(_) returned error: %v", err)
+				t.Errorf("s.ListenAndServe(_) returned error: %v", err)
 			}
 		}()
 		defer func() {
@@ -1746,7 +1841,89 @@ func testChromeExtension(t *testing.T, c Config) {
 	}
 }
 
+func testChromeCDP(t *testing.T, c Config) {
+	if c.SeleniumVersion.Major > 0 {
+		t.Skip("Selenium does not forward Chrome DevTools Protocol commands.")
+	}
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if _, err := cdp.AddScriptToEvaluateOnNewDocument(wd, "window.injected = 42;"); err != nil {
+		t.Fatalf("cdp.AddScriptToEvaluateOnNewDocument() returned error: %v", err)
+	}
+	const timezone = "Asia/Tokyo"
+	if err := cdp.SetTimezoneOverride(wd, timezone); err != nil {
+		t.Fatalf("cdp.SetTimezoneOverride(%q) returned error: %v", timezone, err)
+	}
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+
+	got, err := wd.ExecuteScript("return [window.injected, Intl.DateTimeFormat().resolvedOptions().timeZone];", nil)
+	if err != nil {
+		t.Fatalf("wd.ExecuteScript() returned error: %v", err)
+	}
+	if want := []interface{}{float64(42), timezone}; !reflect.DeepEqual(got, want) {
+		t.Errorf("the injected value and time zone are %v, want %v", got, want)
+	}
+}
+
+func testChromeNetworkConditions(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := selenium.SetNetworkConditions(wd, selenium.NetworkOffline); err != nil {
+		t.Fatalf("SetNetworkConditions(NetworkOffline) returned error: %v", err)
+	}
+	got, err := selenium.GetNetworkConditions(wd)
+	if err != nil {
+		t.Fatalf("GetNetworkConditions() returned error: %v", err)
+	}
+	if *got != selenium.NetworkOffline {
+		t.Errorf("GetNetworkConditions() = %+v, want %+v", *got, selenium.NetworkOffline)
+	}
+	if err := wd.Get(c.ServerURL); err == nil {
+		t.Errorf("wd.Get(%q) while offline returned no error", c.ServerURL)
+	}
+	if err := selenium.DeleteNetworkConditions(wd); err != nil {
+		t.Fatalf("DeleteNetworkConditions() returned error: %v", err)
+	}
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+}
+
+func testChromeGeolocation(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	if err := selenium.SetPermission(wd, "geolocation", selenium.PermissionGranted); err != nil {
+		t.Fatalf("SetPermission() returned error: %v", err)
+	}
+	if err := selenium.SetGeolocation(wd, 48.85, 2.35, 10); err != nil {
+		t.Fatalf("SetGeolocation() returned error: %v", err)
+	}
+	got, err := wd.ExecuteScriptAsync(`
+		var done = arguments[arguments.length - 1];
+		navigator.geolocation.getCurrentPosition(
+			function(p) { done([p.coords.latitude, p.coords.longitude, p.coords.accuracy]); },
+			function(e) { done(e.message); });
+	`, nil)
+	if err != nil {
+		t.Fatalf("wd.ExecuteScriptAsync() returned error: %v", err)
+	}
+	if want := []interface{}{48.85, 2.35, float64(10)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("the position is %v, want %v", got, want)
+	}
+}
+
 func RunChromeTests(t *testing.T, c Config) {
 	// Chrome-specific tests.
 	t.Run("Extension", runTest(testChromeExtension, c))
+	t.Run("CDP", runTest(testChromeCDP, c))
+	t.Run("NetworkConditions", runTest(testChromeNetworkConditions, c))
+	t.Run("Geolocation", runTest(testChromeGeolocation, c))
 }
@@ -0,0 +1,193 @@
+// Package wsrpc implements the transport shared by WebDriver BiDi and the
+// Chrome DevTools Protocol: commands, identified by an ID, and their replies
+// are exchanged as JSON messages over a WebSocket, along with the events sent
+// by the remote end.
+package wsrpc
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"golang.org/x/net/websocket"
+)
+
+// Message is a message received from the remote end: either the reply to a
+// command, which has an ID, or an event.
+type Message struct {
+	ID     *int64          `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	// Raw is the whole message, from which the protocols decode their
+	// errors.
+	Raw json.RawMessage `json:"-"`
+}
+
+// Conn is a connection to a WebSocket. It is safe for concurrent use.
+type Conn struct {
+	ws *websocket.Conn
+	// errClosed is returned by the commands sent on a closed connection.
+	errClosed error
+	// done is closed when the connection is closed.
+	done chan struct{}
+
+	mu       sync.Mutex
+	nextID   int64
+	pending  map[int64]chan *Message
+	handlers map[string]map[int64]func(json.RawMessage)
+	// events are the events waiting to be dispatched to the handlers.
+	events      []*Message
+	eventsReady chan struct{}
+}
+
+// Dial connects to the WebSocket at url. The commands sent once the
+// connection is closed return errClosed.
+func Dial(url string, errClosed error) (*Conn, error) {
+	ws, err := websocket.Dial(url, "", "http://localhost/")
+	if err != nil {
+		return nil, err
+	}
+	c := &Conn{
+		ws:          ws,
+		errClosed:   errClosed,
+		done:        make(chan struct{}),
+		pending:     make(map[int64]chan *Message),
+		handlers:    make(map[string]map[int64]func(json.RawMessage)),
+		eventsReady: make(chan struct{}, 1),
+	}
+	go c.read()
+	go c.dispatch()
+	return c, nil
+}
+
+// read receives the messages of the remote end until the connection is
+// closed.
+func (c *Conn) read() {
+	defer close(c.done)
+	for {
+		var data []byte
+		if err := websocket.Message.Receive(c.ws, &data); err != nil {
+			return
+		}
+		msg := &Message{Raw: data}
+		if err := json.Unmarshal(data, msg); err != nil {
+			continue
+		}
+
+		c.mu.Lock()
+		if msg.ID != nil {
+			if reply, ok := c.pending[*msg.ID]; ok {
+				delete(c.pending, *msg.ID)
+				reply <- msg
+			}
+		} else if msg.Method != "" {
+			c.events = append(c.events, msg)
+			select {
+			case c.eventsReady <- struct{}{}:
+			default:
+			}
+		}
+		c.mu.Unlock()
+	}
+}
+
+// dispatch calls the handlers of the events in the order they arrived, on a
+// single goroutine, so that the handlers can send commands.
+func (c *Conn) dispatch() {
+	for {
+		select {
+		case <-c.eventsReady:
+		case <-c.done:
+			return
+		}
+		c.mu.Lock()
+		events := c.events
+		c.events = nil
+		c.mu.Unlock()
+
+		for _, e := range events {
+			c.mu.Lock()
+			var handlers []func(json.RawMessage)
+			for _, h := range c.handlers[e.Method] {
+				handlers = append(handlers, h)
+			}
+			c.mu.Unlock()
+			for _, h := range handlers {
+				h(e.Params)
+			}
+		}
+	}
+}
+
+// Send sends the command method with the given parameters, which are encoded
+// as JSON and may be nil, and returns its reply.
+func (c *Conn) Send(ctx context.Context, method string, params interface{}) (*Message, error) {
+	if params == nil {
+		params = struct{}{}
+	}
+	reply := make(chan *Message, 1)
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	c.pending[id] = reply
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	data, err := json.Marshal(map[string]interface{}{
+		"id":     id,
+		"method": method,
+		"params": params,
+	})
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case <-c.done:
+		return nil, c.errClosed
+	default:
+	}
+	if err := websocket.Message.Send(c.ws, string(data)); err != nil {
+		return nil, err
+	}
+
+	select {
+	case msg := <-reply:
+		return msg, nil
+	case <-c.done:
+		return nil, c.errClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// On registers f to be called with the parameters of each event named
+// method, and returns a function that unregisters it. The handlers are called
+// one at a time, in the order the events arrive.
+func (c *Conn) On(method string, f func(params json.RawMessage)) (remove func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nextID++
+	id := c.nextID
+	if c.handlers[method] == nil {
+		c.handlers[method] = make(map[int64]func(json.RawMessage))
+	}
+	c.handlers[method][id] = f
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.handlers[method], id)
+	}
+}
+
+// Close closes the connection. Commands waiting for their reply return the
+// error given to Dial.
+func (c *Conn) Close() error {
+	err := c.ws.Close()
+	<-c.done
+	return err
+}
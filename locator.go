@@ -0,0 +1,189 @@
+package selenium
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Locator describes how to find elements: a method, one of the By*
+// constants, and a value whose meaning depends on the method. Locators are
+// usually created using By, for example By.CSS("form input"), and can be
+// chained to find the descendants of the elements found by another locator.
+type Locator struct {
+	By, Value string
+
+	// parent, if set, is the locator of the elements within which this locator
+	// is evaluated.
+	parent *Locator
+}
+
+// LocatorBuilder provides methods that create a Locator for each of the
+// methods by which elements can be found. Use the By variable to access
+// these methods.
+type LocatorBuilder struct{}
+
+// By creates Locators, e.g. By.ID("login") or By.XPath("//a[@href]").
+var By LocatorBuilder
+
+// ID returns a Locator that finds elements by their ID.
+func (LocatorBuilder) ID(id string) Locator { return Locator{By: ByID, Value: id} }
+
+// Name returns a Locator that finds input elements by their name.
+func (LocatorBuilder) Name(name string) Locator { return Locator{By: ByName, Value: name} }
+
+// CSS returns a Locator that finds elements matching a CSS selector.
+func (LocatorBuilder) CSS(selector string) Locator {
+	return Locator{By: ByCSSSelector, Value: selector}
+}
+
+// XPath returns a Locator that finds elements matching an XPath expression.
+func (LocatorBuilder) XPath(expr string) Locator { return Locator{By: ByXPATH, Value: expr} }
+
+// LinkText returns a Locator that finds links by their exact text.
+func (LocatorBuilder) LinkText(text string) Locator {
+	return Locator{By: ByLinkText, Value: text}
+}
+
+// PartialLinkText returns a Locator that finds links whose text contains
+// text.
+func (LocatorBuilder) PartialLinkText(text string) Locator {
+	return Locator{By: ByPartialLinkText, Value: text}
+}
+
+// TagName returns a Locator that finds elements by their tag name.
+func (LocatorBuilder) TagName(name string) Locator { return Locator{By: ByTagName, Value: name} }
+
+// ClassName returns a Locator that finds elements by one of their classes.
+func (LocatorBuilder) ClassName(name string) Locator {
+	return Locator{By: ByClassName, Value: name}
+}
+
+// Descendant returns a Locator that finds the elements matching d that are
+// descendants of the elements matching l.
+func (l Locator) Descendant(d Locator) Locator {
+	var p Locator
+	if d.parent != nil {
+		p = l.Descendant(*d.parent)
+	} else {
+		p = l
+	}
+	d.parent = &p
+	return d
+}
+
+// String returns a description of the locator, for use in diagnostics.
+func (l Locator) String() string {
+	s := fmt.Sprintf("%s=%q", l.By, l.Value)
+	if l.parent != nil {
+		s = l.parent.String() + " >> " + s
+	}
+	return s
+}
+
+// ElementFinder is implemented by both WebDriver and WebElement.
+type ElementFinder interface {
+	FindElement(by, value string) (WebElement, error)
+	FindElements(by, value string) ([]WebElement, error)
+}
+
+// LocatorFinder finds elements using a Locator.
+type LocatorFinder interface {
+	// FindElementBy works like FindElement, but uses a Locator.
+	FindElementBy(l Locator) (WebElement, error)
+	// FindElementsBy works like FindElements, but uses a Locator.
+	FindElementsBy(l Locator) ([]WebElement, error)
+}
+
+// FindElementBy returns the first element found by l within root, a
+// WebDriver or a WebElement. It calls the FindElementBy method of root if
+// root has one.
+func FindElementBy(root ElementFinder, l Locator) (WebElement, error) {
+	if f, ok := root.(interface {
+		FindElementBy(Locator) (WebElement, error)
+	}); ok {
+		return f.FindElementBy(l)
+	}
+	return findElementBy(root, l)
+}
+
+// FindElementsBy returns all elements found by l within root, a WebDriver or
+// a WebElement. It calls the FindElementsBy method of root if root has one.
+func FindElementsBy(root ElementFinder, l Locator) ([]WebElement, error) {
+	if f, ok := root.(interface {
+		FindElementsBy(Locator) ([]WebElement, error)
+	}); ok {
+		return f.FindElementsBy(l)
+	}
+	return findElementsBy(root, l)
+}
+
+// findElementBy returns the first element found by l within root. For
+// chained locators, the ancestors are searched in document order.
+func findElementBy(root ElementFinder, l Locator) (WebElement, error) {
+	if l.parent == nil {
+		return root.FindElement(l.By, l.Value)
+	}
+	parents, err := findElementsBy(root, *l.parent)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range parents {
+		elem, err := p.FindElement(l.By, l.Value)
+		if HasErrorCode(err, CodeNoSuchElement) {
+			continue
+		}
+		return elem, err
+	}
+	return nil, &Error{
+		Err:     CodeNoSuchElement,
+		Message: fmt.Sprintf("no element found by %s", l),
+	}
+}
+
+// findElementsBy returns all elements found by l within root. For chained
+// locators, an element found within several of the ancestors, as happens
+// when they are nested, is only returned once, at its first position.
+func findElementsBy(root ElementFinder, l Locator) ([]WebElement, error) {
+	if l.parent == nil {
+		return root.FindElements(l.By, l.Value)
+	}
+	parents, err := findElementsBy(root, *l.parent)
+	if err != nil {
+		return nil, err
+	}
+	var elems []WebElement
+	seen := make(map[interface{}]bool)
+	for _, p := range parents {
+		found, err := p.FindElements(l.By, l.Value)
+		if err != nil {
+			return nil, err
+		}
+		for _, elem := range found {
+			key := elementKey(elem)
+			if key != nil {
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			}
+			elems = append(elems, elem)
+		}
+	}
+	return elems, nil
+}
+
+// elementKey returns a key identifying elem: its JSON encoding, which holds
+// the ID of the element, or elem itself. It returns nil if elem cannot be
+// identified.
+func elementKey(elem WebElement) interface{} {
+	if m, ok := elem.(json.Marshaler); ok {
+		if b, err := m.MarshalJSON(); err == nil {
+			return string(b)
+		}
+	}
+	if elem != nil && reflect.TypeOf(elem).Comparable() {
+		return elem
+	}
+	return nil
+}
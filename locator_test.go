@@ -0,0 +1,141 @@
+package selenium
+
+import (
+	"reflect"
+	"testing"
+)
+
+// fakeNode is an elementFinder, and WebElement, over a static tree of
+// elements identified by name. Its children are matched by "method=value"
+// keys.
+type fakeNode struct {
+	WebElement
+
+	name     string
+	children map[string][]*fakeNode
+}
+
+func (n *fakeNode) FindElement(by, value string) (WebElement, error) {
+	found := n.children[by+"="+value]
+	if len(found) == 0 {
+		return nil, &Error{Err: CodeNoSuchElement}
+	}
+	return found[0], nil
+}
+
+func (n *fakeNode) FindElements(by, value string) ([]WebElement, error) {
+	var elems []WebElement
+	for _, c := range n.children[by+"="+value] {
+		elems = append(elems, c)
+	}
+	return elems, nil
+}
+
+func names(elems []WebElement) []string {
+	var names []string
+	for _, e := range elems {
+		names = append(names, e.(*fakeNode).name)
+	}
+	return names
+}
+
+func TestLocator(t *testing.T) {
+	q := &fakeNode{name: "q"}
+	submit1 := &fakeNode{name: "submit1"}
+	submit2 := &fakeNode{name: "submit2"}
+	root := &fakeNode{children: map[string][]*fakeNode{
+		"css selector=form": {
+			{name: "login", children: map[string][]*fakeNode{
+				"name=submit": {submit1},
+			}},
+			{name: "search", children: map[string][]*fakeNode{
+				"name=q":      {q},
+				"name=submit": {submit2},
+			}},
+		},
+	}}
+
+	form := By.CSS("form")
+	if got, err := findElementBy(root, form.Descendant(By.Name("q"))); err != nil || got != q {
+		t.Errorf("findElementBy(form >> q) = %v, %v, want %v", got, err, q)
+	}
+
+	elems, err := findElementsBy(root, form.Descendant(By.Name("submit")))
+	if err != nil {
+		t.Fatalf("findElementsBy(form >> submit) returned error: %v", err)
+	}
+	if got, want := names(elems), []string{"submit1", "submit2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("findElementsBy(form >> submit) = %v, want %v", got, want)
+	}
+
+	_, err = findElementBy(root, form.Descendant(By.ID("missing")))
+	if !HasErrorCode(err, CodeNoSuchElement) {
+		t.Errorf("findElementBy(form >> missing) returned error %v, want %q", err, CodeNoSuchElement)
+	}
+}
+
+func TestLocatorString(t *testing.T) {
+	for _, tc := range []struct {
+		l    Locator
+		want string
+	}{
+		{By.ID("login"), `id="login"`},
+		{By.XPath(`//a[@href="/"]`), `xpath="//a[@href=\"/\"]"`},
+		{By.CSS("form").Descendant(By.Name("q")), `css selector="form" >> name="q"`},
+		{
+			By.TagName("body").Descendant(By.CSS("form").Descendant(By.LinkText("help"))),
+			`tag name="body" >> css selector="form" >> link text="help"`,
+		},
+	} {
+		if got := tc.l.String(); got != tc.want {
+			t.Errorf("String() = %s, want %s", got, tc.want)
+		}
+	}
+}
+
+func TestLocatorNestedParents(t *testing.T) {
+	// The inner div is found both by itself and within the outer div.
+	a := &fakeNode{name: "a"}
+	b := &fakeNode{name: "b"}
+	c := &fakeNode{name: "c"}
+	inner := &fakeNode{name: "inner", children: map[string][]*fakeNode{
+		"tag name=span": {b},
+	}}
+	outer := &fakeNode{name: "outer", children: map[string][]*fakeNode{
+		"tag name=span": {a, b, c},
+	}}
+	root := &fakeNode{children: map[string][]*fakeNode{
+		"tag name=div": {outer, inner},
+	}}
+
+	l := By.TagName("div").Descendant(By.TagName("span"))
+	elems, err := FindElementsBy(root, l)
+	if err != nil {
+		t.Fatalf("FindElementsBy(%s) returned error: %v", l, err)
+	}
+	if got, want := names(elems), []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindElementsBy(%s) = %v, want %v", l, got, want)
+	}
+}
+
+func TestLocatorDeduplicatesByID(t *testing.T) {
+	// Distinct values referring to the same element are identified by their
+	// IDs.
+	d := newFakeDriver(t)
+	d.reply("POST", "/element/outer/elements", elementRefs("a", "b"))
+	d.reply("POST", "/element/inner/elements", elementRefs("b"))
+	d.reply("POST", "/elements", elementRefs("outer", "inner"))
+	wd := d.driver("")
+
+	elems, err := wd.FindElementsBy(By.TagName("div").Descendant(By.TagName("span")))
+	if err != nil {
+		t.Fatalf("FindElementsBy() returned error: %v", err)
+	}
+	var ids []string
+	for _, e := range elems {
+		ids = append(ids, e.(*remoteWE).id)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("FindElementsBy() returned elements %v, want %v", ids, want)
+	}
+}
@@ -0,0 +1,355 @@
+// Package logstream delivers the console messages and uncaught JavaScript
+// exceptions of a browser as they happen.
+//
+// Reading the browser log with WebDriver.Log drains it, so concurrent readers
+// lose messages. A Stream is the single reader of the log of a session and
+// delivers each entry to all of its handlers and subscribers. It uses the
+// log.entryAdded event of WebDriver BiDi if the session has a BiDi
+// connection, as requested with bidi.AddCapability, and polls WebDriver.Log
+// otherwise.
+package logstream
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tebeka/selenium"
+	"github.com/tebeka/selenium/bidi"
+	"github.com/tebeka/selenium/log"
+)
+
+// Kind is the kind of a log entry.
+type Kind string
+
+// Kinds of log entries.
+const (
+	// Console entries are written with the console API.
+	Console Kind = "console"
+	// Exception entries are uncaught JavaScript exceptions.
+	Exception Kind = "javascript"
+)
+
+// Entry is a console message or an uncaught JavaScript exception.
+type Entry struct {
+	Kind      Kind
+	Level     log.Level
+	Text      string
+	Timestamp time.Time
+	// URL is the URL of the script of the entry, if known.
+	URL string
+}
+
+func (e Entry) String() string {
+	return fmt.Sprintf("%s %s: %s", e.Level, e.Kind, e.Text)
+}
+
+// DefaultPollInterval is the default interval at which the log is polled when
+// the session has no BiDi connection.
+const DefaultPollInterval = 500 * time.Millisecond
+
+// Options configure a Stream.
+type Options struct {
+	// PollInterval is the interval at which the log is polled when BiDi is not
+	// used. If zero, DefaultPollInterval is used.
+	PollInterval time.Duration
+	// NoBiDi makes the stream poll the log even if the session has a BiDi
+	// connection.
+	NoBiDi bool
+}
+
+// Stream delivers the log entries of a session. It is safe for concurrent
+// use.
+type Stream struct {
+	wd   selenium.WebDriver
+	conn *bidi.Conn
+
+	// stop is closed by Stop to stop polling; polled is closed when the
+	// polling goroutine returns. done is closed when the stream is stopped.
+	stop, polled, done chan struct{}
+	// seen holds the keys of the entries delivered by polling, to drop the
+	// ones returned again by the driver.
+	seen map[string]time.Time
+
+	mu       sync.Mutex
+	stopping bool
+	stopped  bool
+	nextID   int
+	handlers map[int]func(Entry)
+	entries  []Entry
+	err      error
+}
+
+// Start starts delivering the log entries of the session of wd. Entries
+// logged before Start may be delivered when the log is polled. The stream
+// must be stopped with Stop.
+func Start(wd selenium.WebDriver, opts *Options) (*Stream, error) {
+	if opts == nil {
+		opts = new(Options)
+	}
+	s := &Stream{
+		wd:       wd,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		handlers: make(map[int]func(Entry)),
+	}
+
+	if !opts.NoBiDi && selenium.WebSocketURL(wd) != "" {
+		conn, err := bidi.Connect(wd)
+		if err != nil {
+			return nil, err
+		}
+		conn.OnLogEntry(func(e bidi.LogEntry) { s.deliver(fromBiDi(e)) })
+		if err := conn.Subscribe(context.Background(), []string{bidi.EventLogEntryAdded}, nil); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		s.conn = conn
+		return s, nil
+	}
+
+	s.seen = make(map[string]time.Time)
+	if err := s.poll(); err != nil {
+		return nil, err
+	}
+	interval := opts.PollInterval
+	if interval == 0 {
+		interval = DefaultPollInterval
+	}
+	s.polled = make(chan struct{})
+	go func() {
+		defer close(s.polled)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.poll(); err != nil {
+					s.mu.Lock()
+					if s.err == nil {
+						s.err = err
+					}
+					s.mu.Unlock()
+				}
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+	return s, nil
+}
+
+// levels maps the levels of BiDi log entries to log levels.
+var levels = map[string]log.Level{
+	bidi.LogDebug: log.Debug,
+	bidi.LogInfo:  log.Info,
+	bidi.LogWarn:  log.Warning,
+	bidi.LogError: log.Severe,
+}
+
+func fromBiDi(e bidi.LogEntry) Entry {
+	entry := Entry{
+		Kind:      Kind(e.Type),
+		Level:     levels[e.Level],
+		Text:      e.Text,
+		Timestamp: time.Unix(0, e.Timestamp*int64(time.Millisecond)),
+	}
+	if entry.Level == "" {
+		entry.Level = log.Info
+	}
+	if e.StackTrace != nil && len(e.StackTrace.CallFrames) > 0 {
+		entry.URL = e.StackTrace.CallFrames[0].URL
+	}
+	return entry
+}
+
+// fromMessage converts a message of the browser log. Chrome prefixes the
+// messages with the URL and position of the script, and the messages of
+// uncaught exceptions contain "Uncaught".
+func fromMessage(m log.Message) Entry {
+	entry := Entry{
+		Kind:      Console,
+		Level:     m.Level,
+		Text:      m.Message,
+		Timestamp: m.Timestamp,
+	}
+	if strings.Contains(m.Message, "Uncaught") {
+		entry.Kind = Exception
+	}
+	if fields := strings.SplitN(m.Message, " ", 3); len(fields) == 3 && strings.Contains(fields[0], "://") {
+		entry.URL = fields[0]
+		entry.Text = fields[2]
+	}
+	return entry
+}
+
+// dedupWindow is how long the keys of polled entries are kept.
+const dedupWindow = time.Minute
+
+// poll reads the browser log and delivers the entries that were not already
+// delivered.
+func (s *Stream) poll() error {
+	messages, err := s.wd.Log(log.Browser)
+	if err != nil {
+		return err
+	}
+	var newest time.Time
+	for _, m := range messages {
+		if m.Timestamp.After(newest) {
+			newest = m.Timestamp
+		}
+		key := fmt.Sprintf("%d %s %s", m.Timestamp.UnixNano(), m.Level, m.Message)
+		if _, ok := s.seen[key]; ok {
+			continue
+		}
+		s.seen[key] = m.Timestamp
+		s.deliver(fromMessage(m))
+	}
+	for key, ts := range s.seen {
+		if newest.Sub(ts) > dedupWindow {
+			delete(s.seen, key)
+		}
+	}
+	return nil
+}
+
+// deliver records e and calls the handlers with it.
+func (s *Stream) deliver(e Entry) {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	s.entries = append(s.entries, e)
+	handlers := make([]func(Entry), 0, len(s.handlers))
+	for id := 0; id < s.nextID; id++ {
+		if h, ok := s.handlers[id]; ok {
+			handlers = append(handlers, h)
+		}
+	}
+	s.mu.Unlock()
+	for _, h := range handlers {
+		h(e)
+	}
+}
+
+// OnEntry registers f to be called with each entry delivered after the call,
+// and returns a function that unregisters it. The handlers are called one at
+// a time, in the order the entries are logged, and must not block.
+func (s *Stream) OnEntry(f func(Entry)) (remove func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.nextID
+	s.nextID++
+	s.handlers[id] = f
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.handlers, id)
+	}
+}
+
+// Subscribe returns a channel on which the entries delivered after the call
+// are sent, and a function that cancels the subscription and closes the
+// channel. Entries are queued until they are received, and the channel is
+// closed after the remaining ones when the stream is stopped.
+func (s *Stream) Subscribe() (entries <-chan Entry, cancel func()) {
+	out := make(chan Entry)
+	canceled := make(chan struct{})
+	ready := make(chan struct{}, 1)
+	var (
+		mu    sync.Mutex
+		queue []Entry
+	)
+	remove := s.OnEntry(func(e Entry) {
+		mu.Lock()
+		queue = append(queue, e)
+		mu.Unlock()
+		select {
+		case ready <- struct{}{}:
+		default:
+		}
+	})
+
+	go func() {
+		defer close(out)
+		for {
+			mu.Lock()
+			if len(queue) == 0 {
+				mu.Unlock()
+				select {
+				case <-ready:
+					continue
+				case <-canceled:
+					return
+				case <-s.done:
+					// Entries may have been queued before the stream stopped.
+					mu.Lock()
+					empty := len(queue) == 0
+					mu.Unlock()
+					if empty {
+						return
+					}
+					continue
+				}
+			}
+			e := queue[0]
+			queue = queue[1:]
+			mu.Unlock()
+			select {
+			case out <- e:
+			case <-canceled:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return out, func() {
+		once.Do(func() {
+			remove()
+			close(canceled)
+		})
+	}
+}
+
+// Entries returns the entries delivered so far.
+func (s *Stream) Entries() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Entry(nil), s.entries...)
+}
+
+// Stop stops the stream after delivering the entries logged so far, when the
+// log is polled, and closes the channels returned by Subscribe once their
+// entries are received. It returns the first error encountered while reading
+// the log.
+func (s *Stream) Stop() error {
+	s.mu.Lock()
+	if s.stopping {
+		s.mu.Unlock()
+		return nil
+	}
+	s.stopping = true
+	s.mu.Unlock()
+
+	var err error
+	if s.conn != nil {
+		err = s.conn.Close()
+	} else {
+		close(s.stop)
+		<-s.polled
+		err = s.poll()
+	}
+
+	s.mu.Lock()
+	s.stopped = true
+	if s.err != nil {
+		err = s.err
+	}
+	s.mu.Unlock()
+	close(s.done)
+	return err
+}
@@ -0,0 +1,156 @@
+package logstream
+
+import (
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tebeka/selenium"
+	"github.com/tebeka/selenium/log"
+	"golang.org/x/net/websocket"
+)
+
+// fakeDriver returns the batches of its browser log one at a time from Log.
+type fakeDriver struct {
+	selenium.WebDriver
+	url string
+
+	mu      sync.Mutex
+	batches [][]log.Message
+}
+
+func (wd *fakeDriver) WebSocketURL() string { return wd.url }
+
+func (wd *fakeDriver) Log(typ log.Type) ([]log.Message, error) {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	if len(wd.batches) == 0 {
+		return nil, nil
+	}
+	b := wd.batches[0]
+	wd.batches = wd.batches[1:]
+	return b, nil
+}
+
+func (wd *fakeDriver) add(messages ...log.Message) {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	wd.batches = append(wd.batches, messages)
+}
+
+func TestPolling(t *testing.T) {
+	t1 := time.Unix(100, 0)
+	t2 := time.Unix(101, 0)
+	first := log.Message{Timestamp: t1, Level: log.Info, Message: `console-api 3:10 "hello"`}
+	uncaught := log.Message{Timestamp: t2, Level: log.Severe, Message: "http://example.com/app.js 12:5 Uncaught Error: boom"}
+
+	wd := &fakeDriver{}
+	wd.add(first)
+	s, err := Start(wd, &Options{PollInterval: 5 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Start() returned error: %v", err)
+	}
+	entries, _ := s.Subscribe()
+	var called []Entry
+	s.OnEntry(func(e Entry) { called = append(called, e) })
+
+	// The driver returns the first message again with the next one.
+	wd.add(first, uncaught)
+	time.Sleep(50 * time.Millisecond)
+	wd.add(log.Message{Timestamp: t2, Level: log.Warning, Message: "late"})
+	if err := s.Stop(); err != nil {
+		t.Fatalf("Stop() returned error: %v", err)
+	}
+
+	want := []Entry{
+		{Kind: Console, Level: log.Info, Text: `console-api 3:10 "hello"`, Timestamp: t1},
+		{Kind: Exception, Level: log.Severe, Text: "Uncaught Error: boom", Timestamp: t2, URL: "http://example.com/app.js"},
+		{Kind: Console, Level: log.Warning, Text: "late", Timestamp: t2},
+	}
+	if diff := cmp.Diff(want, s.Entries()); diff != "" {
+		t.Errorf("Entries() returned diff (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(want[1:], called); diff != "" {
+		t.Errorf("the handler was called with diff (-want +got):\n%s", diff)
+	}
+	var received []Entry
+	for e := range entries {
+		received = append(received, e)
+	}
+	if diff := cmp.Diff(want[1:], received); diff != "" {
+		t.Errorf("the subscription received diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestSubscribeCancel(t *testing.T) {
+	wd := &fakeDriver{}
+	s, err := Start(wd, &Options{PollInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("Start() returned error: %v", err)
+	}
+	defer s.Stop()
+	entries, cancel := s.Subscribe()
+	cancel()
+	cancel()
+	if _, ok := <-entries; ok {
+		t.Errorf("the subscription channel is open after cancel")
+	}
+}
+
+func TestBiDi(t *testing.T) {
+	hs := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		for {
+			var cmd struct {
+				ID     int64
+				Method string
+			}
+			if err := websocket.JSON.Receive(ws, &cmd); err != nil {
+				return
+			}
+			websocket.JSON.Send(ws, map[string]interface{}{"type": "success", "id": cmd.ID, "result": map[string]interface{}{}})
+			if cmd.Method != "session.subscribe" {
+				continue
+			}
+			websocket.JSON.Send(ws, map[string]interface{}{
+				"type":   "event",
+				"method": "log.entryAdded",
+				"params": map[string]interface{}{
+					"type":      "javascript",
+					"level":     "error",
+					"text":      "Error: boom",
+					"timestamp": 1000,
+					"source":    map[string]interface{}{"realm": "r1"},
+					"stackTrace": map[string]interface{}{"callFrames": []interface{}{
+						map[string]interface{}{"functionName": "f", "url": "http://example.com/app.js", "lineNumber": 11, "columnNumber": 4},
+					}},
+				},
+			})
+		}
+	}))
+	defer hs.Close()
+
+	wd := &fakeDriver{url: "ws" + strings.TrimPrefix(hs.URL, "http")}
+	s, err := Start(wd, nil)
+	if err != nil {
+		t.Fatalf("Start() returned error: %v", err)
+	}
+	// The entry is logged as soon as the stream subscribes to the events.
+	deadline := time.Now().Add(5 * time.Second)
+	for len(s.Entries()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	entries, _ := s.Subscribe()
+	want := []Entry{{Kind: Exception, Level: log.Severe, Text: "Error: boom", Timestamp: time.Unix(1, 0), URL: "http://example.com/app.js"}}
+	if diff := cmp.Diff(want, s.Entries()); diff != "" {
+		t.Errorf("Entries() returned diff (-want +got):\n%s", diff)
+	}
+	if err := s.Stop(); err != nil {
+		t.Errorf("Stop() returned error: %v", err)
+	}
+	if _, ok := <-entries; ok {
+		t.Errorf("the subscription channel is open after Stop()")
+	}
+}
@@ -0,0 +1,151 @@
+package selenium
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"io"
+	"time"
+)
+
+// fourCC returns a four-character code of the RIFF format.
+func fourCC(s string) [4]byte {
+	var c [4]byte
+	copy(c[:], s)
+	return c
+}
+
+// riffChunk writes a RIFF chunk, padded to an even size.
+func riffChunk(w *bytes.Buffer, id string, data []byte) {
+	w.WriteString(id)
+	binary.Write(w, binary.LittleEndian, uint32(len(data)))
+	w.Write(data)
+	if len(data)%2 == 1 {
+		w.WriteByte(0)
+	}
+}
+
+// riffList writes a RIFF list of the given type.
+func riffList(w *bytes.Buffer, listType string, contents []byte) {
+	riffChunk(w, "LIST", append([]byte(listType), contents...))
+}
+
+func riffStruct(v interface{}) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, v)
+	return buf.Bytes()
+}
+
+// aviMainHeader is the AVIMAINHEADER structure.
+type aviMainHeader struct {
+	MicroSecPerFrame    uint32
+	MaxBytesPerSec      uint32
+	PaddingGranularity  uint32
+	Flags               uint32
+	TotalFrames         uint32
+	InitialFrames       uint32
+	Streams             uint32
+	SuggestedBufferSize uint32
+	Width, Height       uint32
+	Reserved            [4]uint32
+}
+
+// aviStreamHeader is the AVISTREAMHEADER structure.
+type aviStreamHeader struct {
+	Type, Handler            [4]byte
+	Flags                    uint32
+	Priority, Language       uint16
+	InitialFrames            uint32
+	Scale, Rate              uint32
+	Start, Length            uint32
+	SuggestedBufferSize      uint32
+	Quality, SampleSize      uint32
+	Left, Top, Right, Bottom int16
+}
+
+// bitmapInfoHeader is the BITMAPINFOHEADER structure.
+type bitmapInfoHeader struct {
+	Size                         uint32
+	Width, Height                int32
+	Planes, BitCount             uint16
+	Compression                  [4]byte
+	SizeImage                    uint32
+	XPelsPerMeter, YPelsPerMeter int32
+	ClrUsed, ClrImportant        uint32
+}
+
+const (
+	aviHasIndex = 0x10
+	aviKeyFrame = 0x10
+)
+
+// writeMJPEG writes the frames as an AVI file with a Motion JPEG stream.
+// AVI streams have a constant frame rate, so the frames are shown for the
+// average time between them.
+func writeMJPEG(w io.Writer, frames []recordedFrame, size image.Rectangle, end time.Time) error {
+	n := len(frames)
+	perFrame := uint32(end.Sub(frames[0].at) / time.Microsecond / time.Duration(n))
+	if perFrame == 0 {
+		perFrame = 1
+	}
+
+	var movi, index bytes.Buffer
+	maxFrame := 0
+	for _, f := range frames {
+		// Offsets are relative to the "movi" list type.
+		offset := 4 + movi.Len()
+		riffChunk(&movi, "00dc", f.jpeg)
+		index.WriteString("00dc")
+		binary.Write(&index, binary.LittleEndian, []uint32{aviKeyFrame, uint32(offset), uint32(len(f.jpeg))})
+		if len(f.jpeg) > maxFrame {
+			maxFrame = len(f.jpeg)
+		}
+	}
+
+	width, height := size.Dx(), size.Dy()
+	var streamList bytes.Buffer
+	riffChunk(&streamList, "strh", riffStruct(aviStreamHeader{
+		Type:                fourCC("vids"),
+		Handler:             fourCC("MJPG"),
+		Scale:               perFrame,
+		Rate:                1000000,
+		Length:              uint32(n),
+		SuggestedBufferSize: uint32(maxFrame),
+		Quality:             0xffffffff,
+		Right:               int16(width),
+		Bottom:              int16(height),
+	}))
+	riffChunk(&streamList, "strf", riffStruct(bitmapInfoHeader{
+		Size:        40,
+		Width:       int32(width),
+		Height:      int32(height),
+		Planes:      1,
+		BitCount:    24,
+		Compression: fourCC("MJPG"),
+		SizeImage:   uint32(width * height * 3),
+	}))
+
+	var headers bytes.Buffer
+	riffChunk(&headers, "avih", riffStruct(aviMainHeader{
+		MicroSecPerFrame:    perFrame,
+		MaxBytesPerSec:      uint32(uint64(maxFrame) * 1000000 / uint64(perFrame)),
+		Flags:               aviHasIndex,
+		TotalFrames:         uint32(n),
+		Streams:             1,
+		SuggestedBufferSize: uint32(maxFrame),
+		Width:               uint32(width),
+		Height:              uint32(height),
+	}))
+	riffList(&headers, "strl", streamList.Bytes())
+
+	var avi bytes.Buffer
+	avi.WriteString("AVI ")
+	riffList(&avi, "hdrl", headers.Bytes())
+	riffList(&avi, "movi", movi.Bytes())
+	riffChunk(&avi, "idx1", index.Bytes())
+
+	var file bytes.Buffer
+	riffChunk(&file, "RIFF", avi.Bytes())
+	_, err := file.WriteTo(w)
+	return err
+}
@@ -0,0 +1,126 @@
+package network
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tebeka/selenium/bidi"
+)
+
+// bidiBackend intercepts requests with the network module of WebDriver BiDi.
+// The intercept pauses all the requests, which are matched against the
+// patterns by the Interceptor: BiDi URL patterns have no wildcards.
+type bidiBackend struct {
+	conn   *bidi.Conn
+	handle func(*Request) Action
+	remove func()
+
+	mu sync.Mutex
+	// id is the ID of the intercept, if any.
+	id string
+}
+
+func newBiDiBackend(conn *bidi.Conn, handle func(*Request) Action) (*bidiBackend, error) {
+	b := &bidiBackend{conn: conn, handle: handle}
+	b.remove = conn.OnNetworkEvent(bidi.EventBeforeRequestSent, b.paused)
+	if err := conn.Subscribe(context.Background(), []string{bidi.EventBeforeRequestSent}, nil); err != nil {
+		b.remove()
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *bidiBackend) intercept(patterns []string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ctx := context.Background()
+	switch {
+	case len(patterns) > 0 && b.id == "":
+		id, err := b.conn.AddIntercept(ctx, []string{bidi.PhaseBeforeRequestSent}, nil)
+		if err != nil {
+			return err
+		}
+		b.id = id
+	case len(patterns) == 0 && b.id != "":
+		if err := b.conn.RemoveIntercept(ctx, b.id); err != nil {
+			return err
+		}
+		b.id = ""
+	}
+	return nil
+}
+
+// paused resolves the requests paused by the intercept.
+func (b *bidiBackend) paused(e bidi.NetworkEvent) {
+	if !e.IsBlocked {
+		return
+	}
+	b.mu.Lock()
+	id := b.id
+	b.mu.Unlock()
+	ours := false
+	for _, intercept := range e.Intercepts {
+		ours = ours || (id != "" && intercept == id)
+	}
+	if !ours {
+		return
+	}
+
+	req := &Request{
+		URL:     e.Request.URL,
+		Method:  e.Request.Method,
+		Headers: make(map[string]string),
+	}
+	for _, h := range e.Request.Headers {
+		value, err := h.Value.Bytes()
+		if err != nil {
+			continue
+		}
+		if v, ok := req.Headers[h.Name]; ok {
+			req.Headers[h.Name] = v + ", " + string(value)
+		} else {
+			req.Headers[h.Name] = string(value)
+		}
+	}
+
+	a := b.handle(req)
+	ctx := context.Background()
+	switch a.kind {
+	case actionRespond:
+		b.conn.ProvideResponse(ctx, e.Request.Request, a.response.Status, bidiHeaders(a.response.Headers), a.response.Body)
+	case actionFail:
+		b.conn.FailRequest(ctx, e.Request.Request)
+	default:
+		var headers []bidi.Header
+		if a.headers != nil {
+			headers = bidiHeaders(mergeHeaders(req, a.headers))
+		}
+		b.conn.ContinueRequest(ctx, e.Request.Request, headers)
+	}
+}
+
+// bidiHeaders converts headers, sorted by name.
+func bidiHeaders(headers map[string]string) []bidi.Header {
+	if headers == nil {
+		return nil
+	}
+	list := make([]bidi.Header, 0, len(headers))
+	for name, value := range headers {
+		list = append(list, bidi.Header{Name: name, Value: bidi.StringValue(value)})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return strings.ToLower(list[i].Name) < strings.ToLower(list[j].Name)
+	})
+	return list
+}
+
+func (b *bidiBackend) close() error {
+	b.remove()
+	err := b.intercept(nil)
+	if cerr := b.conn.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
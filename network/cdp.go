@@ -0,0 +1,113 @@
+package network
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/tebeka/selenium/cdp"
+)
+
+// cdpBackend intercepts requests with the Fetch domain of the Chrome DevTools
+// Protocol, whose URL patterns have the same wildcards as the Interceptor.
+type cdpBackend struct {
+	conn   *cdp.Conn
+	handle func(*Request) Action
+	remove func()
+	// enabled is set while the Fetch domain is enabled. It is guarded by the
+	// mutex of the Interceptor.
+	enabled bool
+}
+
+func newCDPBackend(conn *cdp.Conn, handle func(*Request) Action) *cdpBackend {
+	b := &cdpBackend{conn: conn, handle: handle}
+	b.remove = conn.On("Fetch.requestPaused", b.paused)
+	return b
+}
+
+func (b *cdpBackend) intercept(patterns []string) error {
+	ctx := context.Background()
+	if len(patterns) == 0 {
+		if !b.enabled {
+			return nil
+		}
+		if err := b.conn.Execute(ctx, "Fetch.disable", nil, nil); err != nil {
+			return err
+		}
+		b.enabled = false
+		return nil
+	}
+
+	list := make([]map[string]string, len(patterns))
+	for i, p := range patterns {
+		list[i] = map[string]string{"urlPattern": p, "requestStage": "Request"}
+	}
+	// Enabling the domain again replaces the patterns.
+	if err := b.conn.Execute(ctx, "Fetch.enable", map[string]interface{}{"patterns": list}, nil); err != nil {
+		return err
+	}
+	b.enabled = true
+	return nil
+}
+
+type cdpHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// cdpHeaders converts headers, sorted by name.
+func cdpHeaders(headers map[string]string) []cdpHeader {
+	list := make([]cdpHeader, 0, len(headers))
+	for name, value := range headers {
+		list = append(list, cdpHeader{name, value})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return strings.ToLower(list[i].Name) < strings.ToLower(list[j].Name)
+	})
+	return list
+}
+
+// paused resolves the requests paused by the Fetch domain.
+func (b *cdpBackend) paused(params json.RawMessage) {
+	var e struct {
+		RequestID string `json:"requestId"`
+		Request   Request
+	}
+	if err := json.Unmarshal(params, &e); err != nil {
+		return
+	}
+
+	a := b.handle(&e.Request)
+	ctx := context.Background()
+	switch a.kind {
+	case actionRespond:
+		b.conn.Execute(ctx, "Fetch.fulfillRequest", map[string]interface{}{
+			"requestId":       e.RequestID,
+			"responseCode":    a.response.Status,
+			"responseHeaders": cdpHeaders(a.response.Headers),
+			"body":            base64.StdEncoding.EncodeToString(a.response.Body),
+		}, nil)
+	case actionFail:
+		b.conn.Execute(ctx, "Fetch.failRequest", map[string]string{
+			"requestId":   e.RequestID,
+			"errorReason": "BlockedByClient",
+		}, nil)
+	default:
+		params := map[string]interface{}{"requestId": e.RequestID}
+		if a.headers != nil {
+			params["headers"] = cdpHeaders(mergeHeaders(&e.Request, a.headers))
+		}
+		b.conn.Execute(ctx, "Fetch.continueRequest", params, nil)
+	}
+}
+
+func (b *cdpBackend) close() error {
+	b.remove()
+	err := b.intercept(nil)
+	if cerr := b.conn.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
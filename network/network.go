@@ -0,0 +1,268 @@
+// Package network intercepts the requests of a browser to stub their
+// responses, block them or modify their headers.
+//
+// Requests are intercepted with the network module of WebDriver BiDi if the
+// session has a BiDi connection, as requested with bidi.AddCapability, and
+// with the Fetch domain of the Chrome DevTools Protocol otherwise:
+//
+//	i, err := network.Intercept(wd)
+//	...
+//	defer i.Close()
+//	i.Stub("*/api/user", "GET", network.Response{
+//		Status:  200,
+//		Headers: map[string]string{"Content-Type": "application/json"},
+//		Body:    []byte(`{"name":"gopher"}`),
+//	})
+//	i.Block("*://tracker.example.com/*")
+package network
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/tebeka/selenium"
+	"github.com/tebeka/selenium/bidi"
+	"github.com/tebeka/selenium/cdp"
+)
+
+// Request is an intercepted request.
+type Request struct {
+	URL    string
+	Method string
+	// Headers are the headers of the request. The values of repeated headers
+	// are joined with ", ".
+	Headers map[string]string
+}
+
+// Response is a response provided for an intercepted request.
+type Response struct {
+	// Status is the HTTP status code. If zero, 200 is used.
+	Status  int
+	Headers map[string]string
+	Body    []byte
+}
+
+type actionKind int
+
+const (
+	actionContinue actionKind = iota
+	actionRespond
+	actionFail
+)
+
+// Action is the outcome of an intercepted request, as returned by a Handler.
+type Action struct {
+	kind     actionKind
+	response Response
+	headers  map[string]string
+}
+
+// Continue sends the request to the server. Headers, if not nil, are added
+// to the headers of the request, replacing the ones with the same names.
+func Continue(headers map[string]string) Action {
+	return Action{kind: actionContinue, headers: headers}
+}
+
+// Respond completes the request with the response r instead of sending it.
+func Respond(r Response) Action {
+	if r.Status == 0 {
+		r.Status = 200
+	}
+	return Action{kind: actionRespond, response: r}
+}
+
+// Fail fails the request with a network error.
+func Fail() Action {
+	return Action{kind: actionFail}
+}
+
+// Handler decides the outcome of an intercepted request.
+type Handler func(*Request) Action
+
+// backend pauses the requests of the browser, and resolves them with the
+// actions returned by Interceptor.handle.
+type backend interface {
+	// intercept makes the browser pause the requests whose URL matches one of
+	// the patterns, or none of them if patterns is empty.
+	intercept(patterns []string) error
+	close() error
+}
+
+type rule struct {
+	id      int
+	pattern string
+	re      *regexp.Regexp
+	method  string
+	handler Handler
+}
+
+// Interceptor intercepts the requests of a browser and resolves them with
+// the handlers of the rules they match. Requests that match no rule are sent
+// unmodified. It is safe for concurrent use.
+type Interceptor struct {
+	backend backend
+
+	mu     sync.Mutex
+	nextID int
+	rules  []rule
+}
+
+// Intercept starts intercepting the requests of the session of wd. It returns
+// an error wrapping selenium.ErrUnsupported if the session has neither a
+// BiDi connection nor a DevTools address. The Interceptor must be closed with
+// Close.
+func Intercept(wd selenium.WebDriver) (*Interceptor, error) {
+	i := new(Interceptor)
+	if selenium.WebSocketURL(wd) != "" {
+		conn, err := bidi.Connect(wd)
+		if err != nil {
+			return nil, err
+		}
+		b, err := newBiDiBackend(conn, i.handle)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		i.backend = b
+		return i, nil
+	}
+
+	conn, err := cdp.Connect(wd)
+	if err != nil {
+		return nil, err
+	}
+	i.backend = newCDPBackend(conn, i.handle)
+	return i, nil
+}
+
+// globRegexp compiles a URL pattern in which "*" matches any string and "?"
+// matches any character. Both can be escaped with a backslash.
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	escaped := false
+	for _, r := range glob {
+		switch {
+		case escaped:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '*':
+			b.WriteString(".*")
+		case r == '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	if escaped {
+		return nil, fmt.Errorf("the URL pattern %q ends with an escape character", glob)
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// Handle resolves the requests whose URL matches urlPattern, in which "*"
+// matches any string and "?" any character, and whose method is method, or
+// any method if it is empty, with h. Rules are tried in the order they were
+// added. It returns a function that removes the rule.
+//
+// The handlers are called one at a time, and the browser waits for them, so
+// they must return promptly.
+func (i *Interceptor) Handle(urlPattern, method string, h Handler) (remove func() error, err error) {
+	re, err := globRegexp(urlPattern)
+	if err != nil {
+		return nil, err
+	}
+	i.mu.Lock()
+	id := i.nextID
+	i.nextID++
+	i.rules = append(i.rules, rule{id: id, pattern: urlPattern, re: re, method: method, handler: h})
+	err = i.update()
+	i.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		i.mu.Lock()
+		defer i.mu.Unlock()
+		for j, r := range i.rules {
+			if r.id == id {
+				i.rules = append(i.rules[:j], i.rules[j+1:]...)
+				break
+			}
+		}
+		return i.update()
+	}, nil
+}
+
+// Stub responds to the requests matching urlPattern and method, as for
+// Handle, with r.
+func (i *Interceptor) Stub(urlPattern, method string, r Response) (remove func() error, err error) {
+	return i.Handle(urlPattern, method, func(*Request) Action { return Respond(r) })
+}
+
+// Block fails the requests whose URL matches urlPattern, as for Handle.
+func (i *Interceptor) Block(urlPattern string) (remove func() error, err error) {
+	return i.Handle(urlPattern, "", func(*Request) Action { return Fail() })
+}
+
+// update makes the backend pause the requests matched by the rules. i.mu must
+// be held.
+func (i *Interceptor) update() error {
+	var patterns []string
+	seen := make(map[string]bool)
+	for _, r := range i.rules {
+		if !seen[r.pattern] {
+			seen[r.pattern] = true
+			patterns = append(patterns, r.pattern)
+		}
+	}
+	return i.backend.intercept(patterns)
+}
+
+// handle returns the action for the paused request req.
+func (i *Interceptor) handle(req *Request) Action {
+	i.mu.Lock()
+	var h Handler
+	for _, r := range i.rules {
+		if (r.method == "" || strings.EqualFold(r.method, req.Method)) && r.re.MatchString(req.URL) {
+			h = r.handler
+			break
+		}
+	}
+	i.mu.Unlock()
+	if h == nil {
+		return Continue(nil)
+	}
+	return h(req)
+}
+
+// Close stops intercepting requests and closes the connection to the
+// browser.
+func (i *Interceptor) Close() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.backend.close()
+}
+
+// mergeHeaders returns the headers of req with the values in headers.
+func mergeHeaders(req *Request, headers map[string]string) map[string]string {
+	merged := make(map[string]string, len(req.Headers)+len(headers))
+	for name, value := range req.Headers {
+		merged[name] = value
+	}
+	for name, value := range headers {
+		for existing := range merged {
+			if strings.EqualFold(existing, name) {
+				delete(merged, existing)
+			}
+		}
+		merged[name] = value
+	}
+	return merged
+}
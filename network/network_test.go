@@ -0,0 +1,262 @@
+package network
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tebeka/selenium"
+	"github.com/tebeka/selenium/bidi"
+	"github.com/tebeka/selenium/cdp"
+	"golang.org/x/net/websocket"
+)
+
+// server is a local stand-in for the WebSocket endpoint of a browser. It
+// records the commands it receives, with their parameters encoded as JSON,
+// and replies to them with the results in results, keyed by method.
+type server struct {
+	t       *testing.T
+	url     string
+	results map[string]interface{}
+
+	mu       sync.Mutex
+	ws       *websocket.Conn
+	commands []string
+}
+
+func newServer(t *testing.T, results map[string]interface{}) (*server, func()) {
+	s := &server{t: t, results: results}
+	hs := httptest.NewServer(websocket.Handler(s.handle))
+	s.url = "ws" + strings.TrimPrefix(hs.URL, "http")
+	return s, hs.Close
+}
+
+func (s *server) handle(ws *websocket.Conn) {
+	s.mu.Lock()
+	s.ws = ws
+	s.mu.Unlock()
+	for {
+		var cmd struct {
+			ID     int64
+			Method string
+			Params interface{}
+		}
+		if err := websocket.JSON.Receive(ws, &cmd); err != nil {
+			return
+		}
+		params, _ := json.Marshal(cmd.Params)
+		s.mu.Lock()
+		s.commands = append(s.commands, cmd.Method+" "+string(params))
+		s.mu.Unlock()
+
+		result, ok := s.results[cmd.Method]
+		if !ok {
+			result = map[string]interface{}{}
+		}
+		s.send(map[string]interface{}{"type": "success", "id": cmd.ID, "result": result})
+	}
+}
+
+// send sends msg to the client.
+func (s *server) send(msg interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := websocket.JSON.Send(s.ws, msg); err != nil {
+		s.t.Errorf("Sending %v returned error: %v", msg, err)
+	}
+}
+
+// wait returns the commands received once there are n of them.
+func (s *server) wait(n int) []string {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.mu.Lock()
+		commands := append([]string(nil), s.commands...)
+		s.mu.Unlock()
+		if len(commands) >= n || time.Now().After(deadline) {
+			return commands
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// addRules adds the rules of the tests to i.
+func addRules(t *testing.T, i *Interceptor) {
+	t.Helper()
+	if _, err := i.Stub("*/api/user", "GET", Response{
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body:    []byte("{}"),
+	}); err != nil {
+		t.Fatalf("Stub() returned error: %v", err)
+	}
+	remove, err := i.Block("*://tracker.example.com/*")
+	if err != nil {
+		t.Fatalf("Block() returned error: %v", err)
+	}
+	if err := remove(); err != nil {
+		t.Fatalf("remove() returned error: %v", err)
+	}
+	if _, err := i.Block("*://tracker.example.com/*"); err != nil {
+		t.Fatalf("Block() returned error: %v", err)
+	}
+	if _, err := i.Handle("*", "", func(*Request) Action {
+		return Continue(map[string]string{"x-test": "1"})
+	}); err != nil {
+		t.Fatalf("Handle() returned error: %v", err)
+	}
+}
+
+func TestCDP(t *testing.T) {
+	s, stop := newServer(t, nil)
+	defer stop()
+	conn, err := cdp.Dial(s.url)
+	if err != nil {
+		t.Fatalf("cdp.Dial() returned error: %v", err)
+	}
+	i := new(Interceptor)
+	i.backend = newCDPBackend(conn, i.handle)
+	addRules(t, i)
+
+	paused := func(id, method, url string) {
+		s.send(map[string]interface{}{
+			"method": "Fetch.requestPaused",
+			"params": map[string]interface{}{
+				"requestId": id,
+				"request": map[string]interface{}{
+					"url":     url,
+					"method":  method,
+					"headers": map[string]string{"Accept": "*/*", "X-Test": "0"},
+				},
+			},
+		})
+	}
+	paused("1", "GET", "http://example.com/api/user")
+	paused("2", "GET", "https://tracker.example.com/t.js")
+	paused("3", "POST", "http://example.com/api/user")
+	s.wait(8)
+	if err := i.Close(); err != nil {
+		t.Errorf("Close() returned error: %v", err)
+	}
+
+	want := []string{
+		`Fetch.enable {"patterns":[{"requestStage":"Request","urlPattern":"*/api/user"}]}`,
+		`Fetch.enable {"patterns":[{"requestStage":"Request","urlPattern":"*/api/user"},{"requestStage":"Request","urlPattern":"*://tracker.example.com/*"}]}`,
+		`Fetch.enable {"patterns":[{"requestStage":"Request","urlPattern":"*/api/user"}]}`,
+		`Fetch.enable {"patterns":[{"requestStage":"Request","urlPattern":"*/api/user"},{"requestStage":"Request","urlPattern":"*://tracker.example.com/*"}]}`,
+		`Fetch.enable {"patterns":[{"requestStage":"Request","urlPattern":"*/api/user"},{"requestStage":"Request","urlPattern":"*://tracker.example.com/*"},{"requestStage":"Request","urlPattern":"*"}]}`,
+		`Fetch.fulfillRequest {"body":"e30=","requestId":"1","responseCode":200,"responseHeaders":[{"name":"Content-Type","value":"application/json"}]}`,
+		`Fetch.failRequest {"errorReason":"BlockedByClient","requestId":"2"}`,
+		`Fetch.continueRequest {"headers":[{"name":"Accept","value":"*/*"},{"name":"x-test","value":"1"}],"requestId":"3"}`,
+		`Fetch.disable {}`,
+	}
+	if diff := cmp.Diff(want, s.wait(len(want))); diff != "" {
+		t.Errorf("the browser received diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestBiDi(t *testing.T) {
+	s, stop := newServer(t, map[string]interface{}{
+		"network.addIntercept": map[string]string{"intercept": "i1"},
+	})
+	defer stop()
+	conn, err := bidi.Dial(s.url)
+	if err != nil {
+		t.Fatalf("bidi.Dial() returned error: %v", err)
+	}
+	i := new(Interceptor)
+	b, err := newBiDiBackend(conn, i.handle)
+	if err != nil {
+		t.Fatalf("newBiDiBackend() returned error: %v", err)
+	}
+	i.backend = b
+	addRules(t, i)
+
+	paused := func(id, method, url string, blocked bool) {
+		s.send(map[string]interface{}{
+			"type":   "event",
+			"method": bidi.EventBeforeRequestSent,
+			"params": map[string]interface{}{
+				"isBlocked":  blocked,
+				"intercepts": []string{"i1"},
+				"request": map[string]interface{}{
+					"request": id,
+					"url":     url,
+					"method":  method,
+					"headers": []interface{}{
+						map[string]interface{}{"name": "Accept", "value": map[string]string{"type": "string", "value": "*/*"}},
+						map[string]interface{}{"name": "X-Test", "value": map[string]string{"type": "string", "value": "0"}},
+					},
+				},
+			},
+		})
+	}
+	paused("0", "GET", "http://example.com/api/user", false)
+	paused("1", "GET", "http://example.com/api/user", true)
+	paused("2", "GET", "https://tracker.example.com/t.js", true)
+	paused("3", "POST", "http://example.com/api/user", true)
+	s.wait(5)
+	if err := i.Close(); err != nil {
+		t.Errorf("Close() returned error: %v", err)
+	}
+
+	want := []string{
+		`session.subscribe {"events":["network.beforeRequestSent"]}`,
+		`network.addIntercept {"phases":["beforeRequestSent"]}`,
+		`network.provideResponse {"body":{"type":"base64","value":"e30="},"headers":[{"name":"Content-Type","value":{"type":"string","value":"application/json"}}],"request":"1","statusCode":200}`,
+		`network.failRequest {"request":"2"}`,
+		`network.continueRequest {"headers":[{"name":"Accept","value":{"type":"string","value":"*/*"}},{"name":"x-test","value":{"type":"string","value":"1"}}],"request":"3"}`,
+		`network.removeIntercept {"intercept":"i1"}`,
+	}
+	if diff := cmp.Diff(want, s.wait(len(want))); diff != "" {
+		t.Errorf("the browser received diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestGlob(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, url string
+		want         bool
+	}{
+		{"*", "http://example.com/", true},
+		{"*/api/*", "http://example.com/api/user", true},
+		{"*/api/*", "http://example.com/app.js", false},
+		{"http://example.com/?", "http://example.com/a", true},
+		{"http://example.com/?", "http://example.com/ab", false},
+		{`*\?q=1`, "http://example.com/?q=1", true},
+		{`*\?q=1`, "http://example.com/xq=1", false},
+		{"http://example.com/a.js", "http://example.com/aXjs", false},
+	} {
+		re, err := globRegexp(tc.pattern)
+		if err != nil {
+			t.Errorf("globRegexp(%q) returned error: %v", tc.pattern, err)
+			continue
+		}
+		if got := re.MatchString(tc.url); got != tc.want {
+			t.Errorf("pattern %q matches %q: %t, want %t", tc.pattern, tc.url, got, tc.want)
+		}
+	}
+	if _, err := globRegexp(`*\`); err == nil {
+		t.Errorf("globRegexp() of a pattern ending with an escape returned no error")
+	}
+}
+
+type fakeDriver struct {
+	selenium.WebDriver
+}
+
+func (fakeDriver) WebSocketURL() string { return "" }
+
+func (fakeDriver) Capabilities() (selenium.Capabilities, error) {
+	return selenium.Capabilities{"browserName": "safari"}, nil
+}
+
+func TestInterceptUnsupported(t *testing.T) {
+	if _, err := Intercept(fakeDriver{}); !errors.Is(err, selenium.ErrUnsupported) {
+		t.Errorf("Intercept() returned error %v, want %v", err, selenium.ErrUnsupported)
+	}
+}
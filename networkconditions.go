@@ -0,0 +1,214 @@
+package selenium
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// NetworkConditions are network conditions emulated by the browser.
+type NetworkConditions struct {
+	// Offline, if set, makes the requests fail as if the browser was
+	// disconnected from the network.
+	Offline bool
+	// Latency is added to the round trip time of each request.
+	Latency time.Duration
+	// DownloadThroughput and UploadThroughput are the maximal throughputs in
+	// bytes per second, or zero for no limit.
+	DownloadThroughput int
+	UploadThroughput   int
+}
+
+// Network conditions presets, with the values of the Chrome DevTools.
+var (
+	NetworkOffline   = NetworkConditions{Offline: true}
+	NetworkSlow3G    = NetworkConditions{Latency: 2 * time.Second, DownloadThroughput: 50000, UploadThroughput: 50000}
+	NetworkFast3G    = NetworkConditions{Latency: 562500 * time.Microsecond, DownloadThroughput: 180000, UploadThroughput: 84375}
+	NetworkRegular4G = NetworkConditions{Latency: 20 * time.Millisecond, DownloadThroughput: 524288, UploadThroughput: 393216}
+)
+
+// NetworkPresets holds the network conditions presets by name.
+var NetworkPresets = map[string]NetworkConditions{
+	"offline":    NetworkOffline,
+	"slow-3g":    NetworkSlow3G,
+	"fast-3g":    NetworkFast3G,
+	"regular-4g": NetworkRegular4G,
+}
+
+// NetworkEmulator makes Chromium-based browsers emulate network conditions.
+type NetworkEmulator interface {
+	// SetNetworkConditions makes the browser emulate the network conditions
+	// c, such as NetworkSlow3G. It returns an error wrapping ErrUnsupported
+	// if the browser cannot emulate network conditions, as only
+	// Chromium-based browsers can.
+	SetNetworkConditions(c NetworkConditions) error
+	// GetNetworkConditions returns the network conditions set by
+	// SetNetworkConditions.
+	GetNetworkConditions() (*NetworkConditions, error)
+	// DeleteNetworkConditions stops the emulation of network conditions.
+	DeleteNetworkConditions() error
+}
+
+// networkEmulator returns wd as a NetworkEmulator, or an error wrapping
+// ErrUnsupported if it is not one.
+func networkEmulator(wd WebDriver) (NetworkEmulator, error) {
+	e, ok := wd.(NetworkEmulator)
+	if !ok {
+		return nil, fmt.Errorf("%w: the WebDriver %T cannot emulate network conditions", ErrUnsupported, wd)
+	}
+	return e, nil
+}
+
+// SetNetworkConditions makes the browser of wd emulate the network
+// conditions c, as described by NetworkEmulator.
+func SetNetworkConditions(wd WebDriver, c NetworkConditions) error {
+	e, err := networkEmulator(wd)
+	if err != nil {
+		return err
+	}
+	return e.SetNetworkConditions(c)
+}
+
+// GetNetworkConditions returns the network conditions emulated by the
+// browser of wd, as described by NetworkEmulator.
+func GetNetworkConditions(wd WebDriver) (*NetworkConditions, error) {
+	e, err := networkEmulator(wd)
+	if err != nil {
+		return nil, err
+	}
+	return e.GetNetworkConditions()
+}
+
+// DeleteNetworkConditions stops the emulation of network conditions by the
+// browser of wd, as described by NetworkEmulator.
+func DeleteNetworkConditions(wd WebDriver) error {
+	e, err := networkEmulator(wd)
+	if err != nil {
+		return err
+	}
+	return e.DeleteNetworkConditions()
+}
+
+// chromeNetworkConditions is the JSON representation of NetworkConditions in
+// the network conditions commands of ChromeDriver.
+type chromeNetworkConditions struct {
+	Offline bool `json:"offline"`
+	// Latency is in milliseconds.
+	Latency            float64 `json:"latency"`
+	DownloadThroughput float64 `json:"download_throughput"`
+	UploadThroughput   float64 `json:"upload_throughput"`
+}
+
+// throughput returns the throughput t in the format of the browser, in which
+// -1 disables throttling.
+func throughput(t int) float64 {
+	if t <= 0 {
+		return -1
+	}
+	return float64(t)
+}
+
+func (wd *remoteWD) checkNetworkConditions() error {
+	if wd.cdpVendor() == "" {
+		return fmt.Errorf("%w: network conditions emulation requires a Chromium-based browser, not %q", ErrUnsupported, wd.browser)
+	}
+	return nil
+}
+
+// emulateNetworkConditions sets the network conditions through the Chrome
+// DevTools Protocol, for servers that do not forward the network conditions
+// commands to the driver. It returns an error wrapping ErrUnsupported if the
+// server forwards neither.
+func (wd *remoteWD) emulateNetworkConditions(c NetworkConditions) error {
+	if _, err := wd.executeCDP("Network.enable", nil); err != nil {
+		if HasErrorCode(err, CodeUnknownCommand) {
+			return fmt.Errorf("%w: the server supports neither the network conditions nor the Chrome DevTools Protocol commands: %v", ErrUnsupported, err)
+		}
+		return err
+	}
+	_, err := wd.executeCDP("Network.emulateNetworkConditions", map[string]interface{}{
+		"offline":            c.Offline,
+		"latency":            float64(c.Latency) / float64(time.Millisecond),
+		"downloadThroughput": throughput(c.DownloadThroughput),
+		"uploadThroughput":   throughput(c.UploadThroughput),
+	})
+	return err
+}
+
+func (wd *remoteWD) SetNetworkConditions(c NetworkConditions) error {
+	if err := wd.checkNetworkConditions(); err != nil {
+		return err
+	}
+	err := wd.voidCommand("/session/%s/chromium/network_conditions", map[string]interface{}{
+		"network_conditions": chromeNetworkConditions{
+			Offline:            c.Offline,
+			Latency:            float64(c.Latency) / float64(time.Millisecond),
+			DownloadThroughput: throughput(c.DownloadThroughput),
+			UploadThroughput:   throughput(c.UploadThroughput),
+		},
+	})
+	if !HasErrorCode(err, CodeUnknownCommand) {
+		return err
+	}
+	if err := wd.emulateNetworkConditions(c); err != nil {
+		return err
+	}
+	wd.networkConditions = &c
+	return nil
+}
+
+// errNoNetworkConditions is returned by GetNetworkConditions if the network
+// conditions were set neither by the driver nor by SetNetworkConditions.
+var errNoNetworkConditions = errors.New("no network conditions are emulated")
+
+func (wd *remoteWD) GetNetworkConditions() (*NetworkConditions, error) {
+	if err := wd.checkNetworkConditions(); err != nil {
+		return nil, err
+	}
+	response, err := wd.execute("GET", wd.requestURL("/session/%s/chromium/network_conditions", wd.id), nil)
+	if HasErrorCode(err, CodeUnknownCommand) {
+		if wd.networkConditions == nil {
+			return nil, errNoNetworkConditions
+		}
+		c := *wd.networkConditions
+		return &c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	reply := new(struct{ Value *chromeNetworkConditions })
+	if err := json.Unmarshal(response, reply); err != nil {
+		return nil, err
+	}
+	if reply.Value == nil {
+		return nil, errNoNetworkConditions
+	}
+	c := &NetworkConditions{
+		Offline: reply.Value.Offline,
+		Latency: time.Duration(reply.Value.Latency * float64(time.Millisecond)),
+	}
+	if reply.Value.DownloadThroughput > 0 {
+		c.DownloadThroughput = int(reply.Value.DownloadThroughput)
+	}
+	if reply.Value.UploadThroughput > 0 {
+		c.UploadThroughput = int(reply.Value.UploadThroughput)
+	}
+	return c, nil
+}
+
+func (wd *remoteWD) DeleteNetworkConditions() error {
+	if err := wd.checkNetworkConditions(); err != nil {
+		return err
+	}
+	_, err := wd.execute("DELETE", wd.requestURL("/session/%s/chromium/network_conditions", wd.id), nil)
+	if !HasErrorCode(err, CodeUnknownCommand) {
+		return err
+	}
+	if err := wd.emulateNetworkConditions(NetworkConditions{}); err != nil {
+		return err
+	}
+	wd.networkConditions = nil
+	return nil
+}
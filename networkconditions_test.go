@@ -0,0 +1,183 @@
+package selenium
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// networkConditionsDriver is a fakeDriver that implements the network
+// conditions commands of ChromeDriver if chromium is set, and records the
+// Chrome DevTools Protocol commands it receives if cdp is set.
+type networkConditionsDriver struct {
+	*fakeDriver
+
+	mu         sync.Mutex
+	conditions map[string]interface{}
+	cdp        []string
+}
+
+func newNetworkConditionsDriver(t *testing.T, chromium, cdp bool) *networkConditionsDriver {
+	d := &networkConditionsDriver{fakeDriver: newFakeDriver(t)}
+	if cdp {
+		d.handle("POST", "/goog/cdp/execute", func(r *fakeRequest) (interface{}, error) {
+			var body struct {
+				Cmd    string
+				Params json.RawMessage
+			}
+			r.decode(t, &body)
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			d.cdp = append(d.cdp, body.Cmd+" "+string(body.Params))
+			return map[string]interface{}{}, nil
+		})
+	}
+	if chromium {
+		d.handle("POST", "/chromium/network_conditions", func(r *fakeRequest) (interface{}, error) {
+			var body struct {
+				NetworkConditions map[string]interface{} `json:"network_conditions"`
+			}
+			r.decode(t, &body)
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			d.conditions = body.NetworkConditions
+			return nil, nil
+		})
+		d.handle("GET", "/chromium/network_conditions", func(*fakeRequest) (interface{}, error) {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			if d.conditions == nil {
+				return nil, &Error{Err: "unknown error", Message: "network conditions must be set before it can be retrieved"}
+			}
+			return d.conditions, nil
+		})
+		d.handle("DELETE", "/chromium/network_conditions", func(*fakeRequest) (interface{}, error) {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			d.conditions = nil
+			return nil, nil
+		})
+	}
+	return d
+}
+
+// state returns the network conditions held by the driver and the CDP
+// commands it received.
+func (d *networkConditionsDriver) state() (map[string]interface{}, []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.conditions, append([]string(nil), d.cdp...)
+}
+
+func TestNetworkConditionsChromeDriver(t *testing.T) {
+	d := newNetworkConditionsDriver(t, true, true)
+	wd := d.driver("chrome")
+
+	if err := SetNetworkConditions(wd, NetworkFast3G); err != nil {
+		t.Fatalf("SetNetworkConditions() returned error: %v", err)
+	}
+	want := map[string]interface{}{"offline": false, "latency": 562.5, "download_throughput": 180000.0, "upload_throughput": 84375.0}
+	if conditions, _ := d.state(); !cmp.Equal(want, conditions) {
+		t.Errorf("SetNetworkConditions() sent diff (-want +got):\n%s", cmp.Diff(want, conditions))
+	}
+	got, err := GetNetworkConditions(wd)
+	if err != nil {
+		t.Fatalf("GetNetworkConditions() returned error: %v", err)
+	}
+	if *got != NetworkFast3G {
+		t.Errorf("GetNetworkConditions() = %+v, want %+v", *got, NetworkFast3G)
+	}
+
+	if err := SetNetworkConditions(wd, NetworkConditions{Latency: time.Second}); err != nil {
+		t.Fatalf("SetNetworkConditions() returned error: %v", err)
+	}
+	if conditions, _ := d.state(); conditions["download_throughput"] != -1.0 || conditions["upload_throughput"] != -1.0 {
+		t.Errorf("SetNetworkConditions() without throughputs sent %v, want throughputs of -1", conditions)
+	}
+	if got, err := GetNetworkConditions(wd); err != nil || *got != (NetworkConditions{Latency: time.Second}) {
+		t.Errorf("GetNetworkConditions() = %+v, %v", got, err)
+	}
+
+	if err := DeleteNetworkConditions(wd); err != nil {
+		t.Fatalf("DeleteNetworkConditions() returned error: %v", err)
+	}
+	if _, err := GetNetworkConditions(wd); err == nil {
+		t.Errorf("GetNetworkConditions() after DeleteNetworkConditions() returned no error")
+	}
+	if _, cdp := d.state(); len(cdp) != 0 {
+		t.Errorf("the driver received CDP commands %q", cdp)
+	}
+}
+
+func TestNetworkConditionsCDP(t *testing.T) {
+	d := newNetworkConditionsDriver(t, false, true)
+	wd := d.driver("chrome")
+
+	if _, err := GetNetworkConditions(wd); err == nil {
+		t.Errorf("GetNetworkConditions() before SetNetworkConditions() returned no error")
+	}
+	if err := SetNetworkConditions(wd, NetworkPresets["offline"]); err != nil {
+		t.Fatalf("SetNetworkConditions() returned error: %v", err)
+	}
+	got, err := GetNetworkConditions(wd)
+	if err != nil {
+		t.Fatalf("GetNetworkConditions() returned error: %v", err)
+	}
+	if *got != NetworkOffline {
+		t.Errorf("GetNetworkConditions() = %+v, want %+v", *got, NetworkOffline)
+	}
+	if err := DeleteNetworkConditions(wd); err != nil {
+		t.Fatalf("DeleteNetworkConditions() returned error: %v", err)
+	}
+	if _, err := GetNetworkConditions(wd); err == nil {
+		t.Errorf("GetNetworkConditions() after DeleteNetworkConditions() returned no error")
+	}
+
+	want := []string{
+		"Network.enable {}",
+		`Network.emulateNetworkConditions {"downloadThroughput":-1,"latency":0,"offline":true,"uploadThroughput":-1}`,
+		"Network.enable {}",
+		`Network.emulateNetworkConditions {"downloadThroughput":-1,"latency":0,"offline":false,"uploadThroughput":-1}`,
+	}
+	if _, cdp := d.state(); !cmp.Equal(want, cdp) {
+		t.Errorf("the driver received CDP commands with diff (-want +got):\n%s", cmp.Diff(want, cdp))
+	}
+}
+
+func TestNetworkConditionsUnsupported(t *testing.T) {
+	d := newNetworkConditionsDriver(t, true, true)
+	wd := d.driver("firefox")
+
+	if err := SetNetworkConditions(wd, NetworkSlow3G); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("SetNetworkConditions() on Firefox returned error %v, want ErrUnsupported", err)
+	}
+	if _, err := GetNetworkConditions(wd); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("GetNetworkConditions() on Firefox returned error %v, want ErrUnsupported", err)
+	}
+	if err := DeleteNetworkConditions(wd); !errors.Is(err, ErrUnsupported) || !strings.Contains(err.Error(), "firefox") {
+		t.Errorf("DeleteNetworkConditions() on Firefox returned error %v, want ErrUnsupported", err)
+	}
+	if len(d.received()) != 0 {
+		t.Errorf("the driver received commands %q", d.received())
+	}
+
+	// A server that forwards neither the network conditions nor the CDP
+	// commands cannot emulate network conditions.
+	wd = newNetworkConditionsDriver(t, false, false).driver("chrome")
+	if err := SetNetworkConditions(wd, NetworkSlow3G); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("SetNetworkConditions() without the commands returned error %v, want ErrUnsupported", err)
+	}
+	if err := DeleteNetworkConditions(wd); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("DeleteNetworkConditions() without the commands returned error %v, want ErrUnsupported", err)
+	}
+
+	// Other WebDrivers cannot emulate network conditions.
+	if err := SetNetworkConditions(struct{ WebDriver }{wd}, NetworkSlow3G); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("SetNetworkConditions() with another WebDriver returned error %v, want ErrUnsupported", err)
+	}
+}
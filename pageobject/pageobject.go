@@ -0,0 +1,573 @@
+// Package pageobject populates page objects, structs whose fields describe the
+// elements of a web page, from struct tags.
+//
+// Fields are described by a "selenium" struct tag holding a locator strategy
+// and value, optionally followed by comma-separated options:
+//
+//	type LoginPage struct {
+//		User     selenium.WebElement   `selenium:"id=user"`
+//		Password selenium.WebElement   `selenium:"css=input[type=password]"`
+//		Submit   selenium.WebElement   `selenium:"xpath=//button[@type='submit'],timeout=5s,visible"`
+//		Errors   pageobject.Elements   `selenium:"class=error"`
+//		Footer   Footer                `selenium:"tag=footer"`
+//	}
+//
+// The supported strategies are id, name, css, xpath, link, partiallink, tag and
+// class. The supported options are:
+//
+//	timeout=<duration>   wait up to this long for the element to be located.
+//	interval=<duration>  the polling interval used while waiting.
+//	visible              also wait for the element to be displayed.
+//
+// Fields of type selenium.WebElement are located lazily, when one of their
+// methods is first called, and are located again, once, when the driver
+// reports that the element has become stale. Fields of type Elements are
+// located each time they are called. Fields of type []selenium.WebElement are
+// populated with all of the matching elements when Init is called, without
+// waiting for them, and are not updated afterwards; their tag cannot have
+// options.
+//
+// Fields that are structs, or pointers to structs, are initialized as nested
+// components if they have a tag or if their type has tagged fields; if they
+// have a tag, the elements of the component are located relative to the
+// element it describes. Nil pointers are allocated. Other fields are left
+// unchanged, as are pointers to a struct type that encloses them, so that
+// recursive types such as a linked list do not recurse forever.
+package pageobject
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tebeka/selenium"
+)
+
+// TagName is the name of the struct tag that describes how to locate a field.
+const TagName = "selenium"
+
+// Elements locates all of the elements matching the locator of the field it
+// was created for, each time it is called.
+type Elements func() ([]selenium.WebElement, error)
+
+// finder is implemented by both selenium.WebDriver and selenium.WebElement.
+type finder interface {
+	FindElement(by, value string) (selenium.WebElement, error)
+	FindElements(by, value string) ([]selenium.WebElement, error)
+}
+
+var strategies = map[string]string{
+	"id":          selenium.ByID,
+	"name":        selenium.ByName,
+	"css":         selenium.ByCSSSelector,
+	"xpath":       selenium.ByXPATH,
+	"link":        selenium.ByLinkText,
+	"partiallink": selenium.ByPartialLinkText,
+	"tag":         selenium.ByTagName,
+	"class":       selenium.ByClassName,
+}
+
+// locator describes how to locate a field, as parsed from its struct tag.
+type locator struct {
+	by, value         string
+	timeout, interval time.Duration
+	visible           bool
+}
+
+func (l locator) String() string {
+	return fmt.Sprintf("%s=%q", l.by, l.value)
+}
+
+// parseTag parses a struct tag. As CSS selectors and XPath expressions can
+// contain commas, options are only recognized at the end of the tag.
+func parseTag(tag string) (*locator, error) {
+	l := new(locator)
+	parts := strings.Split(tag, ",")
+	for len(parts) > 1 {
+		opt := strings.TrimSpace(parts[len(parts)-1])
+		ok, err := l.parseOption(opt)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		parts = parts[:len(parts)-1]
+	}
+
+	loc := strings.Join(parts, ",")
+	i := strings.Index(loc, "=")
+	if i < 0 {
+		return nil, fmt.Errorf("invalid locator %q: expected strategy=value", loc)
+	}
+	by, ok := strategies[strings.TrimSpace(loc[:i])]
+	if !ok {
+		return nil, fmt.Errorf("invalid locator %q: unknown strategy %q", loc, loc[:i])
+	}
+	l.by, l.value = by, loc[i+1:]
+	return l, nil
+}
+
+// parseOption parses a single option into l. It returns false if opt is not
+// an option.
+func (l *locator) parseOption(opt string) (bool, error) {
+	if opt == "visible" {
+		l.visible = true
+		return true, nil
+	}
+	i := strings.Index(opt, "=")
+	if i < 0 {
+		return false, nil
+	}
+	var dst *time.Duration
+	switch opt[:i] {
+	case "timeout":
+		dst = &l.timeout
+	case "interval":
+		dst = &l.interval
+	default:
+		return false, nil
+	}
+	d, err := time.ParseDuration(opt[i+1:])
+	if err != nil {
+		return false, fmt.Errorf("invalid option %q: %v", opt, err)
+	}
+	*dst = d
+	return true, nil
+}
+
+// waiter returns the Waiter used to locate elements, or nil if the locator
+// does not require waiting.
+func (l *locator) waiter() *selenium.Waiter {
+	if l.timeout == 0 {
+		return nil
+	}
+	opts := []selenium.WaitOption{
+		selenium.WaitTimeout(l.timeout),
+		selenium.IgnoreErrors(selenium.CodeNoSuchElement, selenium.CodeStaleElementReference),
+		selenium.WaitMessage("locating " + l.String()),
+	}
+	if l.interval > 0 {
+		opts = append(opts, selenium.WaitInterval(l.interval))
+	}
+	return selenium.NewWaiter(opts...)
+}
+
+// find locates a single element within parent.
+func (l *locator) find(wd selenium.WebDriver, parent finder) (selenium.WebElement, error) {
+	find := func() (selenium.WebElement, error) {
+		elem, err := parent.FindElement(l.by, l.value)
+		if err != nil {
+			return nil, err
+		}
+		if l.visible {
+			displayed, err := elem.IsDisplayed()
+			if err != nil {
+				return nil, err
+			}
+			if !displayed {
+				return nil, nil
+			}
+		}
+		return elem, nil
+	}
+
+	w := l.waiter()
+	if w == nil {
+		elem, err := find()
+		if err == nil && elem == nil {
+			err = fmt.Errorf("element %s is not displayed", l)
+		}
+		return elem, err
+	}
+	var elem selenium.WebElement
+	err := w.Until(wd, func(selenium.WebDriver) (bool, error) {
+		var err error
+		elem, err = find()
+		return elem != nil, err
+	})
+	return elem, err
+}
+
+// findAll locates all matching elements within parent. When waiting, it waits
+// until at least one element is found.
+func (l *locator) findAll(wd selenium.WebDriver, parent finder) ([]selenium.WebElement, error) {
+	find := func() ([]selenium.WebElement, error) {
+		elems, err := parent.FindElements(l.by, l.value)
+		if err != nil || !l.visible {
+			return elems, err
+		}
+		var visible []selenium.WebElement
+		for _, elem := range elems {
+			displayed, err := elem.IsDisplayed()
+			if err != nil {
+				return nil, err
+			}
+			if displayed {
+				visible = append(visible, elem)
+			}
+		}
+		return visible, nil
+	}
+
+	w := l.waiter()
+	if w == nil {
+		return find()
+	}
+	var elems []selenium.WebElement
+	err := w.Until(wd, func(selenium.WebDriver) (bool, error) {
+		var err error
+		elems, err = find()
+		return len(elems) > 0, err
+	})
+	return elems, err
+}
+
+var (
+	webElementType  = reflect.TypeOf((*selenium.WebElement)(nil)).Elem()
+	webElementsType = reflect.TypeOf([]selenium.WebElement(nil))
+	elementsType    = reflect.TypeOf(Elements(nil))
+)
+
+// Init populates the fields of the struct pointed to by page, locating the
+// elements using wd.
+func Init(wd selenium.WebDriver, page interface{}) error {
+	v := reflect.ValueOf(page)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("page must be a pointer to a struct, got %T", page)
+	}
+	return initStruct(wd, wd, v.Elem(), map[reflect.Type]bool{})
+}
+
+// initStruct initializes the fields of v. enclosing holds the types of the
+// components being initialized, v's included.
+func initStruct(wd selenium.WebDriver, parent finder, v reflect.Value, enclosing map[reflect.Type]bool) error {
+	t := v.Type()
+	enclosing[t] = true
+	defer delete(enclosing, t)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			// Unexported field.
+			continue
+		}
+		tag, hasTag := field.Tag.Lookup(TagName)
+		var loc *locator
+		if hasTag {
+			var err error
+			if loc, err = parseTag(tag); err != nil {
+				return fmt.Errorf("field %s: %v", field.Name, err)
+			}
+		}
+		if err := initField(wd, parent, loc, v.Field(i), enclosing); err != nil {
+			return fmt.Errorf("field %s: %v", field.Name, err)
+		}
+	}
+	return nil
+}
+
+func initField(wd selenium.WebDriver, parent finder, loc *locator, f reflect.Value, enclosing map[reflect.Type]bool) error {
+	switch {
+	case f.Type() == webElementType:
+		if loc == nil {
+			return nil
+		}
+		f.Set(reflect.ValueOf(&element{wd: wd, parent: parent, loc: loc}))
+
+	case f.Type() == elementsType:
+		if loc == nil {
+			return nil
+		}
+		f.Set(reflect.ValueOf(Elements(func() ([]selenium.WebElement, error) {
+			return loc.findAll(wd, parent)
+		})))
+
+	case f.Type() == webElementsType:
+		if loc == nil {
+			return nil
+		}
+		if loc.timeout != 0 || loc.interval != 0 || loc.visible {
+			return fmt.Errorf("options are not supported for a field of type %s, use pageobject.Elements", f.Type())
+		}
+		elems, err := parent.FindElements(loc.by, loc.value)
+		if err != nil {
+			return err
+		}
+		f.Set(reflect.ValueOf(elems))
+
+	case f.Kind() == reflect.Struct:
+		if loc == nil && !hasTaggedFields(f.Type(), map[reflect.Type]bool{}) {
+			return nil
+		}
+		return initComponent(wd, parent, loc, f, enclosing)
+
+	case f.Kind() == reflect.Ptr && f.Type().Elem().Kind() == reflect.Struct:
+		t := f.Type().Elem()
+		if enclosing[t] || (loc == nil && !hasTaggedFields(t, map[reflect.Type]bool{})) {
+			return nil
+		}
+		if f.IsNil() {
+			f.Set(reflect.New(t))
+		}
+		return initComponent(wd, parent, loc, f.Elem(), enclosing)
+
+	default:
+		if loc != nil {
+			return fmt.Errorf("unsupported type %s for a tagged field", f.Type())
+		}
+	}
+	return nil
+}
+
+// hasTaggedFields reports whether the struct type t has tagged fields, or
+// components that do. visited holds the types already inspected, which are
+// skipped.
+func hasTaggedFields(t reflect.Type, visited map[reflect.Type]bool) bool {
+	visited[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		if _, ok := field.Tag.Lookup(TagName); ok {
+			return true
+		}
+		ft := field.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct && !visited[ft] && hasTaggedFields(ft, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+func initComponent(wd selenium.WebDriver, parent finder, loc *locator, v reflect.Value, enclosing map[reflect.Type]bool) error {
+	if loc != nil {
+		parent = &element{wd: wd, parent: parent, loc: loc}
+	}
+	return initStruct(wd, parent, v, enclosing)
+}
+
+// element is a selenium.WebElement that is located when first used and
+// located again when it becomes stale.
+type element struct {
+	wd     selenium.WebDriver
+	parent finder
+	loc    *locator
+
+	mu   sync.Mutex // guards elem
+	elem selenium.WebElement
+}
+
+// resolve returns the located element, locating it if it has not been
+// located yet or if refresh is true.
+func (e *element) resolve(refresh bool) (selenium.WebElement, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.elem != nil && !refresh {
+		return e.elem, nil
+	}
+	elem, err := e.loc.find(e.wd, e.parent)
+	if err != nil {
+		return nil, fmt.Errorf("locating element %s: %w", e.loc, err)
+	}
+	e.elem = elem
+	return elem, nil
+}
+
+// do calls f with the located element, locating the element again and
+// retrying once if f reports that the element is stale.
+func (e *element) do(f func(selenium.WebElement) error) error {
+	elem, err := e.resolve(false)
+	if err != nil {
+		return err
+	}
+	err = f(elem)
+	if !selenium.HasErrorCode(err, selenium.CodeStaleElementReference) {
+		return err
+	}
+	if elem, err = e.resolve(true); err != nil {
+		return err
+	}
+	return f(elem)
+}
+
+func (e *element) Click() error {
+	return e.do(func(elem selenium.WebElement) error { return elem.Click() })
+}
+
+func (e *element) SendKeys(keys string) error {
+	return e.do(func(elem selenium.WebElement) error { return elem.SendKeys(keys) })
+}
+
+func (e *element) Submit() error {
+	return e.do(func(elem selenium.WebElement) error { return elem.Submit() })
+}
+
+func (e *element) Clear() error {
+	return e.do(func(elem selenium.WebElement) error { return elem.Clear() })
+}
+
+func (e *element) MoveTo(xOffset, yOffset int) error {
+	return e.do(func(elem selenium.WebElement) error { return elem.MoveTo(xOffset, yOffset) })
+}
+
+func (e *element) FindElement(by, value string) (selenium.WebElement, error) {
+	var found selenium.WebElement
+	err := e.do(func(elem selenium.WebElement) error {
+		var err error
+		found, err = elem.FindElement(by, value)
+		return err
+	})
+	return found, err
+}
+
+func (e *element) FindElements(by, value string) ([]selenium.WebElement, error) {
+	var found []selenium.WebElement
+	err := e.do(func(elem selenium.WebElement) error {
+		var err error
+		found, err = elem.FindElements(by, value)
+		return err
+	})
+	return found, err
+}
+
+func (e *element) FindElementBy(l selenium.Locator) (selenium.WebElement, error) {
+	var found selenium.WebElement
+	err := e.do(func(elem selenium.WebElement) error {
+		var err error
+		found, err = selenium.FindElementBy(elem, l)
+		return err
+	})
+	return found, err
+}
+
+func (e *element) FindElementsBy(l selenium.Locator) ([]selenium.WebElement, error) {
+	var found []selenium.WebElement
+	err := e.do(func(elem selenium.WebElement) error {
+		var err error
+		found, err = selenium.FindElementsBy(elem, l)
+		return err
+	})
+	return found, err
+}
+
+func (e *element) stringQuery(f func(selenium.WebElement) (string, error)) (string, error) {
+	var s string
+	err := e.do(func(elem selenium.WebElement) error {
+		var err error
+		s, err = f(elem)
+		return err
+	})
+	return s, err
+}
+
+func (e *element) boolQuery(f func(selenium.WebElement) (bool, error)) (bool, error) {
+	var b bool
+	err := e.do(func(elem selenium.WebElement) error {
+		var err error
+		b, err = f(elem)
+		return err
+	})
+	return b, err
+}
+
+func (e *element) TagName() (string, error) {
+	return e.stringQuery(selenium.WebElement.TagName)
+}
+
+func (e *element) Text() (string, error) {
+	return e.stringQuery(selenium.WebElement.Text)
+}
+
+func (e *element) IsSelected() (bool, error) {
+	return e.boolQuery(selenium.WebElement.IsSelected)
+}
+
+func (e *element) IsEnabled() (bool, error) {
+	return e.boolQuery(selenium.WebElement.IsEnabled)
+}
+
+func (e *element) IsDisplayed() (bool, error) {
+	return e.boolQuery(selenium.WebElement.IsDisplayed)
+}
+
+func (e *element) GetAttribute(name string) (string, error) {
+	return e.stringQuery(func(elem selenium.WebElement) (string, error) { return elem.GetAttribute(name) })
+}
+
+func (e *element) GetProperty(name string) (string, error) {
+	return e.stringQuery(func(elem selenium.WebElement) (string, error) { return elem.GetProperty(name) })
+}
+
+func (e *element) CSSProperty(name string) (string, error) {
+	return e.stringQuery(func(elem selenium.WebElement) (string, error) { return elem.CSSProperty(name) })
+}
+
+func (e *element) pointQuery(f func(selenium.WebElement) (*selenium.Point, error)) (*selenium.Point, error) {
+	var p *selenium.Point
+	err := e.do(func(elem selenium.WebElement) error {
+		var err error
+		p, err = f(elem)
+		return err
+	})
+	return p, err
+}
+
+func (e *element) Location() (*selenium.Point, error) {
+	return e.pointQuery(selenium.WebElement.Location)
+}
+
+func (e *element) LocationInView() (*selenium.Point, error) {
+	return e.pointQuery(selenium.WebElement.LocationInView)
+}
+
+func (e *element) Size() (*selenium.Size, error) {
+	var s *selenium.Size
+	err := e.do(func(elem selenium.WebElement) error {
+		var err error
+		s, err = elem.Size()
+		return err
+	})
+	return s, err
+}
+
+func (e *element) Screenshot(scroll bool) ([]byte, error) {
+	var buf []byte
+	err := e.do(func(elem selenium.WebElement) error {
+		var err error
+		buf, err = elem.Screenshot(scroll)
+		return err
+	})
+	return buf, err
+}
+
+func (e *element) ScreenshotImage(opts *selenium.ScreenshotOptions) (image.Image, error) {
+	var img image.Image
+	err := e.do(func(elem selenium.WebElement) error {
+		var err error
+		img, err = selenium.ElementScreenshotImage(elem, opts)
+		return err
+	})
+	return img, err
+}
+
+// MarshalJSON locates the element and encodes it as a web element reference,
+// so that it can be passed as an argument to ExecuteScript and SwitchFrame.
+func (e *element) MarshalJSON() ([]byte, error) {
+	elem, err := e.resolve(false)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := elem.(interface{ MarshalJSON() ([]byte, error) })
+	if !ok {
+		return nil, errors.New("located element cannot be encoded")
+	}
+	return m.MarshalJSON()
+}
@@ -0,0 +1,326 @@
+package pageobject
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tebeka/selenium"
+)
+
+// fakeFinder locates fakeElements by "strategy=value" keys. Elements with a
+// delay are only found once they have been searched for that many times.
+type fakeFinder struct {
+	elems map[string][]*fakeElement
+	delay map[string]int
+	finds map[string]int
+}
+
+func (f *fakeFinder) FindElement(by, value string) (selenium.WebElement, error) {
+	key := by + "=" + value
+	if f.finds == nil {
+		f.finds = make(map[string]int)
+	}
+	f.finds[key]++
+	elems := f.elems[key]
+	if len(elems) == 0 || f.finds[key] <= f.delay[key] {
+		return nil, &selenium.Error{Err: selenium.CodeNoSuchElement}
+	}
+	return elems[0], nil
+}
+
+func (f *fakeFinder) FindElements(by, value string) ([]selenium.WebElement, error) {
+	var elems []selenium.WebElement
+	for _, e := range f.elems[by+"="+value] {
+		elems = append(elems, e)
+	}
+	return elems, nil
+}
+
+type fakeDriver struct {
+	selenium.WebDriver
+	fakeFinder
+}
+
+func (wd *fakeDriver) FindElement(by, value string) (selenium.WebElement, error) {
+	return wd.fakeFinder.FindElement(by, value)
+}
+
+func (wd *fakeDriver) FindElements(by, value string) ([]selenium.WebElement, error) {
+	return wd.fakeFinder.FindElements(by, value)
+}
+
+type fakeElement struct {
+	selenium.WebElement
+	fakeFinder
+
+	text   string
+	hidden bool
+	stale  bool
+	clicks int
+}
+
+func (e *fakeElement) FindElement(by, value string) (selenium.WebElement, error) {
+	return e.fakeFinder.FindElement(by, value)
+}
+
+func (e *fakeElement) FindElements(by, value string) ([]selenium.WebElement, error) {
+	return e.fakeFinder.FindElements(by, value)
+}
+
+func (e *fakeElement) Click() error {
+	if e.stale {
+		return &selenium.Error{Err: selenium.CodeStaleElementReference}
+	}
+	e.clicks++
+	return nil
+}
+
+func (e *fakeElement) Text() (string, error) { return e.text, nil }
+
+func (e *fakeElement) IsDisplayed() (bool, error) { return !e.hidden, nil }
+
+type footer struct {
+	Copyright selenium.WebElement `selenium:"class=copyright"`
+}
+
+type loginPage struct {
+	User    selenium.WebElement `selenium:"id=user"`
+	Submit  selenium.WebElement `selenium:"css=button.submit, input[type=submit],timeout=1s,interval=1ms"`
+	Missing selenium.WebElement `selenium:"id=missing"`
+	Errors  Elements            `selenium:"class=error"`
+	Links   Elements            `selenium:"tag=a"`
+	Footer  *footer             `selenium:"tag=footer"`
+
+	Title   string
+	Client  *http.Client
+	Next    *loginPage
+	private selenium.WebElement
+}
+
+func TestInit(t *testing.T) {
+	user := &fakeElement{text: "user"}
+	submit := &fakeElement{text: "submit"}
+	copyright := &fakeElement{text: "(c) 2017"}
+	wd := &fakeDriver{fakeFinder: fakeFinder{elems: map[string][]*fakeElement{
+		"css selector=#user": {user},
+		"tag name=a":         {{text: "home"}, {text: "about"}},
+		"tag name=footer": {{fakeFinder: fakeFinder{elems: map[string][]*fakeElement{
+			"class name=copyright": {copyright},
+		}}}},
+	}}}
+	// ByID is sent as-is to the fake driver.
+	wd.elems["id=user"] = []*fakeElement{user}
+
+	var page loginPage
+	if err := Init(wd, &page); err != nil {
+		t.Fatalf("Init() returned error: %v", err)
+	}
+	if links, err := page.Links(); err != nil || len(links) != 2 {
+		t.Fatalf("page.Links() = %v, %v, want two elements", links, err)
+	}
+	if page.private != nil {
+		t.Fatalf("page.private = %v, want nil", page.private)
+	}
+	if page.Client != nil {
+		t.Fatalf("page.Client = %v, want nil", page.Client)
+	}
+	if page.Next != nil {
+		t.Fatalf("page.Next = %v, want nil", page.Next)
+	}
+	if n := wd.finds["id=user"]; n != 0 {
+		t.Fatalf("page.User was located %d times before being used, want 0", n)
+	}
+
+	if got, err := page.User.Text(); err != nil || got != "user" {
+		t.Fatalf("page.User.Text() = %q, %v, want %q, nil", got, err, "user")
+	}
+	if got, err := page.Footer.Copyright.Text(); err != nil || got != "(c) 2017" {
+		t.Fatalf("page.Footer.Copyright.Text() = %q, %v, want %q, nil", got, err, "(c) 2017")
+	}
+	if _, err := page.Missing.Text(); !selenium.HasErrorCode(err, selenium.CodeNoSuchElement) {
+		t.Fatalf("page.Missing.Text() returned error %v, want %q", err, selenium.CodeNoSuchElement)
+	}
+
+	// The submit button only appears after a few attempts to locate it.
+	const submitKey = "css selector=button.submit, input[type=submit]"
+	wd.elems[submitKey] = []*fakeElement{submit}
+	wd.delay = map[string]int{submitKey: 3}
+	if err := page.Submit.Click(); err != nil {
+		t.Fatalf("page.Submit.Click() returned error: %v", err)
+	}
+	if submit.clicks != 1 {
+		t.Fatalf("submit.clicks = %d, want 1", submit.clicks)
+	}
+
+	errs, err := page.Errors()
+	if err != nil || len(errs) != 0 {
+		t.Fatalf("page.Errors() = %v, %v, want no elements", errs, err)
+	}
+	wd.elems["class name=error"] = []*fakeElement{{text: "invalid password"}}
+	if errs, err = page.Errors(); err != nil || len(errs) != 1 {
+		t.Fatalf("page.Errors() = %v, %v, want one element", errs, err)
+	}
+}
+
+func TestStaleElement(t *testing.T) {
+	old := &fakeElement{stale: true}
+	wd := &fakeDriver{fakeFinder: fakeFinder{elems: map[string][]*fakeElement{
+		"id=button": {old},
+	}}}
+
+	var page struct {
+		Button selenium.WebElement `selenium:"id=button"`
+	}
+	if err := Init(wd, &page); err != nil {
+		t.Fatalf("Init() returned error: %v", err)
+	}
+	if _, err := page.Button.Text(); err != nil {
+		t.Fatalf("page.Button.Text() returned error: %v", err)
+	}
+
+	// The page re-rendered the button after it was located.
+	fresh := &fakeElement{}
+	wd.elems["id=button"] = []*fakeElement{fresh}
+	if err := page.Button.Click(); err != nil {
+		t.Fatalf("page.Button.Click() returned error: %v", err)
+	}
+	if fresh.clicks != 1 {
+		t.Fatalf("fresh.clicks = %d, want 1", fresh.clicks)
+	}
+	if n := wd.finds["id=button"]; n != 2 {
+		t.Fatalf("button located %d times, want 2", n)
+	}
+}
+
+func TestParseTag(t *testing.T) {
+	for _, tc := range []struct {
+		tag  string
+		want locator
+	}{
+		{"id=login", locator{by: selenium.ByID, value: "login"}},
+		{"css=a, b", locator{by: selenium.ByCSSSelector, value: "a, b"}},
+		{"xpath=//a[@x='=']", locator{by: selenium.ByXPATH, value: "//a[@x='=']"}},
+		{"css=#login,timeout=2s,interval=50ms,visible", locator{
+			by:       selenium.ByCSSSelector,
+			value:    "#login",
+			timeout:  2 * time.Second,
+			interval: 50 * time.Millisecond,
+			visible:  true,
+		}},
+	} {
+		got, err := parseTag(tc.tag)
+		if err != nil {
+			t.Errorf("parseTag(%q) returned error: %v", tc.tag, err)
+			continue
+		}
+		if *got != tc.want {
+			t.Errorf("parseTag(%q) = %+v, want %+v", tc.tag, *got, tc.want)
+		}
+	}
+
+	for _, tag := range []string{"", "login", "foo=bar", "id=x,timeout=soon"} {
+		if _, err := parseTag(tag); err == nil {
+			t.Errorf("parseTag(%q) did not return an error", tag)
+		}
+	}
+}
+
+func TestInitErrors(t *testing.T) {
+	wd := &fakeDriver{}
+	var page struct {
+		Name string `selenium:"id=name"`
+	}
+	if err := Init(wd, &page); err == nil {
+		t.Errorf("Init() with a tagged string field did not return an error")
+	}
+	if err := Init(wd, page); err == nil {
+		t.Errorf("Init() with a non-pointer did not return an error")
+	}
+	var list struct {
+		Links []selenium.WebElement `selenium:"tag=a,visible"`
+	}
+	if err := Init(wd, &list); err == nil {
+		t.Errorf("Init() with options on a []selenium.WebElement field did not return an error")
+	}
+}
+
+func TestInitElementSlice(t *testing.T) {
+	home, about := &fakeElement{text: "home"}, &fakeElement{text: "about", hidden: true}
+	wd := &fakeDriver{fakeFinder: fakeFinder{elems: map[string][]*fakeElement{
+		"tag name=a": {home, about},
+		"tag name=nav": {{fakeFinder: fakeFinder{elems: map[string][]*fakeElement{
+			"tag name=a": {home},
+		}}}},
+	}}}
+
+	var page struct {
+		Links   []selenium.WebElement `selenium:"tag=a"`
+		Missing []selenium.WebElement `selenium:"class=missing"`
+		Nav     struct {
+			Links []selenium.WebElement `selenium:"tag=a"`
+		} `selenium:"tag=nav"`
+		Untagged []selenium.WebElement
+	}
+	if err := Init(wd, &page); err != nil {
+		t.Fatalf("Init() returned error: %v", err)
+	}
+	// Hidden elements are included.
+	if len(page.Links) != 2 || page.Links[0] != home || page.Links[1] != about {
+		t.Errorf("page.Links = %v, want both links", page.Links)
+	}
+	if len(page.Missing) != 0 {
+		t.Errorf("page.Missing = %v, want no elements", page.Missing)
+	}
+	if len(page.Nav.Links) != 1 || page.Nav.Links[0] != home {
+		t.Errorf("page.Nav.Links = %v, want the link of the component", page.Nav.Links)
+	}
+	if page.Untagged != nil {
+		t.Errorf("page.Untagged = %v, want nil", page.Untagged)
+	}
+}
+
+type node struct {
+	Label selenium.WebElement `selenium:"class=label"`
+	Next  *node
+}
+
+type tree struct {
+	Root node `selenium:"id=root"`
+}
+
+func TestInitRecursiveType(t *testing.T) {
+	wd := &fakeDriver{}
+	var page tree
+	if err := Init(wd, &page); err != nil {
+		t.Fatalf("Init() returned error: %v", err)
+	}
+	if page.Root.Label == nil {
+		t.Errorf("page.Root.Label = nil, want an element")
+	}
+	if page.Root.Next != nil {
+		t.Errorf("page.Root.Next = %v, want nil", page.Root.Next)
+	}
+}
+
+func TestConcurrentElement(t *testing.T) {
+	wd := &fakeDriver{fakeFinder: fakeFinder{elems: map[string][]*fakeElement{
+		"id=user": {{text: "user"}},
+	}}}
+	var page loginPage
+	if err := Init(wd, &page); err != nil {
+		t.Fatalf("Init() returned error: %v", err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := page.User.Text(); err != nil {
+				t.Errorf("page.User.Text() returned error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,439 @@
+// Package perflog parses the performance log of Chrome into typed DevTools
+// events, and correlates the events of each network request.
+//
+// The performance log is enabled with Enable, and its messages are returned
+// by WebDriver.Log(log.Performance):
+//
+//	caps := selenium.Capabilities{"browserName": "chrome"}
+//	perflog.Enable(caps, nil)
+//	wd, err := selenium.NewRemote(caps, "")
+//	...
+//	var l perflog.Log
+//	if err := l.Fetch(wd); err != nil {
+//		...
+//	}
+//	for _, r := range l.Requests() {
+//		if r.Response != nil {
+//			fmt.Println(r.Sent.Request.URL, r.Response.Response.Status)
+//		}
+//	}
+package perflog
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tebeka/selenium"
+	"github.com/tebeka/selenium/chrome"
+	"github.com/tebeka/selenium/log"
+)
+
+// Enable configures caps for ChromeDriver to record the performance log with
+// the given preferences, which may be nil for the defaults of ChromeDriver.
+// The preferences are set in the Chrome capabilities of caps, which are
+// created in W3C mode if caps has none.
+func Enable(caps selenium.Capabilities, prefs *chrome.PerfLoggingPreferences) {
+	caps.SetLogLevel(log.Performance, log.All)
+	if prefs == nil {
+		prefs = new(chrome.PerfLoggingPreferences)
+	}
+	c, ok := caps[chrome.CapabilitiesKey].(chrome.Capabilities)
+	if !ok {
+		c = chrome.Capabilities{W3C: true}
+	}
+	c.PerfLoggingPrefs = prefs
+	caps.AddChrome(c)
+}
+
+// Names of the events with typed parameters.
+const (
+	EventRequestWillBeSent       = "Network.requestWillBeSent"
+	EventResponseReceived        = "Network.responseReceived"
+	EventDataReceived            = "Network.dataReceived"
+	EventLoadingFinished         = "Network.loadingFinished"
+	EventLoadingFailed           = "Network.loadingFailed"
+	EventDOMContentEventFired    = "Page.domContentEventFired"
+	EventLoadEventFired          = "Page.loadEventFired"
+	EventFrameNavigated          = "Page.frameNavigated"
+	EventFrameStartedLoading     = "Page.frameStartedLoading"
+	EventFrameStoppedLoading     = "Page.frameStoppedLoading"
+	EventLifecycleEvent          = "Page.lifecycleEvent"
+	EventNavigatedWithinDocument = "Page.navigatedWithinDocument"
+)
+
+// Event is an event of the performance log.
+type Event struct {
+	Method string
+	// Params holds the parameters of the event: for the events named by the
+	// Event constants, a pointer to the type that documents them, such as
+	// *RequestWillBeSent, and a json.RawMessage for the other events.
+	Params interface{}
+	// WebView is the ID of the page or worker that sent the event.
+	WebView string
+	// Time is the time at which ChromeDriver logged the event.
+	Time time.Time
+}
+
+// Headers are HTTP headers. The values of repeated headers are joined with
+// newlines.
+type Headers map[string]string
+
+// UnmarshalJSON implements the json.Unmarshaler interface. It accepts
+// non-string values, which Chrome sends for some headers.
+func (h *Headers) UnmarshalJSON(data []byte) error {
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*h = make(Headers, len(m))
+	for k, v := range m {
+		if s, ok := v.(string); ok {
+			(*h)[k] = s
+		} else {
+			(*h)[k] = fmt.Sprint(v)
+		}
+	}
+	return nil
+}
+
+// MonotonicTime is a time in seconds since an arbitrary point in the past,
+// which is the same for all the events of a browser.
+type MonotonicTime float64
+
+// Request describes a request.
+type Request struct {
+	URL         string
+	URLFragment string
+	Method      string
+	Headers     Headers
+	PostData    string
+	HasPostData bool
+}
+
+// ResourceTiming holds the timings of a request. The times other than
+// RequestTime are in milliseconds since RequestTime, and are -1 for the
+// phases the request skipped.
+type ResourceTiming struct {
+	RequestTime       MonotonicTime
+	ProxyStart        float64
+	ProxyEnd          float64
+	DNSStart          float64 `json:"dnsStart"`
+	DNSEnd            float64 `json:"dnsEnd"`
+	ConnectStart      float64
+	ConnectEnd        float64
+	SSLStart          float64 `json:"sslStart"`
+	SSLEnd            float64 `json:"sslEnd"`
+	SendStart         float64
+	SendEnd           float64
+	ReceiveHeadersEnd float64
+}
+
+// Response describes a response.
+type Response struct {
+	URL               string
+	Status            int
+	StatusText        string
+	Headers           Headers
+	MIMEType          string `json:"mimeType"`
+	RequestHeaders    Headers
+	ConnectionReused  bool
+	ConnectionID      float64 `json:"connectionId"`
+	RemoteIPAddress   string  `json:"remoteIPAddress"`
+	RemotePort        int
+	FromDiskCache     bool
+	FromServiceWorker bool
+	FromPrefetchCache bool
+	EncodedDataLength float64
+	Timing            *ResourceTiming
+	Protocol          string
+}
+
+// RequestWillBeSent holds the parameters of the Network.requestWillBeSent
+// event, which is sent for each request and for each of its redirects.
+type RequestWillBeSent struct {
+	RequestID   string `json:"requestId"`
+	LoaderID    string `json:"loaderId"`
+	DocumentURL string `json:"documentURL"`
+	Request     Request
+	Timestamp   MonotonicTime
+	// WallTime is the time of the event in seconds since the epoch.
+	WallTime float64
+	// RedirectResponse is the response that redirected the request, if any.
+	RedirectResponse *Response
+	// Type is the type of the resource, such as "Document" or "Script".
+	Type    string
+	FrameID string `json:"frameId"`
+}
+
+// ResponseReceived holds the parameters of the Network.responseReceived
+// event.
+type ResponseReceived struct {
+	RequestID string `json:"requestId"`
+	LoaderID  string `json:"loaderId"`
+	Timestamp MonotonicTime
+	Type      string
+	Response  Response
+	FrameID   string `json:"frameId"`
+}
+
+// DataReceived holds the parameters of the Network.dataReceived event.
+type DataReceived struct {
+	RequestID         string `json:"requestId"`
+	Timestamp         MonotonicTime
+	DataLength        int64
+	EncodedDataLength int64
+}
+
+// LoadingFinished holds the parameters of the Network.loadingFinished event.
+type LoadingFinished struct {
+	RequestID string `json:"requestId"`
+	Timestamp MonotonicTime
+	// EncodedDataLength is the number of bytes received for the request,
+	// headers included.
+	EncodedDataLength float64
+}
+
+// LoadingFailed holds the parameters of the Network.loadingFailed event.
+type LoadingFailed struct {
+	RequestID     string `json:"requestId"`
+	Timestamp     MonotonicTime
+	Type          string
+	ErrorText     string
+	Canceled      bool
+	BlockedReason string
+}
+
+// PageTimestamp holds the parameters of the Page.domContentEventFired and
+// Page.loadEventFired events.
+type PageTimestamp struct {
+	Timestamp MonotonicTime
+}
+
+// Frame describes a frame.
+type Frame struct {
+	ID       string
+	ParentID string `json:"parentId"`
+	LoaderID string `json:"loaderId"`
+	Name     string
+	URL      string
+	MIMEType string `json:"mimeType"`
+}
+
+// FrameNavigated holds the parameters of the Page.frameNavigated event.
+type FrameNavigated struct {
+	Frame Frame
+	Type  string
+}
+
+// FrameEvent holds the parameters of the Page.frameStartedLoading and
+// Page.frameStoppedLoading events.
+type FrameEvent struct {
+	FrameID string `json:"frameId"`
+}
+
+// LifecycleEvent holds the parameters of the Page.lifecycleEvent event.
+type LifecycleEvent struct {
+	FrameID   string `json:"frameId"`
+	LoaderID  string `json:"loaderId"`
+	Name      string
+	Timestamp MonotonicTime
+}
+
+// NavigatedWithinDocument holds the parameters of the
+// Page.navigatedWithinDocument event.
+type NavigatedWithinDocument struct {
+	FrameID string `json:"frameId"`
+	URL     string
+}
+
+// newParams returns a pointer to the type of the parameters of the event
+// method, or nil if the event has no typed parameters.
+func newParams(method string) interface{} {
+	switch method {
+	case EventRequestWillBeSent:
+		return new(RequestWillBeSent)
+	case EventResponseReceived:
+		return new(ResponseReceived)
+	case EventDataReceived:
+		return new(DataReceived)
+	case EventLoadingFinished:
+		return new(LoadingFinished)
+	case EventLoadingFailed:
+		return new(LoadingFailed)
+	case EventDOMContentEventFired, EventLoadEventFired:
+		return new(PageTimestamp)
+	case EventFrameNavigated:
+		return new(FrameNavigated)
+	case EventFrameStartedLoading, EventFrameStoppedLoading:
+		return new(FrameEvent)
+	case EventLifecycleEvent:
+		return new(LifecycleEvent)
+	case EventNavigatedWithinDocument:
+		return new(NavigatedWithinDocument)
+	}
+	return nil
+}
+
+// ParseError is returned by Parse when some messages cannot be parsed. It
+// holds an error for each of them.
+type ParseError struct {
+	Errs []error
+}
+
+// Error implements the error interface.
+func (e *ParseError) Error() string {
+	msgs := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d performance log messages could not be parsed: %s", len(e.Errs), strings.Join(msgs, "; "))
+}
+
+// Parse parses the messages of the performance log. The messages that cannot
+// be parsed are skipped: the events of the others are returned along with a
+// *ParseError.
+func Parse(messages []log.Message) ([]Event, error) {
+	events := make([]Event, 0, len(messages))
+	var errs []error
+	for i, m := range messages {
+		var entry struct {
+			Message struct {
+				Method string
+				Params json.RawMessage
+			}
+			WebView string
+		}
+		if err := json.Unmarshal([]byte(m.Message), &entry); err != nil {
+			errs = append(errs, fmt.Errorf("parsing performance log message %d: %v", i, err))
+			continue
+		}
+		e := Event{
+			Method:  entry.Message.Method,
+			Params:  entry.Message.Params,
+			WebView: entry.WebView,
+			Time:    m.Timestamp,
+		}
+		if params := newParams(e.Method); params != nil {
+			if err := json.Unmarshal(entry.Message.Params, params); err != nil {
+				errs = append(errs, fmt.Errorf("parsing the parameters of performance log message %d (%s): %v", i, e.Method, err))
+				continue
+			}
+			e.Params = params
+		}
+		events = append(events, e)
+	}
+	if len(errs) > 0 {
+		return events, &ParseError{Errs: errs}
+	}
+	return events, nil
+}
+
+// NetworkRequest holds the events of a request, correlated by request ID. A
+// redirected request is split into one NetworkRequest per URL, all with the
+// same ID: the response of each one but the last is the redirect response.
+type NetworkRequest struct {
+	ID   string
+	Sent *RequestWillBeSent
+	// Response is nil until the response is received.
+	Response *ResponseReceived
+	// Finished is set once the response has been received entirely.
+	Finished *LoadingFinished
+	// Failed is set if the request failed.
+	Failed *LoadingFailed
+	// Redirected is set if the response is a redirect.
+	Redirected bool
+	// DataLength is the size of the body received, after decoding.
+	DataLength int64
+}
+
+// Log accumulates the events of the performance log of a session. The zero
+// value is an empty log. ChromeDriver returns each message of the
+// performance log once, so the messages must be added to a single Log to
+// correlate the events of requests that span several calls to
+// WebDriver.Log.
+type Log struct {
+	events   []Event
+	requests []*NetworkRequest
+	// byID holds the latest request with each ID.
+	byID map[string]*NetworkRequest
+}
+
+// Fetch adds the messages of the performance log of wd.
+func (l *Log) Fetch(wd selenium.WebDriver) error {
+	messages, err := wd.Log(log.Performance)
+	if err != nil {
+		return err
+	}
+	return l.Add(messages)
+}
+
+// Add parses and adds messages of the performance log. The messages that
+// cannot be parsed are skipped, and reported by the *ParseError returned
+// after adding the others.
+func (l *Log) Add(messages []log.Message) error {
+	events, err := Parse(messages)
+	if l.byID == nil {
+		l.byID = make(map[string]*NetworkRequest)
+	}
+	for _, e := range events {
+		l.events = append(l.events, e)
+		l.correlate(e)
+	}
+	return err
+}
+
+// correlate adds the network event e to its request.
+func (l *Log) correlate(e Event) {
+	switch p := e.Params.(type) {
+	case *RequestWillBeSent:
+		if prev, ok := l.byID[p.RequestID]; ok && p.RedirectResponse != nil {
+			prev.Response = &ResponseReceived{
+				RequestID: p.RequestID,
+				LoaderID:  p.LoaderID,
+				Timestamp: p.Timestamp,
+				Type:      prev.Sent.Type,
+				Response:  *p.RedirectResponse,
+				FrameID:   p.FrameID,
+			}
+			prev.Redirected = true
+		}
+		r := &NetworkRequest{ID: p.RequestID, Sent: p}
+		l.requests = append(l.requests, r)
+		l.byID[p.RequestID] = r
+	case *ResponseReceived:
+		if r, ok := l.byID[p.RequestID]; ok {
+			r.Response = p
+		}
+	case *DataReceived:
+		if r, ok := l.byID[p.RequestID]; ok {
+			r.DataLength += p.DataLength
+		}
+	case *LoadingFinished:
+		if r, ok := l.byID[p.RequestID]; ok {
+			r.Finished = p
+		}
+	case *LoadingFailed:
+		if r, ok := l.byID[p.RequestID]; ok {
+			r.Failed = p
+		}
+	}
+}
+
+// Events returns the events added to the log, in order.
+func (l *Log) Events() []Event {
+	return append([]Event(nil), l.events...)
+}
+
+// Requests returns the requests of the network events added to the log, in
+// the order they were sent. The events of requests that were sent before
+// the first message added are ignored.
+func (l *Log) Requests() []*NetworkRequest {
+	return append([]*NetworkRequest(nil), l.requests...)
+}
+
+// Request returns the latest request with the given ID, or nil.
+func (l *Log) Request(id string) *NetworkRequest {
+	return l.byID[id]
+}
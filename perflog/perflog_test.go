@@ -0,0 +1,159 @@
+package perflog
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tebeka/selenium"
+	"github.com/tebeka/selenium/chrome"
+	"github.com/tebeka/selenium/log"
+)
+
+func message(t *testing.T, method string, params interface{}) log.Message {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{
+		"message": map[string]interface{}{"method": method, "params": params},
+		"webview": "w1",
+	})
+	if err != nil {
+		t.Fatalf("json.Marshal() returned error: %v", err)
+	}
+	return log.Message{Timestamp: time.Unix(100, 0), Level: log.Info, Message: string(data)}
+}
+
+func TestEnable(t *testing.T) {
+	caps := selenium.Capabilities{}
+	caps.AddChrome(chrome.Capabilities{Args: []string{"--headless"}})
+	enabled := false
+	Enable(caps, &chrome.PerfLoggingPreferences{EnablePage: &enabled})
+
+	c := caps[chrome.CapabilitiesKey].(chrome.Capabilities)
+	if diff := cmp.Diff([]string{"--headless"}, c.Args); diff != "" {
+		t.Errorf("Enable() changed the arguments (-want +got):\n%s", diff)
+	}
+	if c.PerfLoggingPrefs == nil || c.PerfLoggingPrefs.EnablePage != &enabled {
+		t.Errorf("Enable() set the preferences to %+v", c.PerfLoggingPrefs)
+	}
+	if got := caps[log.CapabilitiesKey].(log.Capabilities)[log.Performance]; got != log.All {
+		t.Errorf("Enable() set the performance log level to %q, want %q", got, log.All)
+	}
+
+	caps = selenium.Capabilities{}
+	Enable(caps, nil)
+	if c := caps[chrome.CapabilitiesKey].(chrome.Capabilities); !c.W3C || c.PerfLoggingPrefs == nil {
+		t.Errorf("Enable() set the Chrome capabilities %+v, want W3C mode and preferences", c)
+	}
+}
+
+func TestLog(t *testing.T) {
+	var l Log
+	err := l.Add([]log.Message{
+		message(t, EventRequestWillBeSent, map[string]interface{}{
+			"requestId": "1",
+			"loaderId":  "L1",
+			"request":   map[string]interface{}{"url": "http://example.com/old", "method": "GET", "headers": map[string]interface{}{"Accept": "*/*"}},
+			"timestamp": 10.0,
+			"wallTime":  1600000000.0,
+			"type":      "Document",
+		}),
+		message(t, EventRequestWillBeSent, map[string]interface{}{
+			"requestId":        "1",
+			"request":          map[string]interface{}{"url": "http://example.com/", "method": "GET"},
+			"timestamp":        10.1,
+			"redirectResponse": map[string]interface{}{"url": "http://example.com/old", "status": 301, "headers": map[string]interface{}{"Location": "/", "Content-Length": 0}},
+			"type":             "Document",
+		}),
+		message(t, EventRequestWillBeSent, map[string]interface{}{
+			"requestId": "2",
+			"request":   map[string]interface{}{"url": "http://example.com/missing.js", "method": "GET"},
+			"timestamp": 10.3,
+			"type":      "Script",
+		}),
+		message(t, EventResponseReceived, map[string]interface{}{
+			"requestId": "1",
+			"timestamp": 10.2,
+			"type":      "Document",
+			"response": map[string]interface{}{
+				"url":      "http://example.com/",
+				"status":   200,
+				"mimeType": "text/html",
+				"timing":   map[string]interface{}{"requestTime": 10.1, "dnsStart": -1, "sendStart": 1.5, "receiveHeadersEnd": 20},
+			},
+		}),
+	})
+	if err != nil {
+		t.Fatalf("Add() returned error: %v", err)
+	}
+	err = l.Add([]log.Message{
+		message(t, EventDataReceived, map[string]interface{}{"requestId": "1", "timestamp": 10.25, "dataLength": 100, "encodedDataLength": 60}),
+		message(t, EventLoadingFinished, map[string]interface{}{"requestId": "1", "timestamp": 10.3, "encodedDataLength": 300}),
+		message(t, EventLoadingFailed, map[string]interface{}{"requestId": "2", "timestamp": 10.4, "errorText": "net::ERR_FAILED"}),
+		message(t, EventLoadEventFired, map[string]interface{}{"timestamp": 10.5}),
+		message(t, "Tracing.dataCollected", map[string]interface{}{"value": []interface{}{}}),
+	})
+	if err != nil {
+		t.Fatalf("Add() returned error: %v", err)
+	}
+
+	events := l.Events()
+	if len(events) != 9 {
+		t.Fatalf("Events() returned %d events, want 9", len(events))
+	}
+	if e := events[7]; e.Method != EventLoadEventFired || e.WebView != "w1" || !e.Time.Equal(time.Unix(100, 0)) {
+		t.Errorf("Events()[7] = %+v", e)
+	} else if p, ok := e.Params.(*PageTimestamp); !ok || p.Timestamp != 10.5 {
+		t.Errorf("Events()[7].Params = %#v, want a *PageTimestamp", e.Params)
+	}
+	if _, ok := events[8].Params.(json.RawMessage); !ok {
+		t.Errorf("Events()[8].Params = %#v, want a json.RawMessage", events[8].Params)
+	}
+
+	requests := l.Requests()
+	if len(requests) != 3 {
+		t.Fatalf("Requests() returned %d requests, want 3", len(requests))
+	}
+	redirect, page, script := requests[0], requests[1], requests[2]
+	if !redirect.Redirected || redirect.Response == nil || redirect.Response.Response.Status != 301 ||
+		redirect.Response.Response.Headers["Content-Length"] != "0" || redirect.Finished != nil {
+		t.Errorf("the redirect is %+v", redirect)
+	}
+	if redirect.Sent.Request.Headers["Accept"] != "*/*" || redirect.Sent.WallTime != 1600000000 {
+		t.Errorf("the redirect was sent with %+v", redirect.Sent)
+	}
+	if page.Redirected || page.Response == nil || page.Response.Response.Status != 200 ||
+		page.Finished == nil || page.Finished.EncodedDataLength != 300 || page.DataLength != 100 {
+		t.Errorf("the page request is %+v", page)
+	}
+	if timing := page.Response.Response.Timing; timing == nil || timing.RequestTime != 10.1 || timing.DNSStart != -1 || timing.ReceiveHeadersEnd != 20 {
+		t.Errorf("the page response has timing %+v", timing)
+	}
+	if script.Failed == nil || script.Failed.ErrorText != "net::ERR_FAILED" || script.Response != nil {
+		t.Errorf("the script request is %+v", script)
+	}
+	if l.Request("1") != page {
+		t.Errorf("Request(%q) did not return the latest request with the ID", "1")
+	}
+}
+
+func TestParseError(t *testing.T) {
+	var l Log
+	err := l.Add([]log.Message{
+		message(t, EventLoadEventFired, map[string]interface{}{"timestamp": 1}),
+		{Message: "not JSON"},
+		message(t, EventRequestWillBeSent, map[string]interface{}{"requestId": 1}),
+	})
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("Add() of malformed messages returned error %v, want a *ParseError", err)
+	}
+	if len(pe.Errs) != 2 {
+		t.Errorf("Add() reported %d malformed messages, want 2: %v", len(pe.Errs), err)
+	}
+	events := l.Events()
+	if len(events) != 1 || events[0].Method != EventLoadEventFired {
+		t.Errorf("Add() of malformed messages added %+v, want only the %s event", events, EventLoadEventFired)
+	}
+}
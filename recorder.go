@@ -0,0 +1,304 @@
+package selenium
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"io"
+	"sync"
+	"time"
+)
+
+// RecordingFormat is the file format of a screencast.
+type RecordingFormat int
+
+const (
+	// RecordingGIF is an animated GIF.
+	RecordingGIF RecordingFormat = iota
+	// RecordingMJPEG is an AVI file of JPEG frames (Motion JPEG).
+	RecordingMJPEG
+)
+
+// DefaultRecordingInterval is the default time between the frames of a
+// screencast.
+const DefaultRecordingInterval = 500 * time.Millisecond
+
+// RecorderOptions configures a Recorder.
+type RecorderOptions struct {
+	// Format is the file format of the screencast.
+	Format RecordingFormat
+	// Interval is the time between frames. If zero, DefaultRecordingInterval
+	// is used.
+	Interval time.Duration
+	// PerCommand, if true, captures a frame after each command that may change
+	// the page, instead of at a fixed interval. It requires a WebDriver
+	// created by NewRemote.
+	PerCommand bool
+	// MaxDuration, if positive, stops capturing frames after this time.
+	MaxDuration time.Duration
+	// MaxDimension, if positive, scales the frames down so that neither their
+	// width nor their height exceeds it.
+	MaxDimension int
+	// JPEGQuality is the quality of the frames in the RecordingMJPEG format,
+	// from 1 to 100. If zero, jpeg.DefaultQuality is used.
+	JPEGQuality int
+}
+
+// recordedFrame is a captured frame: a paletted image for GIF screencasts,
+// or a JPEG image for MJPEG ones.
+type recordedFrame struct {
+	img  *image.Paletted
+	jpeg []byte
+	at   time.Time
+}
+
+// Recorder records a screencast of a session from screenshots of the browser
+// window. Frames are kept in memory and the screencast is written when
+// recording stops.
+type Recorder struct {
+	wd   WebDriver
+	w    io.Writer
+	opts RecorderOptions
+
+	mu        sync.Mutex
+	frames    []recordedFrame
+	size      image.Rectangle
+	capturing bool
+	lastErr   error
+	start     time.Time
+	started   bool
+	stopped   bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewRecorder returns a Recorder for the session of wd that writes the
+// screencast to w. opts may be nil.
+func NewRecorder(wd WebDriver, w io.Writer, opts *RecorderOptions) (*Recorder, error) {
+	r := &Recorder{wd: wd, w: w}
+	if opts != nil {
+		r.opts = *opts
+	}
+	if r.opts.Interval <= 0 {
+		r.opts.Interval = DefaultRecordingInterval
+	}
+	if r.opts.JPEGQuality <= 0 {
+		r.opts.JPEGQuality = jpeg.DefaultQuality
+	}
+	if r.opts.Format != RecordingGIF && r.opts.Format != RecordingMJPEG {
+		return nil, errors.New("selenium: unknown recording format")
+	}
+	if _, ok := wd.(*remoteWD); r.opts.PerCommand && !ok {
+		return nil, errors.New("selenium: per-command recording requires a WebDriver created by NewRemote")
+	}
+	return r, nil
+}
+
+// Start captures the first frame and starts recording.
+func (r *Recorder) Start() error {
+	r.mu.Lock()
+	if r.started {
+		r.mu.Unlock()
+		return errors.New("selenium: the recorder was already started")
+	}
+	r.started = true
+	r.start = time.Now()
+	r.mu.Unlock()
+
+	r.capture()
+	if r.opts.PerCommand {
+		r.wd.(*remoteWD).setCommandHook(r.onCommand)
+		return nil
+	}
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+	go r.run()
+	return nil
+}
+
+func (r *Recorder) run() {
+	defer close(r.done)
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			if r.expired() {
+				return
+			}
+			r.capture()
+		}
+	}
+}
+
+func (r *Recorder) expired() bool {
+	return r.opts.MaxDuration > 0 && time.Since(r.start) > r.opts.MaxDuration
+}
+
+// onCommand captures a frame after commands that may change the page.
+func (r *Recorder) onCommand(method, url string) {
+	if method != "POST" || r.expired() {
+		return
+	}
+	r.capture()
+}
+
+// screenshot takes a screenshot of the browser window, scaled down to the
+// maximum dimension. In per-command mode, the command is sent without
+// calling the command hook, which would capture a frame again.
+func (r *Recorder) screenshot() (image.Image, error) {
+	opts := &ScreenshotOptions{MaxDimension: r.opts.MaxDimension}
+	if !r.opts.PerCommand {
+		return ScreenshotImage(r.wd, opts)
+	}
+	wd := r.wd.(*remoteWD)
+	response, err := executeCommand("GET", wd.requestURL("/session/%s/screenshot", wd.id), nil)
+	if err != nil {
+		return nil, err
+	}
+	reply := new(struct{ Value string })
+	if err := json.Unmarshal(response, reply); err != nil {
+		return nil, err
+	}
+	buf, err := decodeBase64(reply.Value)
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+	return transformScreenshot(img, image.Rectangle{}, opts.MaxDimension)
+}
+
+// capture takes a screenshot and adds it to the frames. Errors are recorded
+// but do not stop the recording, since screenshots fail transiently, e.g.
+// while an alert is open.
+func (r *Recorder) capture() {
+	r.mu.Lock()
+	if r.capturing || r.stopped {
+		r.mu.Unlock()
+		return
+	}
+	// Frames are captured one at a time.
+	r.capturing = true
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.capturing = false
+		r.mu.Unlock()
+	}()
+
+	at := time.Now()
+	img, err := r.screenshot()
+	if err != nil {
+		r.mu.Lock()
+		r.lastErr = err
+		r.mu.Unlock()
+		return
+	}
+
+	r.mu.Lock()
+	if r.size.Empty() {
+		r.size = image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy())
+	}
+	size := r.size
+	r.mu.Unlock()
+
+	// All frames have the size of the first one.
+	f := recordedFrame{at: at}
+	switch r.opts.Format {
+	case RecordingGIF:
+		f.img = image.NewPaletted(size, palette.Plan9)
+		draw.FloydSteinberg.Draw(f.img, size, img, img.Bounds().Min)
+	case RecordingMJPEG:
+		canvas := image.NewRGBA(size)
+		draw.Draw(canvas, size, img, img.Bounds().Min, draw.Src)
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, canvas, &jpeg.Options{Quality: r.opts.JPEGQuality}); err != nil {
+			r.mu.Lock()
+			r.lastErr = err
+			r.mu.Unlock()
+			return
+		}
+		f.jpeg = buf.Bytes()
+	}
+
+	r.mu.Lock()
+	r.frames = append(r.frames, f)
+	r.mu.Unlock()
+}
+
+// Stop stops recording and writes the screencast.
+func (r *Recorder) Stop() error {
+	r.mu.Lock()
+	if !r.started || r.stopped {
+		r.mu.Unlock()
+		return errors.New("selenium: the recorder is not recording")
+	}
+	r.mu.Unlock()
+
+	if r.opts.PerCommand {
+		r.wd.(*remoteWD).setCommandHook(nil)
+	} else {
+		close(r.stop)
+		<-r.done
+	}
+
+	r.mu.Lock()
+	r.stopped = true
+	frames, lastErr := r.frames, r.lastErr
+	r.mu.Unlock()
+
+	if len(frames) == 0 {
+		if lastErr != nil {
+			return lastErr
+		}
+		return errors.New("selenium: no frames were recorded")
+	}
+	end := time.Now()
+	if r.opts.MaxDuration > 0 && end.Sub(r.start) > r.opts.MaxDuration {
+		end = r.start.Add(r.opts.MaxDuration)
+	}
+
+	switch r.opts.Format {
+	case RecordingGIF:
+		return writeGIF(r.w, frames, end)
+	default:
+		return writeMJPEG(r.w, frames, r.size, end)
+	}
+}
+
+// frameDuration returns the time frame i is shown.
+func frameDuration(frames []recordedFrame, i int, end time.Time) time.Duration {
+	next := end
+	if i+1 < len(frames) {
+		next = frames[i+1].at
+	}
+	if d := next.Sub(frames[i].at); d > 0 {
+		return d
+	}
+	return 0
+}
+
+func writeGIF(w io.Writer, frames []recordedFrame, end time.Time) error {
+	anim := &gif.GIF{}
+	for i, f := range frames {
+		// GIF delays are in hundredths of a second.
+		delay := int(frameDuration(frames, i, end) / (10 * time.Millisecond))
+		if delay < 1 {
+			delay = 1
+		}
+		anim.Image = append(anim.Image, f.img)
+		anim.Delay = append(anim.Delay, delay)
+	}
+	return gif.EncodeAll(w, anim)
+}
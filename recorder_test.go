@@ -0,0 +1,161 @@
+package selenium
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"testing"
+	"time"
+)
+
+func TestRecorderPerCommand(t *testing.T) {
+	d := newFakeDriver(t)
+	serveScreenshots(t, d)
+	wd := d.driver("")
+
+	var buf bytes.Buffer
+	r, err := NewRecorder(wd, &buf, &RecorderOptions{PerCommand: true, MaxDimension: 20})
+	if err != nil {
+		t.Fatalf("NewRecorder() returned error: %v", err)
+	}
+	if err := r.Stop(); err == nil {
+		t.Errorf("Stop() before Start() did not return an error")
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() returned error: %v", err)
+	}
+	// The commands fail, but the page is captured after each of them.
+	for i := 0; i < 2; i++ {
+		wd.voidCommand("/session/%s/url", map[string]string{"url": "about:blank"})
+	}
+	if _, err := wd.Title(); err == nil {
+		t.Errorf("wd.Title() did not return an error")
+	}
+	if err := r.Stop(); err != nil {
+		t.Fatalf("Stop() returned error: %v", err)
+	}
+	if wd.commandHook() != nil {
+		t.Errorf("the recorder is still called after Stop()")
+	}
+	// A screenshot is taken when starting and after each POST command.
+	screenshots := 0
+	for _, c := range d.received() {
+		if c == "GET /screenshot" {
+			screenshots++
+		}
+	}
+	if screenshots != 3 {
+		t.Errorf("the recorder took %d screenshots, want 3", screenshots)
+	}
+
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatalf("gif.DecodeAll() returned error: %v", err)
+	}
+	if len(anim.Image) != 3 {
+		t.Errorf("the screencast has %d frames, want 3", len(anim.Image))
+	}
+	if got, want := anim.Image[0].Bounds(), image.Rect(0, 0, 20, 10); got != want {
+		t.Errorf("the screencast frame bounds are %v, want %v", got, want)
+	}
+}
+
+func TestRecorderPerCommandConcurrent(t *testing.T) {
+	d := newFakeDriver(t)
+	serveScreenshots(t, d)
+	d.reply("POST", "/url", nil)
+	wd := d.driver("")
+
+	// The recorder is started and stopped while the session is in use.
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				wd.Get("about:blank")
+			}
+		}
+	}()
+	var buf bytes.Buffer
+	r, err := NewRecorder(wd, &buf, &RecorderOptions{PerCommand: true, MaxDimension: 20})
+	if err != nil {
+		t.Fatalf("NewRecorder() returned error: %v", err)
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() returned error: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if err := r.Stop(); err != nil {
+		t.Fatalf("Stop() returned error: %v", err)
+	}
+	close(stop)
+	<-done
+}
+
+func TestRecorderMJPEG(t *testing.T) {
+	d := newFakeDriver(t)
+	serveScreenshots(t, d)
+	wd := d.driver("")
+
+	var buf bytes.Buffer
+	r, err := NewRecorder(wd, &buf, &RecorderOptions{
+		Format:      RecordingMJPEG,
+		Interval:    10 * time.Millisecond,
+		MaxDuration: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewRecorder() returned error: %v", err)
+	}
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start() returned error: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := r.Stop(); err != nil {
+		t.Fatalf("Stop() returned error: %v", err)
+	}
+
+	avi := buf.Bytes()
+	if string(avi[:4]) != "RIFF" || string(avi[8:12]) != "AVI " {
+		t.Fatalf("the screencast does not start with an AVI header: %q", avi[:12])
+	}
+	if size := binary.LittleEndian.Uint32(avi[4:8]); int(size) != len(avi)-8 {
+		t.Errorf("RIFF size = %d, want %d", size, len(avi)-8)
+	}
+	// The main header follows "LIST", its size, "hdrl", "avih" and its size.
+	header := avi[12+20:]
+	frames := binary.LittleEndian.Uint32(header[16:20])
+	if frames < 1 || frames > 7 {
+		t.Errorf("the screencast has %d frames, want between 1 and 7", frames)
+	}
+	if w, h := binary.LittleEndian.Uint32(header[32:36]), binary.LittleEndian.Uint32(header[36:40]); w != 40 || h != 20 {
+		t.Errorf("the screencast size is %dx%d, want 40x20", w, h)
+	}
+
+	i := bytes.Index(avi, []byte("00dc"))
+	if i < 0 {
+		t.Fatalf("the screencast has no frame chunks")
+	}
+	size := binary.LittleEndian.Uint32(avi[i+4 : i+8])
+	if _, err := jpeg.Decode(bytes.NewReader(avi[i+8 : i+8+int(size)])); err != nil {
+		t.Errorf("decoding the first frame returned error: %v", err)
+	}
+	if n := bytes.Count(avi[bytes.Index(avi, []byte("idx1")):], []byte("00dc")); uint32(n) != frames {
+		t.Errorf("the index has %d entries, want %d", n, frames)
+	}
+}
+
+func TestNewRecorderErrors(t *testing.T) {
+	type otherDriver struct{ WebDriver }
+	if _, err := NewRecorder(otherDriver{}, nil, &RecorderOptions{PerCommand: true}); err == nil {
+		t.Errorf("NewRecorder() with PerCommand and a custom WebDriver did not return an error")
+	}
+	if _, err := NewRecorder(otherDriver{}, nil, &RecorderOptions{Format: 5}); err == nil {
+		t.Errorf("NewRecorder() with an unknown format did not return an error")
+	}
+}
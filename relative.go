@@ -0,0 +1,172 @@
+package selenium
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultNearDistance is the distance, in CSS pixels, used by
+// RelativeLocator.Near when no distance is given.
+const DefaultNearDistance = 50
+
+// RelativeLocator finds elements by their position on the page relative to
+// other elements, the anchors. For example, the following finds the input
+// element directly below a label:
+//
+//	label, err := selenium.FindElementBy(wd, selenium.By.ID("username-label"))
+//	...
+//	input, err := selenium.Relative(selenium.By.TagName("input")).Below(label).FindElement(wd)
+//
+// The positions are evaluated in the browser using the elements' bounding
+// client rectangles. Matching elements are ordered by the distance between
+// their center and the center of the closest anchor.
+type RelativeLocator struct {
+	base    Locator
+	filters []relativeFilter
+}
+
+// relativeFilter is a spatial constraint. It is passed as an argument to
+// relativeScript.
+type relativeFilter struct {
+	Kind     string     `json:"kind"`
+	Anchor   WebElement `json:"anchor"`
+	Distance int        `json:"distance,omitempty"`
+}
+
+// Relative returns a RelativeLocator that finds the elements matching base
+// that satisfy the constraints added by its methods.
+func Relative(base Locator) RelativeLocator {
+	return RelativeLocator{base: base}
+}
+
+func (r RelativeLocator) with(f relativeFilter) RelativeLocator {
+	filters := make([]relativeFilter, len(r.filters), len(r.filters)+1)
+	copy(filters, r.filters)
+	r.filters = append(filters, f)
+	return r
+}
+
+// Above restricts the locator to elements whose bottom edge is above the top
+// edge of anchor.
+func (r RelativeLocator) Above(anchor WebElement) RelativeLocator {
+	return r.with(relativeFilter{Kind: "above", Anchor: anchor})
+}
+
+// Below restricts the locator to elements whose top edge is below the bottom
+// edge of anchor.
+func (r RelativeLocator) Below(anchor WebElement) RelativeLocator {
+	return r.with(relativeFilter{Kind: "below", Anchor: anchor})
+}
+
+// ToLeftOf restricts the locator to elements whose right edge is left of the
+// left edge of anchor.
+func (r RelativeLocator) ToLeftOf(anchor WebElement) RelativeLocator {
+	return r.with(relativeFilter{Kind: "left", Anchor: anchor})
+}
+
+// ToRightOf restricts the locator to elements whose left edge is right of the
+// right edge of anchor.
+func (r RelativeLocator) ToRightOf(anchor WebElement) RelativeLocator {
+	return r.with(relativeFilter{Kind: "right", Anchor: anchor})
+}
+
+// Near restricts the locator to elements that are at most distance CSS pixels
+// away from anchor, measured between the closest edges of the elements. If
+// distance is not positive, DefaultNearDistance is used.
+func (r RelativeLocator) Near(anchor WebElement, distance int) RelativeLocator {
+	if distance <= 0 {
+		distance = DefaultNearDistance
+	}
+	return r.with(relativeFilter{Kind: "near", Anchor: anchor, Distance: distance})
+}
+
+// String returns a description of the locator, for use in diagnostics.
+func (r RelativeLocator) String() string {
+	parts := []string{r.base.String()}
+	for _, f := range r.filters {
+		if f.Kind == "near" {
+			parts = append(parts, fmt.Sprintf("near(%d)", f.Distance))
+			continue
+		}
+		parts = append(parts, f.Kind)
+	}
+	return strings.Join(parts, " ")
+}
+
+// relativeScript filters the candidate elements, passed as the first
+// argument, by the constraints passed as the second argument, and returns
+// the matching elements ordered by proximity to the anchors.
+const relativeScript = `
+var candidates = arguments[0], filters = arguments[1];
+function rect(e) {
+	var r = e.getBoundingClientRect();
+	return {left: r.left, top: r.top, right: r.right, bottom: r.bottom};
+}
+function center(r) {
+	return {x: (r.left + r.right) / 2, y: (r.top + r.bottom) / 2};
+}
+function gap(a, b) {
+	var dx = Math.max(0, b.left - a.right, a.left - b.right);
+	var dy = Math.max(0, b.top - a.bottom, a.top - b.bottom);
+	return Math.sqrt(dx * dx + dy * dy);
+}
+var checks = {
+	above: function(c, a) { return c.bottom <= a.top; },
+	below: function(c, a) { return c.top >= a.bottom; },
+	left: function(c, a) { return c.right <= a.left; },
+	right: function(c, a) { return c.left >= a.right; },
+	near: function(c, a, d) { return gap(c, a) <= d; }
+};
+var anchors = filters.map(function(f) { return rect(f.anchor); });
+var matches = [];
+candidates.forEach(function(e) {
+	var r = rect(e);
+	for (var i = 0; i < filters.length; i++) {
+		var f = filters[i];
+		if (e === f.anchor || !checks[f.kind](r, anchors[i], f.distance)) {
+			return;
+		}
+	}
+	var c = center(r), distance = Infinity;
+	anchors.forEach(function(a) {
+		var ac = center(a);
+		distance = Math.min(distance, Math.sqrt(Math.pow(c.x - ac.x, 2) + Math.pow(c.y - ac.y, 2)));
+	});
+	matches.push({element: e, distance: distance});
+});
+matches.sort(function(a, b) { return a.distance - b.distance; });
+return matches.map(function(m) { return m.element; });
+`
+
+// FindElements returns all elements found by the locator, ordered by their
+// proximity to the anchors.
+func (r RelativeLocator) FindElements(wd WebDriver) ([]WebElement, error) {
+	candidates, err := FindElementsBy(wd, r.base)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	response, err := wd.ExecuteScriptRaw(relativeScript, []interface{}{candidates, r.filters})
+	if err != nil {
+		return nil, err
+	}
+	return wd.DecodeElements(response)
+}
+
+// FindElement returns the element found by the locator that is closest to
+// the anchors.
+func (r RelativeLocator) FindElement(wd WebDriver) (WebElement, error) {
+	elems, err := r.FindElements(wd)
+	if err != nil {
+		return nil, err
+	}
+	if len(elems) == 0 {
+		return nil, &Error{
+			Err:     CodeNoSuchElement,
+			Message: fmt.Sprintf("no element found by %s", r),
+		}
+	}
+	return elems[0], nil
+}
@@ -0,0 +1,90 @@
+package selenium
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// relativeDriver is a WebDriver that returns fixed candidates for every
+// locator and records the arguments of the relative locator script.
+type relativeDriver struct {
+	WebDriver
+
+	candidates []WebElement
+	args       []interface{}
+	result     []WebElement
+}
+
+func (wd *relativeDriver) FindElementsBy(l Locator) ([]WebElement, error) {
+	return wd.candidates, nil
+}
+
+func (wd *relativeDriver) ExecuteScriptRaw(script string, args []interface{}) ([]byte, error) {
+	wd.args = args
+	return []byte(`{"value": []}`), nil
+}
+
+func (wd *relativeDriver) DecodeElements(data []byte) ([]WebElement, error) {
+	return wd.result, nil
+}
+
+func TestRelativeLocator(t *testing.T) {
+	label := &fakeNode{name: "label"}
+	first := &fakeNode{name: "first"}
+	second := &fakeNode{name: "second"}
+	wd := &relativeDriver{
+		candidates: []WebElement{second, first},
+		result:     []WebElement{first, second},
+	}
+
+	base := Relative(By.TagName("input"))
+	l := base.Below(label).Near(label, 0)
+	if len(base.filters) != 0 {
+		t.Fatalf("adding constraints modified the base locator: %+v", base.filters)
+	}
+	if got, want := l.String(), `tag name="input" below near(50)`; got != want {
+		t.Errorf("String() = %s, want %s", got, want)
+	}
+
+	elem, err := l.FindElement(wd)
+	if err != nil {
+		t.Fatalf("FindElement() returned error: %v", err)
+	}
+	if elem != first {
+		t.Errorf("FindElement() = %v, want %v", elem, first)
+	}
+	if len(wd.args) != 2 || !reflect.DeepEqual(wd.args[0], wd.candidates) {
+		t.Fatalf("script arguments = %v, want the candidates and filters", wd.args)
+	}
+	want := []relativeFilter{
+		{Kind: "below", Anchor: label},
+		{Kind: "near", Anchor: label, Distance: DefaultNearDistance},
+	}
+	if !reflect.DeepEqual(wd.args[1], want) {
+		t.Errorf("script filters = %+v, want %+v", wd.args[1], want)
+	}
+
+	wd.result = nil
+	if _, err := l.FindElement(wd); !HasErrorCode(err, CodeNoSuchElement) {
+		t.Errorf("FindElement() with no matches returned error %v, want %q", err, CodeNoSuchElement)
+	}
+}
+
+func TestRelativeFilterJSON(t *testing.T) {
+	f := relativeFilter{Kind: "left", Anchor: &remoteWE{id: "1"}}
+	data, err := json.Marshal(f)
+	if err != nil {
+		t.Fatalf("json.Marshal() returned error: %v", err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("json.Unmarshal(%s) returned error: %v", data, err)
+	}
+	if got["kind"] != "left" || got["anchor"] == nil {
+		t.Errorf("json.Marshal(%+v) = %s, want kind and anchor", f, data)
+	}
+	if _, ok := got["distance"]; ok {
+		t.Errorf("json.Marshal(%+v) = %s, want no distance", f, data)
+	}
+}
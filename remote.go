@@ -15,6 +15,7 @@ import (
 	"net/url"
 	"path"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/blang/semver"
@@ -54,6 +55,30 @@ type remoteWD struct {
 	storedActions  Actions
 	browser        string
 	browserVersion semver.Version
+	// webSocketURL is the URL of the WebDriver BiDi connection returned by
+	// the server.
+	webSocketURL string
+	// healing is set by the SelfHealing option. healHook, if not nil, is
+	// called after each attempt to re-locate a stale element.
+	healing  bool
+	healHook func(HealEvent)
+	// autoWait is the budget set by the AutoWait option.
+	autoWait time.Duration
+	// retryIntercepted is set by the RetryInterceptedClicks option.
+	retryIntercepted bool
+	// onCommand, if not nil, is called after each command sent to the
+	// server. It is guarded by hookMu, since it is set while the session may
+	// be in use.
+	hookMu    sync.Mutex
+	onCommand func(method, url string)
+	// networkConditions are the conditions emulated through the Chrome
+	// DevTools Protocol, which cannot be read back from the browser.
+	networkConditions *NetworkConditions
+	// geolocation, if not nil, is the position injected in each document
+	// loaded by Get, Refresh, Back and Forward, on browsers that cannot
+	// emulate one. geolocationErr is the error of the latest injection.
+	geolocation    *geolocation
+	geolocationErr error
 }
 
 // HTTPClient is the default client to use to communicate with the WebDriver
@@ -121,11 +146,67 @@ func (e *Error) Error() string {
 	return fmt.Sprintf("%s: %s", e.Err, e.Message)
 }
 
+// Error codes, as found in the Err field of Error, for failures that clients
+// commonly need to handle. See https://www.w3.org/TR/webdriver/#handling-errors
+// for the complete list.
+const (
+	CodeElementClickIntercepted = "element click intercepted"
+	CodeElementNotInteractable  = "element not interactable"
+	CodeInvalidElementState     = "invalid element state"
+	CodeNoSuchAlert             = "no such alert"
+	CodeNoSuchElement           = "no such element"
+	CodeNoSuchFrame             = "no such frame"
+	CodeNoSuchWindow            = "no such window"
+	CodeStaleElementReference   = "stale element reference"
+	CodeTimeout                 = "timeout"
+	CodeUnknownCommand          = "unknown command"
+	CodeUnsupportedOperation    = "unsupported operation"
+)
+
+// HasErrorCode returns true if err was returned by the remote end with one of
+// the given error codes, possibly wrapped. Errors from servers that implement
+// the W3C specification and from legacy servers are both handled.
+func HasErrorCode(err error, codes ...string) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	var e *Error
+	if errors.As(err, &e) {
+		msg = e.Err
+	}
+	for _, code := range codes {
+		if msg == code {
+			return true
+		}
+	}
+	return false
+}
+
 // execute performs an HTTP request and inspects the returned data for an error
 // encoded by the remote end in a JSON structure. If no error is present, the
 // entire, raw request payload is returned.
 func (wd *remoteWD) execute(method, url string, data []byte) (json.RawMessage, error) {
-	return executeCommand(method, url, data)
+	buf, err := executeCommand(method, url, data)
+	if hook := wd.commandHook(); hook != nil {
+		hook(method, url)
+	}
+	return buf, err
+}
+
+// setCommandHook sets the function called after each command, or removes it
+// if hook is nil.
+func (wd *remoteWD) setCommandHook(hook func(method, url string)) {
+	wd.hookMu.Lock()
+	defer wd.hookMu.Unlock()
+	wd.onCommand = hook
+}
+
+// commandHook returns the function called after each command, or nil.
+func (wd *remoteWD) commandHook() func(method, url string) {
+	wd.hookMu.Lock()
+	defer wd.hookMu.Unlock()
+	return wd.onCommand
 }
 
 func executeCommand(method, url string, data []byte) (json.RawMessage, error) {
@@ -213,13 +294,16 @@ func executeCommand(method, url string, data []byte) (json.RawMessage, error) {
 // DefaultURLPrefix is the default HTTP endpoint that offers the WebDriver API.
 const DefaultURLPrefix = "http://127.0.0.1:4444/wd/hub"
 
+// RemoteOption configures the client created by NewRemote.
+type RemoteOption func(*remoteWD) error
+
 // NewRemote creates new remote client, this will also start a new session.
 // capabilities provides the desired capabilities. urlPrefix is the URL to the
 // Selenium server, must be prefixed with protocol (http, https, ...).
 //
 // Providing an empty string for urlPrefix causes the DefaultURLPrefix to be
 // used.
-func NewRemote(capabilities Capabilities, urlPrefix string) (WebDriver, error) {
+func NewRemote(capabilities Capabilities, urlPrefix string, opts ...RemoteOption) (WebDriver, error) {
 	if urlPrefix == "" {
 		urlPrefix = DefaultURLPrefix
 	}
@@ -231,6 +315,11 @@ func NewRemote(capabilities Capabilities, urlPrefix string) (WebDriver, error) {
 	if b := capabilities["browserName"]; b != nil {
 		wd.browser = b.(string)
 	}
+	for _, opt := range opts {
+		if err := opt(wd); err != nil {
+			return nil, err
+		}
+	}
 
 	if _, err := wd.NewSession(); err != nil {
 		return nil, err
@@ -268,11 +357,15 @@ func (wd *remoteWD) stringCommand(urlTemplate string) (string, error) {
 	return *reply.Value, nil
 }
 
-func voidCommand(method, url string, params interface{}) error {
+func marshalParams(params interface{}) ([]byte, error) {
 	if params == nil {
 		params = make(map[string]interface{})
 	}
-	data, err := json.Marshal(params)
+	return json.Marshal(params)
+}
+
+func voidCommand(method, url string, params interface{}) error {
+	data, err := marshalParams(params)
 	if err != nil {
 		return err
 	}
@@ -281,10 +374,15 @@ func voidCommand(method, url string, params interface{}) error {
 }
 
 func (wd *remoteWD) voidCommand(urlTemplate string, params interface{}) error {
-	return voidCommand("POST", wd.requestURL(urlTemplate, wd.id), params)
+	data, err := marshalParams(params)
+	if err != nil {
+		return err
+	}
+	_, err = wd.execute("POST", wd.requestURL(urlTemplate, wd.id), data)
+	return err
 }
 
-func (wd remoteWD) stringsCommand(urlTemplate string) ([]string, error) {
+func (wd *remoteWD) stringsCommand(urlTemplate string) ([]string, error) {
 	url := wd.requestURL(urlTemplate, wd.id)
 	response, err := wd.execute("GET", url, nil)
 	if err != nil {
@@ -359,6 +457,7 @@ var w3cCapabilityNames = []string{
 	"setWindowRect",
 	"timeouts",
 	"unhandledPromptBehavior",
+	"webSocketUrl",
 }
 
 var chromeCapabilityNames = []string{
@@ -474,6 +573,9 @@ func (wd *remoteWD) NewSession() (string, error) {
 					PageLoad       float32
 					Script         float32
 				}
+				// WebSocketURL is returned if the webSocketUrl capability was
+				// requested.
+				WebSocketURL string `json:"webSocketUrl"`
 			}
 
 			value := struct {
@@ -502,6 +604,8 @@ func (wd *remoteWD) NewSession() (string, error) {
 				caps = value.returnedCapabilities
 			}
 
+			wd.webSocketURL = caps.WebSocketURL
+
 			for _, s := range []string{caps.Version, caps.BrowserVersion} {
 				if s == "" {
 					continue
@@ -520,6 +624,28 @@ func (wd *remoteWD) NewSession() (string, error) {
 	panic("unreachable")
 }
 
+// BiDiSession reports the URL of the WebDriver BiDi connection of a session.
+type BiDiSession interface {
+	// WebSocketURL returns the URL of the WebDriver BiDi connection of the
+	// session, or an empty string if the webSocketUrl capability was not set
+	// to true when creating the session.
+	WebSocketURL() string
+}
+
+// WebSocketURL returns the URL of the WebDriver BiDi connection of the
+// session of wd, or an empty string if it has none or if wd does not
+// implement BiDiSession.
+func WebSocketURL(wd WebDriver) string {
+	if s, ok := wd.(BiDiSession); ok {
+		return s.WebSocketURL()
+	}
+	return ""
+}
+
+func (wd *remoteWD) WebSocketURL() string {
+	return wd.webSocketURL
+}
+
 // SessionId returns the current session ID
 //
 // Deprecated: This identifier is not Go-style correct. Use SessionID instead.
@@ -634,20 +760,35 @@ func (wd *remoteWD) Get(url string) error {
 	if err != nil {
 		return err
 	}
-	_, err = wd.execute("POST", requestURL, data)
-	return err
+	if _, err = wd.execute("POST", requestURL, data); err != nil {
+		return err
+	}
+	wd.injectGeolocation()
+	return nil
 }
 
 func (wd *remoteWD) Forward() error {
-	return wd.voidCommand("/session/%s/forward", nil)
+	if err := wd.voidCommand("/session/%s/forward", nil); err != nil {
+		return err
+	}
+	wd.injectGeolocation()
+	return nil
 }
 
 func (wd *remoteWD) Back() error {
-	return wd.voidCommand("/session/%s/back", nil)
+	if err := wd.voidCommand("/session/%s/back", nil); err != nil {
+		return err
+	}
+	wd.injectGeolocation()
+	return nil
 }
 
 func (wd *remoteWD) Refresh() error {
-	return wd.voidCommand("/session/%s/refresh", nil)
+	if err := wd.voidCommand("/session/%s/refresh", nil); err != nil {
+		return err
+	}
+	wd.injectGeolocation()
+	return nil
 }
 
 func (wd *remoteWD) Title() (string, error) {
@@ -753,7 +894,12 @@ func (wd *remoteWD) FindElement(by, value string) (WebElement, error) {
 	if err != nil {
 		return nil, err
 	}
-	return wd.DecodeElement(response)
+	elem, err := wd.DecodeElement(response)
+	if err != nil {
+		return nil, err
+	}
+	wd.track(elem, nil, by, value, -1)
+	return elem, nil
 }
 
 func (wd *remoteWD) FindElements(by, value string) ([]WebElement, error) {
@@ -762,7 +908,22 @@ func (wd *remoteWD) FindElements(by, value string) ([]WebElement, error) {
 		return nil, err
 	}
 
-	return wd.DecodeElements(response)
+	elems, err := wd.DecodeElements(response)
+	if err != nil {
+		return nil, err
+	}
+	for i, elem := range elems {
+		wd.track(elem, nil, by, value, i)
+	}
+	return elems, nil
+}
+
+func (wd *remoteWD) FindElementBy(l Locator) (WebElement, error) {
+	return findElementBy(wd, l)
+}
+
+func (wd *remoteWD) FindElementsBy(l Locator) ([]WebElement, error) {
+	return findElementsBy(wd, l)
 }
 
 func (wd *remoteWD) Close() error {
@@ -914,7 +1075,7 @@ type cookie struct {
 	Secure   bool        `json:"secure"`
 	Expiry   interface{} `json:"expiry"`
 	HTTPOnly bool        `json:"httpOnly"`
-	SameSite string      `json:"sameSite",omitempty`
+	SameSite string      `json:"sameSite,omitempty"`
 }
 
 func (c cookie) sanitize() Cookie {
@@ -1296,22 +1457,7 @@ const (
 )
 
 func (wd *remoteWD) WaitWithTimeoutAndInterval(condition Condition, timeout, interval time.Duration) error {
-	startTime := time.Now()
-
-	for {
-		done, err := condition(wd)
-		if err != nil {
-			return err
-		}
-		if done {
-			return nil
-		}
-
-		if elapsed := time.Since(startTime); elapsed > timeout {
-			return fmt.Errorf("timeout after %v", elapsed)
-		}
-		time.Sleep(interval)
-	}
+	return NewWaiter(WaitTimeout(timeout), WaitInterval(interval)).Until(wd, condition)
 }
 
 func (wd *remoteWD) WaitWithTimeout(condition Condition, timeout time.Duration) error {
@@ -1368,17 +1514,38 @@ type remoteWE struct {
 	// specifies that this key has changed to an UUID-based string constant and
 	// that the value is called a "reference". For ease of transition, we store
 	// the "reference" in this now misnamed field.
-	id string
+	//
+	// The ID changes when a stale element is healed, so it must be read with
+	// ref while commands may run concurrently.
+	idMu sync.Mutex
+	id   string
+	// origin records how the element was found, if self-healing is enabled.
+	origin *elementOrigin
+}
+
+// ref returns the ID of the element.
+func (elem *remoteWE) ref() string {
+	elem.idMu.Lock()
+	defer elem.idMu.Unlock()
+	return elem.id
 }
 
 func (elem *remoteWE) Click() error {
-	urlTemplate := fmt.Sprintf("/session/%%s/element/%s/click", elem.id)
-	return elem.parent.voidCommand(urlTemplate, nil)
+	return elem.do(func() error {
+		if err := elem.waitActionable(); err != nil {
+			return err
+		}
+		return elem.click()
+	})
 }
 
 func (elem *remoteWE) SendKeys(keys string) error {
-	urlTemplate := fmt.Sprintf("/session/%%s/element/%s/value", elem.id)
-	return elem.parent.voidCommand(urlTemplate, elem.parent.processKeyString(keys))
+	return elem.do(func() error {
+		if err := elem.waitActionable(); err != nil {
+			return err
+		}
+		return elem.voidCommand("/session/%%s/element/%s/value", elem.parent.processKeyString(keys))
+	})
 }
 
 func (wd *remoteWD) processKeyString(keys string) interface{} {
@@ -1392,56 +1559,106 @@ func (wd *remoteWD) processKeyString(keys string) interface{} {
 	return map[string]string{"text": keys}
 }
 
+// voidCommand sends a command for the element. urlTemplate must contain an
+// escaped verb for the session ID followed by a verb for the element ID.
+func (elem *remoteWE) voidCommand(urlTemplate string, params interface{}) error {
+	return elem.parent.voidCommand(fmt.Sprintf(urlTemplate, elem.ref()), params)
+}
+
+// stringCommand is like voidCommand, but returns a string result. Any args
+// are formatted into urlTemplate after the element ID.
+func (elem *remoteWE) stringCommand(urlTemplate string, args ...interface{}) (string, error) {
+	return elem.parent.stringCommand(fmt.Sprintf(urlTemplate, append([]interface{}{elem.ref()}, args...)...))
+}
+
 func (elem *remoteWE) TagName() (string, error) {
-	urlTemplate := fmt.Sprintf("/session/%%s/element/%s/name", elem.id)
-	return elem.parent.stringCommand(urlTemplate)
+	return elem.stringCommand("/session/%%s/element/%s/name")
 }
 
 func (elem *remoteWE) Text() (string, error) {
-	urlTemplate := fmt.Sprintf("/session/%%s/element/%s/text", elem.id)
-	return elem.parent.stringCommand(urlTemplate)
+	return elem.doString(func() (string, error) {
+		return elem.stringCommand("/session/%%s/element/%s/text")
+	})
 }
 
 func (elem *remoteWE) Submit() error {
-	urlTemplate := fmt.Sprintf("/session/%%s/element/%s/submit", elem.id)
-	return elem.parent.voidCommand(urlTemplate, nil)
+	return elem.do(func() error {
+		if err := elem.waitActionable(); err != nil {
+			return err
+		}
+		return elem.voidCommand("/session/%%s/element/%s/submit", nil)
+	})
 }
 
 func (elem *remoteWE) Clear() error {
-	urlTemplate := fmt.Sprintf("/session/%%s/element/%s/clear", elem.id)
-	return elem.parent.voidCommand(urlTemplate, nil)
+	return elem.do(func() error {
+		if err := elem.waitActionable(); err != nil {
+			return err
+		}
+		return elem.voidCommand("/session/%%s/element/%s/clear", nil)
+	})
 }
 
 func (elem *remoteWE) MoveTo(xOffset, yOffset int) error {
 	return elem.parent.voidCommand("/session/%s/moveto", map[string]interface{}{
-		"element": elem.id,
+		"element": elem.ref(),
 		"xoffset": xOffset,
 		"yoffset": yOffset,
 	})
 }
 
 func (elem *remoteWE) FindElement(by, value string) (WebElement, error) {
-	url := fmt.Sprintf("/session/%%s/element/%s/element", elem.id)
-	response, err := elem.parent.find(by, value, "", url)
+	var response []byte
+	err := elem.do(func() error {
+		url := fmt.Sprintf("/session/%%s/element/%s/element", elem.ref())
+		var err error
+		response, err = elem.parent.find(by, value, "", url)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return elem.parent.DecodeElement(response)
+	found, err := elem.parent.DecodeElement(response)
+	if err != nil {
+		return nil, err
+	}
+	elem.parent.track(found, elem, by, value, -1)
+	return found, nil
 }
 
 func (elem *remoteWE) FindElements(by, value string) ([]WebElement, error) {
-	url := fmt.Sprintf("/session/%%s/element/%s/element", elem.id)
-	response, err := elem.parent.find(by, value, "s", url)
+	var response []byte
+	err := elem.do(func() error {
+		url := fmt.Sprintf("/session/%%s/element/%s/element", elem.ref())
+		var err error
+		response, err = elem.parent.find(by, value, "s", url)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return elem.parent.DecodeElements(response)
+	found, err := elem.parent.DecodeElements(response)
+	if err != nil {
+		return nil, err
+	}
+	for i, f := range found {
+		elem.parent.track(f, elem, by, value, i)
+	}
+	return found, nil
+}
+
+func (elem *remoteWE) FindElementBy(l Locator) (WebElement, error) {
+	return findElementBy(elem, l)
+}
+
+func (elem *remoteWE) FindElementsBy(l Locator) ([]WebElement, error) {
+	return findElementsBy(elem, l)
 }
 
 func (elem *remoteWE) boolQuery(urlTemplate string) (bool, error) {
-	return elem.parent.boolCommand(fmt.Sprintf(urlTemplate, elem.id))
+	return elem.parent.boolCommand(fmt.Sprintf(urlTemplate, elem.ref()))
 }
 
 func (elem *remoteWE) IsSelected() (bool, error) {
@@ -1457,17 +1674,15 @@ func (elem *remoteWE) IsDisplayed() (bool, error) {
 }
 
 func (elem *remoteWE) GetProperty(name string) (string, error) {
-	template := "/session/%%s/element/%s/property/%s"
-	urlTemplate := fmt.Sprintf(template, elem.id, name)
-
-	return elem.parent.stringCommand(urlTemplate)
+	return elem.doString(func() (string, error) {
+		return elem.stringCommand("/session/%%s/element/%s/property/%s", name)
+	})
 }
 
 func (elem *remoteWE) GetAttribute(name string) (string, error) {
-	template := "/session/%%s/element/%s/attribute/%s"
-	urlTemplate := fmt.Sprintf(template, elem.id, name)
-
-	return elem.parent.stringCommand(urlTemplate)
+	return elem.doString(func() (string, error) {
+		return elem.stringCommand("/session/%%s/element/%s/attribute/%s", name)
+	})
 }
 
 func round(f float64) int {
@@ -1484,7 +1699,7 @@ func (elem *remoteWE) location(suffix string) (*Point, error) {
 	if !elem.parent.w3cCompatible {
 		wd := elem.parent
 		path := "/session/%s/element/%s/location" + suffix
-		url := wd.requestURL(path, wd.id, elem.id)
+		url := wd.requestURL(path, wd.id, elem.ref())
 		response, err := wd.execute("GET", url, nil)
 		if err != nil {
 			return nil, err
@@ -1514,7 +1729,7 @@ func (elem *remoteWE) LocationInView() (*Point, error) {
 func (elem *remoteWE) Size() (*Size, error) {
 	if !elem.parent.w3cCompatible {
 		wd := elem.parent
-		url := wd.requestURL("/session/%s/element/%s/size", wd.id, elem.id)
+		url := wd.requestURL("/session/%s/element/%s/size", wd.id, elem.ref())
 		response, err := wd.execute("GET", url, nil)
 		if err != nil {
 			return nil, err
@@ -1544,7 +1759,7 @@ type rect struct {
 // rect implements the "Get Element Rect" method of the W3C standard.
 func (elem *remoteWE) rect() (*rect, error) {
 	wd := elem.parent
-	url := wd.requestURL("/session/%s/element/%s/rect", wd.id, elem.id)
+	url := wd.requestURL("/session/%s/element/%s/rect", wd.id, elem.ref())
 	response, err := wd.execute("GET", url, nil)
 	if err != nil {
 		return nil, err
@@ -1557,19 +1772,22 @@ func (elem *remoteWE) rect() (*rect, error) {
 }
 
 func (elem *remoteWE) CSSProperty(name string) (string, error) {
-	wd := elem.parent
-	return wd.stringCommand(fmt.Sprintf("/session/%%s/element/%s/css/%s", elem.id, name))
+	return elem.stringCommand("/session/%%s/element/%s/css/%s", name)
 }
 
 func (elem *remoteWE) MarshalJSON() ([]byte, error) {
+	id := elem.ref()
 	return json.Marshal(map[string]string{
-		"ELEMENT":            elem.id,
-		webElementIdentifier: elem.id,
+		"ELEMENT":            id,
+		webElementIdentifier: id,
 	})
 }
 
 func (elem *remoteWE) Screenshot(scroll bool) ([]byte, error) {
-	data, err := elem.parent.stringCommand(fmt.Sprintf("/session/%%s/element/%s/screenshot", elem.id))
+	if !scroll {
+		return elem.screenshotInViewport()
+	}
+	data, err := elem.stringCommand("/session/%%s/element/%s/screenshot")
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,229 @@
+package selenium
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+// ScreenshotOptions configures the image returned by ScreenshotImage and its
+// encoding by EncodeScreenshot. The zero value returns the screenshot as is.
+type ScreenshotOptions struct {
+	// Element, if set, crops a screenshot of the browser window to the part of
+	// the viewport covered by the element. The page is not scrolled. It is
+	// ignored for element screenshots.
+	Element WebElement
+	// Region, if not empty, crops the screenshot to a rectangle, in pixels of
+	// the screenshot. If Element is also set, the screenshot is cropped to the
+	// intersection of both.
+	Region image.Rectangle
+	// MaxDimension, if positive, is the maximum width and height of the
+	// image. Larger screenshots are scaled down, preserving their aspect
+	// ratio.
+	MaxDimension int
+	// JPEGQuality, if positive, makes EncodeScreenshot encode the image as
+	// JPEG with this quality, from 1 to 100. Otherwise, the image is encoded
+	// as PNG.
+	JPEGQuality int
+}
+
+// EncodeScreenshot writes img to w as PNG, or as JPEG if opts.JPEGQuality is
+// positive. opts may be nil.
+func EncodeScreenshot(w io.Writer, img image.Image, opts *ScreenshotOptions) error {
+	if opts != nil && opts.JPEGQuality > 0 {
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: opts.JPEGQuality})
+	}
+	return png.Encode(w, img)
+}
+
+// ImageScreenshotter returns screenshots as images.
+type ImageScreenshotter interface {
+	// ScreenshotImage takes a screenshot and returns it as an image, cropped
+	// and scaled as specified by opts, which may be nil.
+	ScreenshotImage(opts *ScreenshotOptions) (image.Image, error)
+}
+
+// ScreenshotImage takes a screenshot of the browser window and returns it as
+// an image, cropped and scaled as specified by opts, which may be nil. It
+// calls the ScreenshotImage method of wd if wd implements
+// ImageScreenshotter.
+func ScreenshotImage(wd WebDriver, opts *ScreenshotOptions) (image.Image, error) {
+	if s, ok := wd.(ImageScreenshotter); ok {
+		return s.ScreenshotImage(opts)
+	}
+	return screenshotImage(wd, opts)
+}
+
+// ElementScreenshotImage takes a screenshot of elem, scrolling it into view,
+// and returns it as an image, cropped and scaled as specified by opts, which
+// may be nil. opts.Element is ignored. It calls the ScreenshotImage method of
+// elem if elem implements ImageScreenshotter.
+func ElementScreenshotImage(elem WebElement, opts *ScreenshotOptions) (image.Image, error) {
+	if s, ok := elem.(ImageScreenshotter); ok {
+		return s.ScreenshotImage(opts)
+	}
+	return elementScreenshotImage(elem, opts)
+}
+
+func (wd *remoteWD) ScreenshotImage(opts *ScreenshotOptions) (image.Image, error) {
+	return screenshotImage(wd, opts)
+}
+
+func (elem *remoteWE) ScreenshotImage(opts *ScreenshotOptions) (image.Image, error) {
+	return elementScreenshotImage(elem, opts)
+}
+
+func screenshotImage(wd WebDriver, opts *ScreenshotOptions) (image.Image, error) {
+	if opts == nil {
+		opts = new(ScreenshotOptions)
+	}
+	buf, err := wd.Screenshot()
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+
+	region := opts.Region
+	if opts.Element != nil {
+		r, err := viewportRect(wd, opts.Element)
+		if err != nil {
+			return nil, err
+		}
+		if region.Empty() {
+			region = r
+		} else {
+			region = region.Intersect(r)
+		}
+	}
+	return transformScreenshot(img, region, opts.MaxDimension)
+}
+
+func elementScreenshotImage(elem WebElement, opts *ScreenshotOptions) (image.Image, error) {
+	if opts == nil {
+		opts = new(ScreenshotOptions)
+	}
+	buf, err := elem.Screenshot(true)
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+	return transformScreenshot(img, opts.Region, opts.MaxDimension)
+}
+
+// screenshotInViewport takes a screenshot of the element without scrolling
+// it into view, by cropping a screenshot of the browser window. It returns an
+// error if the element is outside of the viewport.
+func (elem *remoteWE) screenshotInViewport() ([]byte, error) {
+	img, err := screenshotImage(elem.parent, &ScreenshotOptions{Element: elem})
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// viewportRectScript returns the bounding client rectangle of its argument
+// and the device pixel ratio.
+const viewportRectScript = `
+var r = arguments[0].getBoundingClientRect();
+return [r.left, r.top, r.right, r.bottom, window.devicePixelRatio || 1];
+`
+
+// viewportRect returns the rectangle covered by elem in a screenshot of the
+// browser window of wd.
+func viewportRect(wd WebDriver, elem WebElement) (image.Rectangle, error) {
+	response, err := wd.ExecuteScriptRaw(viewportRectScript, []interface{}{elem})
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+	reply := new(struct{ Value []float64 })
+	if err := json.Unmarshal(response, reply); err != nil {
+		return image.Rectangle{}, err
+	}
+	v := reply.Value
+	if len(v) != 5 {
+		return image.Rectangle{}, errors.New("invalid element rectangle returned")
+	}
+	ratio := v[4]
+	return image.Rect(round(v[0]*ratio), round(v[1]*ratio), round(v[2]*ratio), round(v[3]*ratio)), nil
+}
+
+// transformScreenshot crops img to region, unless it is empty, and scales it
+// down to fit within maxDimension, if it is positive.
+func transformScreenshot(img image.Image, region image.Rectangle, maxDimension int) (image.Image, error) {
+	if !region.Empty() {
+		region = region.Add(img.Bounds().Min).Intersect(img.Bounds())
+		if region.Empty() {
+			return nil, errors.New("the screenshot region is outside of the screenshot")
+		}
+		img = crop(img, region)
+	}
+	return scaleDown(img, maxDimension), nil
+}
+
+func crop(img image.Image, r image.Rectangle) image.Image {
+	if s, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return s.SubImage(r)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, r.Min, draw.Src)
+	return dst
+}
+
+// scaleDown scales img so that neither its width nor its height exceeds
+// maxDimension, averaging the source pixels covered by each destination
+// pixel.
+func scaleDown(img image.Image, maxDimension int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	longest := w
+	if h > longest {
+		longest = h
+	}
+	if maxDimension <= 0 || longest <= maxDimension {
+		return img
+	}
+	nw := w * maxDimension / longest
+	if nw < 1 {
+		nw = 1
+	}
+	nh := h * maxDimension / longest
+	if nh < 1 {
+		nh = 1
+	}
+
+	dst := image.NewRGBA64(image.Rect(0, 0, nw, nh))
+	for y := 0; y < nh; y++ {
+		y0, y1 := b.Min.Y+y*h/nh, b.Min.Y+(y+1)*h/nh
+		for x := 0; x < nw; x++ {
+			x0, x1 := b.Min.X+x*w/nw, b.Min.X+(x+1)*w/nw
+			var sr, sg, sb, sa, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					r, g, b, a := img.At(sx, sy).RGBA()
+					sr, sg, sb, sa = sr+uint64(r), sg+uint64(g), sb+uint64(b), sa+uint64(a)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{uint16(sr / n), uint16(sg / n), uint16(sb / n), uint16(sa / n)})
+		}
+	}
+	return dst
+}
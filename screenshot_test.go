@@ -0,0 +1,164 @@
+package selenium
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// testImage returns a w×h image whose left half is red and right half is
+// blue.
+func testImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBA{R: 255, A: 255}
+			if x >= w/2 {
+				c = color.NRGBA{B: 255, A: 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// serveScreenshots makes d reply to screenshot commands, of the window or of
+// an element, with a 40×20 testImage, with a device pixel ratio of 2, and
+// with elements covering the CSS pixel rectangle (5, 2)-(10, 6).
+func serveScreenshots(t *testing.T, d *fakeDriver) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(40, 20)); err != nil {
+		t.Fatalf("png.Encode() returned error: %v", err)
+	}
+	d.reply("GET", "/screenshot", base64.StdEncoding.EncodeToString(buf.Bytes()))
+	d.reply("POST", "/execute/sync", []float64{5, 2, 10, 6, 2})
+}
+
+func TestScreenshotImage(t *testing.T) {
+	d := newFakeDriver(t)
+	serveScreenshots(t, d)
+	wd := d.driver("")
+	elem := &remoteWE{parent: wd, id: "e"}
+
+	for _, tc := range []struct {
+		desc string
+		opts *ScreenshotOptions
+		want image.Rectangle
+	}{
+		{"nil options", nil, image.Rect(0, 0, 40, 20)},
+		{"element", &ScreenshotOptions{Element: elem}, image.Rect(10, 4, 20, 12)},
+		{"region", &ScreenshotOptions{Region: image.Rect(30, 0, 50, 10)}, image.Rect(30, 0, 40, 10)},
+		{
+			"element and region",
+			&ScreenshotOptions{Element: elem, Region: image.Rect(0, 0, 15, 15)},
+			image.Rect(10, 4, 15, 12),
+		},
+		{"scaled", &ScreenshotOptions{MaxDimension: 10}, image.Rect(0, 0, 10, 5)},
+	} {
+		img, err := wd.ScreenshotImage(tc.opts)
+		if err != nil {
+			t.Errorf("%s: ScreenshotImage() returned error: %v", tc.desc, err)
+			continue
+		}
+		if got := img.Bounds(); got != tc.want {
+			t.Errorf("%s: ScreenshotImage() bounds = %v, want %v", tc.desc, got, tc.want)
+		}
+	}
+
+	if _, err := wd.ScreenshotImage(&ScreenshotOptions{Region: image.Rect(50, 50, 60, 60)}); err == nil {
+		t.Errorf("ScreenshotImage() with a region outside of the screenshot did not return an error")
+	}
+
+	// Other WebDrivers are supported by the ScreenshotImage function.
+	other := struct{ WebDriver }{wd}
+	img, err := ScreenshotImage(other, &ScreenshotOptions{Element: elem})
+	if err != nil {
+		t.Fatalf("ScreenshotImage() returned error: %v", err)
+	}
+	if got, want := img.Bounds(), image.Rect(10, 4, 20, 12); got != want {
+		t.Errorf("ScreenshotImage() bounds = %v, want %v", got, want)
+	}
+
+	buf, err := elem.Screenshot(true)
+	if err != nil {
+		t.Fatalf("elem.Screenshot(true) returned error: %v", err)
+	}
+	img, err = png.Decode(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatalf("png.Decode() returned error: %v", err)
+	}
+	// The element screenshot of the driver is returned as is.
+	if got, want := img.Bounds().Size(), image.Pt(40, 20); got != want {
+		t.Errorf("elem.Screenshot(true) size = %v, want %v", got, want)
+	}
+	if got, want := d.received()[len(d.received())-1], "GET /element/e/screenshot"; got != want {
+		t.Errorf("elem.Screenshot(true) sent %q, want %q", got, want)
+	}
+
+	// Without scrolling, the screenshot of the window is cropped.
+	n := len(d.received())
+	buf, err = elem.Screenshot(false)
+	if err != nil {
+		t.Fatalf("elem.Screenshot(false) returned error: %v", err)
+	}
+	img, err = png.Decode(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatalf("png.Decode() returned error: %v", err)
+	}
+	if got, want := img.Bounds().Size(), image.Pt(10, 8); got != want {
+		t.Errorf("elem.Screenshot(false) size = %v, want %v", got, want)
+	}
+	for _, c := range d.received()[n:] {
+		if c == "GET /element/e/screenshot" {
+			t.Errorf("elem.Screenshot(false) sent %q", c)
+		}
+	}
+}
+
+func TestElementScreenshotOutsideViewport(t *testing.T) {
+	d := newFakeDriver(t)
+	d.reply("POST", "/execute/sync", []float64{100, 100, 110, 110, 1})
+	serveScreenshots(t, d)
+	elem := &remoteWE{parent: d.driver(""), id: "e"}
+
+	if _, err := elem.Screenshot(false); err == nil {
+		t.Errorf("elem.Screenshot(false) of an element outside of the viewport returned no error")
+	}
+}
+
+func TestScaleDown(t *testing.T) {
+	img := scaleDown(testImage(8, 4), 2)
+	if got, want := img.Bounds(), image.Rect(0, 0, 2, 1); got != want {
+		t.Fatalf("scaleDown() bounds = %v, want %v", got, want)
+	}
+	for x, want := range []color.RGBA64{{R: 0xffff, A: 0xffff}, {B: 0xffff, A: 0xffff}} {
+		if got := color.RGBA64Model.Convert(img.At(x, 0)); got != want {
+			t.Errorf("scaleDown() pixel (%d, 0) = %v, want %v", x, got, want)
+		}
+	}
+	if small := testImage(4, 4); scaleDown(small, 10) != image.Image(small) {
+		t.Errorf("scaleDown() of a small image returned a copy")
+	}
+}
+
+func TestEncodeScreenshot(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeScreenshot(&buf, testImage(16, 16), &ScreenshotOptions{JPEGQuality: 80}); err != nil {
+		t.Fatalf("EncodeScreenshot() returned error: %v", err)
+	}
+	if _, err := jpeg.Decode(&buf); err != nil {
+		t.Errorf("EncodeScreenshot() with JPEGQuality did not return a JPEG: %v", err)
+	}
+
+	buf.Reset()
+	if err := EncodeScreenshot(&buf, testImage(16, 16), nil); err != nil {
+		t.Fatalf("EncodeScreenshot() returned error: %v", err)
+	}
+	if _, err := png.Decode(&buf); err != nil {
+		t.Errorf("EncodeScreenshot() without options did not return a PNG: %v", err)
+	}
+}
@@ -8,9 +8,8 @@ import (
 	"github.com/tebeka/selenium/log"
 )
 
-// TODO(minusnine): make an enum type called FindMethod.
-
-// Methods by which to find elements.
+// Methods by which to find elements. See also Locator, which pairs a method
+// with a value.
 const (
 	ByID              = "id"
 	ByXPATH           = "xpath"
@@ -423,6 +422,8 @@ type WebDriver interface {
 	ExecuteScriptAsyncRaw(script string, args []interface{}) ([]byte, error)
 
 	// WaitWithTimeoutAndInterval waits for the condition to evaluate to true.
+	// If the timeout expires first, a *TimeoutError is returned. Use a Waiter
+	// for more control over how the condition is polled.
 	WaitWithTimeoutAndInterval(condition Condition, timeout, interval time.Duration) error
 
 	// WaitWithTimeout works like WaitWithTimeoutAndInterval, but with default polling interval.
@@ -434,7 +435,8 @@ type WebDriver interface {
 
 // WebElement defines method supported by web elements.
 type WebElement interface {
-	// Click clicks on the element.
+	// Click clicks on the element. If another element would receive the
+	// click, the returned error is a *ClickInterceptedError describing it.
 	Click() error
 	// SendKeys types into the element.
 	SendKeys(keys string) error
@@ -476,6 +478,9 @@ type WebElement interface {
 	// CSSProperty returns the value of the specified CSS property of the
 	// element.
 	CSSProperty(name string) (string, error)
-	// Screenshot takes a screenshot of the attribute scroll'ing if necessary.
+	// Screenshot takes a PNG screenshot of the element. If scroll is set, the
+	// element is scrolled into view if necessary. Otherwise, the screenshot
+	// of the browser window is cropped to the element, and an error is
+	// returned if the element is outside of the viewport.
 	Screenshot(scroll bool) ([]byte, error)
 }
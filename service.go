@@ -268,28 +268,41 @@ func (s *Service) start(port int) error {
 }
 
 // Stop shuts down the WebDriver service, and the X virtual frame buffer
-// if one was started.
+// if one was started. A recording of the frame buffer is finalized first.
 func (s *Service) Stop() error {
+	// Every step of the shutdown is run, and the first error is returned.
+	var firstErr error
+	check := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if s.xvfb != nil && s.xvfb.recording.active() {
+		check(s.xvfb.StopRecording())
+	}
 	// Selenium 3 stopped supporting the shutdown URL by default.
 	// https://github.com/SeleniumHQ/selenium/issues/2852
-	if s.shutdownURLPath == "" {
-		if err := s.cmd.Process.Kill(); err != nil {
-			return err
-		}
-	} else {
+	kill := s.shutdownURLPath == ""
+	if !kill {
 		resp, err := http.Get(s.addr + s.shutdownURLPath)
 		if err != nil {
-			return err
+			// The process is killed instead, so that waiting for it ends.
+			check(err)
+			kill = true
+		} else {
+			resp.Body.Close()
 		}
-		resp.Body.Close()
+	}
+	if kill {
+		check(s.cmd.Process.Kill())
 	}
 	if err := s.cmd.Wait(); err != nil && err.Error() != "signal: killed" {
-		return err
+		check(err)
 	}
 	if s.xvfb != nil {
-		return s.xvfb.Stop()
+		check(s.xvfb.Stop())
 	}
-	return nil
+	return firstErr
 }
 
 // FrameBuffer controls an X virtual frame buffer running as a background
@@ -304,6 +317,11 @@ type FrameBuffer struct {
 	AuthPath string
 
 	cmd *exec.Cmd
+	// screenSize is the "{width}x{height}" size of the screen.
+	screenSize string
+	// recording is shared by the copies of the FrameBuffer, so that any of
+	// them can stop the recording.
+	recording *recordingState
 }
 
 // NewFrameBuffer starts an X virtual frame buffer running in the background.
@@ -387,18 +405,157 @@ func NewFrameBufferWithOptions(options FrameBufferOptions) (*FrameBuffer, error)
 		return nil, err
 	}
 
-	return &FrameBuffer{display, authPath, xvfb}, nil
+	screenSize := defaultScreenSize
+	if options.ScreenSize != "" {
+		screenSize = strings.Join(strings.Split(options.ScreenSize, "x")[:2], "x")
+	}
+	return &FrameBuffer{
+		Display:    display,
+		AuthPath:   authPath,
+		cmd:        xvfb,
+		screenSize: screenSize,
+		recording:  new(recordingState),
+	}, nil
 }
 
 // Stop kills the background frame buffer process and removes the X
-// authorization file.
+// authorization file. A recording in progress is stopped first. Every step
+// is run, and the first error is returned.
 func (f FrameBuffer) Stop() error {
-	if err := f.cmd.Process.Kill(); err != nil {
-		return err
+	var firstErr error
+	if f.recording.active() {
+		firstErr = f.StopRecording()
+	}
+	if err := f.cmd.Process.Kill(); err != nil && firstErr == nil {
+		firstErr = err
 	}
 	os.Remove(f.AuthPath) // best effort removal; ignore error
-	if err := f.cmd.Wait(); err != nil && err.Error() != "signal: killed" {
+	if err := f.cmd.Wait(); err != nil && err.Error() != "signal: killed" && firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
+
+// defaultScreenSize is the screen size of Xvfb when none is specified.
+const defaultScreenSize = "1280x1024"
+
+// DefaultRecordingFrameRate is the default frame rate of FrameBuffer
+// recordings.
+const DefaultRecordingFrameRate = 15
+
+// RecordingOptions describes the options of a FrameBuffer recording.
+type RecordingOptions struct {
+	// FrameRate is the number of frames per second. If zero,
+	// DefaultRecordingFrameRate is used.
+	FrameRate int
+	// FFmpegPath is the path to the ffmpeg binary. If empty, ffmpeg is
+	// searched for in the PATH.
+	FFmpegPath string
+	// Args are additional ffmpeg arguments for the output file, e.g. to choose
+	// a codec. By default, ffmpeg chooses one from the file extension.
+	Args []string
+	// Output, if not nil, receives the log of ffmpeg.
+	Output io.Writer
+}
+
+// screenRecording is an ffmpeg process recording a frame buffer.
+type screenRecording struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+}
+
+// recordingState holds the recording in progress of a frame buffer, if any.
+type recordingState struct {
+	current *screenRecording
+}
+
+func (s *recordingState) active() bool {
+	return s != nil && s.current != nil
+}
+
+// recordingStopTimeout is the time given to ffmpeg to finalize a recording.
+const recordingStopTimeout = 10 * time.Second
+
+// recordingArgs returns the arguments of ffmpeg to record the frame buffer
+// to path.
+func (f *FrameBuffer) recordingArgs(path string, opts *RecordingOptions) []string {
+	frameRate := opts.FrameRate
+	if frameRate <= 0 {
+		frameRate = DefaultRecordingFrameRate
+	}
+	size := f.screenSize
+	if size == "" {
+		size = defaultScreenSize
+	}
+	args := []string{
+		"-y", "-loglevel", "error",
+		"-f", "x11grab",
+		"-framerate", strconv.Itoa(frameRate),
+		"-video_size", size,
+		"-i", ":" + f.Display,
+	}
+	args = append(args, opts.Args...)
+	return append(args, path)
+}
+
+// StartRecording starts recording the screen of the frame buffer to the
+// video file at path using ffmpeg, which must be installed. opts may be nil.
+// The recording is finalized by StopRecording, or when the frame buffer is
+// stopped.
+func (f *FrameBuffer) StartRecording(path string, opts *RecordingOptions) error {
+	if f.recording.active() {
+		return errors.New("the frame buffer is already being recorded")
+	}
+	if opts == nil {
+		opts = &RecordingOptions{}
+	}
+	ffmpeg := opts.FFmpegPath
+	if ffmpeg == "" {
+		ffmpeg = "ffmpeg"
+	}
+
+	cmd := exec.Command(ffmpeg, f.recordingArgs(path, opts)...)
+	cmd.Env = append(os.Environ(), "XAUTHORITY="+f.AuthPath)
+	cmd.Stdout = opts.Output
+	cmd.Stderr = opts.Output
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
 		return err
 	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("error starting ffmpeg: %v", err)
+	}
+	if f.recording == nil {
+		f.recording = new(recordingState)
+	}
+	f.recording.current = &screenRecording{cmd: cmd, stdin: stdin}
 	return nil
 }
+
+// StopRecording stops the recording started by StartRecording and waits
+// for ffmpeg to finalize the video file.
+func (f *FrameBuffer) StopRecording() error {
+	if !f.recording.active() {
+		return errors.New("the frame buffer is not being recorded")
+	}
+	r := f.recording.current
+	f.recording.current = nil
+
+	// Typing "q" makes ffmpeg stop gracefully, writing the file trailer.
+	io.WriteString(r.stdin, "q")
+	r.stdin.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- r.cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("error finalizing the recording: %v", err)
+		}
+		return nil
+	case <-time.After(recordingStopTimeout):
+		r.cmd.Process.Kill()
+		<-done
+		return errors.New("timeout waiting for ffmpeg to finalize the recording")
+	}
+}
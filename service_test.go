@@ -2,6 +2,10 @@ package selenium
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -145,3 +149,91 @@ func TestFrameBuffer(t *testing.T) {
 		}
 	})
 }
+
+func TestFrameBufferRecording(t *testing.T) {
+	dir, err := ioutil.TempDir("", "selenium-recording")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() returned error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The fake ffmpeg records its arguments to the output file once it is told
+	// to quit.
+	ffmpeg := filepath.Join(dir, "ffmpeg")
+	script := "#!/bin/sh\nread q\n[ \"$q\" = q ] || exit 1\nfor out; do :; done\necho \"$@\" > \"$out\"\n"
+	if err := ioutil.WriteFile(ffmpeg, []byte(script), 0755); err != nil {
+		t.Fatalf("ioutil.WriteFile() returned error: %v", err)
+	}
+
+	fb := &FrameBuffer{Display: "99", screenSize: "1024x768"}
+	path := filepath.Join(dir, "run.mp4")
+	if err := fb.StopRecording(); err == nil {
+		t.Errorf("StopRecording() before StartRecording() did not return an error")
+	}
+	if err := fb.StartRecording(path, &RecordingOptions{FFmpegPath: ffmpeg, FrameRate: 5}); err != nil {
+		t.Fatalf("StartRecording() returned error: %v", err)
+	}
+	if err := fb.StartRecording(path, nil); err == nil {
+		t.Errorf("StartRecording() while recording did not return an error")
+	}
+	if err := fb.StopRecording(); err != nil {
+		t.Fatalf("StopRecording() returned error: %v", err)
+	}
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile(%q) returned error: %v", path, err)
+	}
+	want := fmt.Sprintf("-y -loglevel error -f x11grab -framerate 5 -video_size 1024x768 -i :99 %s\n", path)
+	if string(got) != want {
+		t.Errorf("ffmpeg arguments = %q, want %q", got, want)
+	}
+
+	// The copies of a frame buffer share its recording.
+	if err := fb.StartRecording(path, &RecordingOptions{FFmpegPath: ffmpeg}); err != nil {
+		t.Fatalf("StartRecording() returned error: %v", err)
+	}
+	c := *fb
+	if err := c.StopRecording(); err != nil {
+		t.Fatalf("StopRecording() on a copy returned error: %v", err)
+	}
+	if err := fb.StopRecording(); err == nil {
+		t.Errorf("StopRecording() after a copy stopped the recording did not return an error")
+	}
+}
+
+func TestServiceStopAfterRecordingError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "selenium-recording")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() returned error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The fake ffmpeg fails to finalize the recording.
+	ffmpeg := filepath.Join(dir, "ffmpeg")
+	if err := ioutil.WriteFile(ffmpeg, []byte("#!/bin/sh\nread q\nexit 1\n"), 0755); err != nil {
+		t.Fatalf("ioutil.WriteFile() returned error: %v", err)
+	}
+	start := func() *exec.Cmd {
+		cmd := exec.Command("sleep", "60")
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("cmd.Start() returned error: %v", err)
+		}
+		return cmd
+	}
+	fb := &FrameBuffer{Display: "99", AuthPath: filepath.Join(dir, "xauth"), cmd: start()}
+	if err := fb.StartRecording(filepath.Join(dir, "run.mp4"), &RecordingOptions{FFmpegPath: ffmpeg}); err != nil {
+		t.Fatalf("StartRecording() returned error: %v", err)
+	}
+	s := &Service{cmd: start(), xvfb: fb}
+
+	if err := s.Stop(); err == nil {
+		t.Errorf("Stop() did not return the recording error")
+	}
+	if s.cmd.ProcessState == nil {
+		t.Errorf("the service process was not stopped")
+	}
+	if fb.cmd.ProcessState == nil {
+		t.Errorf("the frame buffer process was not stopped")
+	}
+}
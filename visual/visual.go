@@ -0,0 +1,429 @@
+// Package visual compares screenshots against golden images, to catch visual
+// regressions in tests.
+//
+// A typical test takes a screenshot and checks it against a golden PNG file
+// stored with the test:
+//
+//	if err := visual.CheckScreenshot(wd, "testdata/home.png", &visual.Options{
+//		Tolerance:    8,
+//		MaskElements: []selenium.WebElement{clock},
+//	}); err != nil {
+//		t.Error(err)
+//	}
+//
+// Setting Update writes the screenshots to the golden files instead of
+// comparing them. It is also enabled by setting the VISUAL_UPDATE environment
+// variable to a true value, which works for every package at once:
+//
+//	VISUAL_UPDATE=1 go test ./...
+//
+// Test packages can instead register the -update-goldens flag:
+//
+//	func init() {
+//		visual.RegisterUpdateFlag()
+//	}
+package visual
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tebeka/selenium"
+)
+
+// Update, if true, makes CheckGolden and CheckScreenshot write the images to
+// the golden files instead of comparing them.
+var Update bool
+
+// UpdateEnv is the environment variable that, if set to a true value as
+// parsed by strconv.ParseBool, has the same effect as Update.
+const UpdateEnv = "VISUAL_UPDATE"
+
+// UpdateFlag is the name of the flag registered by RegisterUpdateFlag.
+const UpdateFlag = "update-goldens"
+
+// RegisterUpdateFlag registers the -update-goldens flag, which sets Update, on
+// flag.CommandLine unless a flag of that name is already registered. It must
+// be called before the flags are parsed, such as from an init function of the
+// test package.
+func RegisterUpdateFlag() {
+	if flag.Lookup(UpdateFlag) == nil {
+		flag.BoolVar(&Update, UpdateFlag, Update, "write the images to the golden files instead of comparing them")
+	}
+}
+
+// updating reports whether the golden files are to be updated.
+func updating() bool {
+	if Update {
+		return true
+	}
+	update, err := strconv.ParseBool(os.Getenv(UpdateEnv))
+	return err == nil && update
+}
+
+// Options configures how images are compared. The zero value requires the
+// images to be identical, except for anti-aliased pixels.
+type Options struct {
+	// Tolerance is the maximum difference, from 0 to 255, between the color
+	// channels of two pixels that are considered equal.
+	Tolerance uint8
+	// MaxDiffPixels is the number of pixels that may differ for the images to
+	// match.
+	MaxDiffPixels int
+	// CountAntialiasing, if true, counts pixels that differ because of
+	// anti-aliasing as different. By default, they are ignored, since
+	// anti-aliasing varies between machines.
+	CountAntialiasing bool
+	// Masks are regions, in pixels of the images, that are not compared.
+	Masks []image.Rectangle
+	// MaskElements are elements that are not compared. They are only used by
+	// CheckScreenshot.
+	MaskElements []selenium.WebElement
+	// DiffDir is the directory where the diff image of mismatched images is
+	// written, under a unique name starting with the base name of the golden
+	// image. If empty, the system's temporary directory is used.
+	DiffDir string
+}
+
+// Result is the result of comparing two images.
+type Result struct {
+	// DiffPixels is the number of pixels that differ.
+	DiffPixels int
+	// AntialiasedPixels is the number of pixels that differ because of
+	// anti-aliasing and were not counted in DiffPixels.
+	AntialiasedPixels int
+	// Diff highlights the differences: pixels that differ are red, ignored
+	// anti-aliased pixels are yellow, masked regions are blue, and other
+	// pixels are a faded version of the expected image.
+	Diff *image.RGBA
+}
+
+var (
+	diffColor    = color.RGBA{255, 0, 0, 255}
+	aaColor      = color.RGBA{255, 255, 0, 255}
+	maskColor    = color.RGBA{0, 0, 255, 255}
+	maskedPixels = color.RGBA{200, 200, 255, 255}
+)
+
+// Compare compares got with want, which must have the same size. opts may
+// be nil.
+func Compare(got, want image.Image, opts *Options) (*Result, error) {
+	if opts == nil {
+		opts = new(Options)
+	}
+	gb, wb := got.Bounds(), want.Bounds()
+	if gb.Size() != wb.Size() {
+		return nil, fmt.Errorf("image sizes differ: got %v, want %v", gb.Size(), wb.Size())
+	}
+	g, w := toNRGBA(got), toNRGBA(want)
+	bounds := image.Rect(0, 0, wb.Dx(), wb.Dy())
+
+	res := &Result{Diff: image.NewRGBA(bounds)}
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			p := image.Pt(x, y)
+			switch {
+			case masked(p, opts.Masks):
+				res.Diff.SetRGBA(x, y, maskedPixels)
+			case equal(g.NRGBAAt(x, y), w.NRGBAAt(x, y), opts.Tolerance):
+				res.Diff.SetRGBA(x, y, faded(w.NRGBAAt(x, y)))
+			case !opts.CountAntialiasing && (antialiased(w, g, x, y) || antialiased(g, w, x, y)):
+				res.AntialiasedPixels++
+				res.Diff.SetRGBA(x, y, aaColor)
+			default:
+				res.DiffPixels++
+				res.Diff.SetRGBA(x, y, diffColor)
+			}
+		}
+	}
+	for _, m := range opts.Masks {
+		outline(res.Diff, m.Intersect(bounds))
+	}
+	return res, nil
+}
+
+// toNRGBA returns img as an *image.NRGBA whose bounds start at the origin.
+func toNRGBA(img image.Image) *image.NRGBA {
+	b := img.Bounds()
+	if n, ok := img.(*image.NRGBA); ok && b.Min == (image.Point{}) {
+		return n
+	}
+	n := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			n.Set(x, y, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return n
+}
+
+func masked(p image.Point, masks []image.Rectangle) bool {
+	for _, m := range masks {
+		if p.In(m) {
+			return true
+		}
+	}
+	return false
+}
+
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+func equal(a, b color.NRGBA, tolerance uint8) bool {
+	return absDiff(a.R, b.R) <= tolerance &&
+		absDiff(a.G, b.G) <= tolerance &&
+		absDiff(a.B, b.B) <= tolerance &&
+		absDiff(a.A, b.A) <= tolerance
+}
+
+// brightness returns the luma of c, blended with white according to its
+// alpha.
+func brightness(c color.NRGBA) float64 {
+	a := float64(c.A) / 255
+	blend := func(v uint8) float64 { return 255 + (float64(v)-255)*a }
+	return 0.299*blend(c.R) + 0.587*blend(c.G) + 0.114*blend(c.B)
+}
+
+func faded(c color.NRGBA) color.RGBA {
+	v := uint8(255 - (255-brightness(c))*0.1)
+	return color.RGBA{v, v, v, 255}
+}
+
+// neighbors calls f for each pixel adjacent to (x, y) within img.
+func neighbors(img *image.NRGBA, x, y int, f func(x, y int)) {
+	b := img.Bounds()
+	for ny := y - 1; ny <= y+1; ny++ {
+		for nx := x - 1; nx <= x+1; nx++ {
+			if (nx == x && ny == y) || !image.Pt(nx, ny).In(b) {
+				continue
+			}
+			f(nx, ny)
+		}
+	}
+}
+
+// hasManySiblings reports whether the pixel at (x, y) has at least three
+// adjacent pixels of the same color, i.e. is part of a uniform area.
+func hasManySiblings(img *image.NRGBA, x, y int) bool {
+	c, n := img.NRGBAAt(x, y), 0
+	neighbors(img, x, y, func(nx, ny int) {
+		if img.NRGBAAt(nx, ny) == c {
+			n++
+		}
+	})
+	return n >= 3
+}
+
+// antialiased reports whether the pixel at (x, y) of img looks like it was
+// produced by anti-aliasing: it is on an edge between a darker and a
+// brighter uniform area, in both img and other. This is the heuristic of
+// "Anti-aliased Pixel and Intensity Slope Detector" by V. Vysniauskas.
+func antialiased(img, other *image.NRGBA, x, y int) bool {
+	c := img.NRGBAAt(x, y)
+	b := brightness(c)
+	var (
+		zeroes             int
+		minDelta, maxDelta float64
+		minX, minY         int
+		maxX, maxY         int
+		found              bool
+	)
+	neighbors(img, x, y, func(nx, ny int) {
+		delta := brightness(img.NRGBAAt(nx, ny)) - b
+		if delta == 0 {
+			zeroes++
+			return
+		}
+		if !found || delta < minDelta {
+			minDelta, minX, minY = delta, nx, ny
+		}
+		if !found || delta > maxDelta {
+			maxDelta, maxX, maxY = delta, nx, ny
+		}
+		found = true
+	})
+	if zeroes > 2 || minDelta >= 0 || maxDelta <= 0 {
+		return false
+	}
+	return (hasManySiblings(img, minX, minY) && hasManySiblings(other, minX, minY)) ||
+		(hasManySiblings(img, maxX, maxY) && hasManySiblings(other, maxX, maxY))
+}
+
+// outline draws the border of r on img.
+func outline(img *image.RGBA, r image.Rectangle) {
+	if r.Empty() {
+		return
+	}
+	for x := r.Min.X; x < r.Max.X; x++ {
+		img.SetRGBA(x, r.Min.Y, maskColor)
+		img.SetRGBA(x, r.Max.Y-1, maskColor)
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		img.SetRGBA(r.Min.X, y, maskColor)
+		img.SetRGBA(r.Max.X-1, y, maskColor)
+	}
+}
+
+// MismatchError is returned when an image does not match its golden image.
+type MismatchError struct {
+	// Golden is the path to the golden image.
+	Golden string
+	// DiffPixels is the number of pixels that differ.
+	DiffPixels int
+	// DiffPath is the path to the diff image, or empty if it could not be
+	// written.
+	DiffPath string
+}
+
+func (e *MismatchError) Error() string {
+	msg := fmt.Sprintf("image does not match golden %s: %d pixels differ", e.Golden, e.DiffPixels)
+	if e.DiffPath != "" {
+		msg += "; diff written to " + e.DiffPath
+	}
+	return msg
+}
+
+// CheckGolden compares img with the golden PNG image at path. If the images
+// do not match, a diff image is written to opts.DiffDir and a
+// *MismatchError is returned. If Update is true, or the VISUAL_UPDATE
+// environment variable is, img is written to path instead. opts may be nil.
+func CheckGolden(path string, img image.Image, opts *Options) error {
+	if opts == nil {
+		opts = new(Options)
+	}
+	if updating() {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		return writePNG(path, img)
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("golden image %s does not exist; set visual.Update or %s=1 to create it", path, UpdateEnv)
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	want, err := png.Decode(f)
+	if err != nil {
+		return fmt.Errorf("error decoding golden image %s: %v", path, err)
+	}
+
+	res, err := Compare(img, want, opts)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	if res.DiffPixels <= opts.MaxDiffPixels {
+		return nil
+	}
+
+	merr := &MismatchError{Golden: path, DiffPixels: res.DiffPixels}
+	dir := opts.DiffDir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	// Goldens in different directories may have the same base name.
+	prefix := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	f, err = ioutil.TempFile(dir, prefix+".*.diff.png")
+	if err != nil {
+		return merr
+	}
+	err = png.Encode(f, res.Diff)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return merr
+	}
+	merr.DiffPath = f.Name()
+	return merr
+}
+
+func writePNG(path string, img image.Image) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// elementRectsScript returns the bounding client rectangles of its arguments,
+// in device pixels.
+const elementRectsScript = `
+var ratio = window.devicePixelRatio || 1;
+return Array.prototype.map.call(arguments, function(e) {
+	var r = e.getBoundingClientRect();
+	return [r.left * ratio, r.top * ratio, r.right * ratio, r.bottom * ratio];
+});
+`
+
+// ElementMasks returns the regions covered by elems in a screenshot of the
+// browser window.
+func ElementMasks(wd selenium.WebDriver, elems ...selenium.WebElement) ([]image.Rectangle, error) {
+	if len(elems) == 0 {
+		return nil, nil
+	}
+	args := make([]interface{}, len(elems))
+	for i, e := range elems {
+		args[i] = e
+	}
+	response, err := wd.ExecuteScriptRaw(elementRectsScript, args)
+	if err != nil {
+		return nil, err
+	}
+	reply := new(struct{ Value [][]float64 })
+	if err := json.Unmarshal(response, reply); err != nil {
+		return nil, err
+	}
+	if len(reply.Value) != len(elems) {
+		return nil, errors.New("invalid element rectangles returned")
+	}
+	masks := make([]image.Rectangle, len(elems))
+	for i, r := range reply.Value {
+		if len(r) != 4 {
+			return nil, errors.New("invalid element rectangle returned")
+		}
+		// Round outwards so that the masks cover partially covered pixels.
+		masks[i] = image.Rect(int(r[0]), int(r[1]), int(r[2]+0.999), int(r[3]+0.999))
+	}
+	return masks, nil
+}
+
+// CheckScreenshot takes a screenshot of the browser window and checks it with
+// CheckGolden, masking opts.MaskElements in addition to opts.Masks. opts may
+// be nil.
+func CheckScreenshot(wd selenium.WebDriver, golden string, opts *Options) error {
+	o := Options{}
+	if opts != nil {
+		o = *opts
+	}
+	masks, err := ElementMasks(wd, o.MaskElements...)
+	if err != nil {
+		return err
+	}
+	o.Masks = append(append([]image.Rectangle(nil), o.Masks...), masks...)
+
+	img, err := selenium.ScreenshotImage(wd, nil)
+	if err != nil {
+		return err
+	}
+	return CheckGolden(golden, img, &o)
+}
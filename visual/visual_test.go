@@ -0,0 +1,199 @@
+package visual
+
+import (
+	"errors"
+	"flag"
+	"image"
+	"image/color"
+	"image/draw"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var (
+	white = color.NRGBA{255, 255, 255, 255}
+	black = color.NRGBA{0, 0, 0, 255}
+	gray  = color.NRGBA{128, 128, 128, 255}
+)
+
+// square returns a white 10×10 image with a black 4×4 square at (3, 3).
+func square() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	draw.Draw(img, img.Bounds(), image.NewUniform(white), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(3, 3, 7, 7), image.NewUniform(black), image.Point{}, draw.Src)
+	return img
+}
+
+func TestCompare(t *testing.T) {
+	for _, tc := range []struct {
+		desc           string
+		change         func(img *image.NRGBA)
+		opts           *Options
+		diff, aliasing int
+	}{
+		{"identical", func(*image.NRGBA) {}, nil, 0, 0},
+		{
+			"changed pixel",
+			func(img *image.NRGBA) { img.SetNRGBA(0, 0, black) },
+			nil, 1, 0,
+		},
+		{
+			"within tolerance",
+			func(img *image.NRGBA) { img.SetNRGBA(0, 0, color.NRGBA{250, 250, 250, 255}) },
+			&Options{Tolerance: 5}, 0, 0,
+		},
+		{
+			"beyond tolerance",
+			func(img *image.NRGBA) { img.SetNRGBA(0, 0, color.NRGBA{250, 250, 250, 255}) },
+			&Options{Tolerance: 4}, 1, 0,
+		},
+		{
+			"masked",
+			func(img *image.NRGBA) {
+				img.SetNRGBA(0, 0, black)
+				img.SetNRGBA(9, 9, black)
+			},
+			&Options{Masks: []image.Rectangle{image.Rect(0, 0, 2, 2)}}, 1, 0,
+		},
+		{
+			// The corner of the square is smoothed by anti-aliasing.
+			"anti-aliased edge",
+			func(img *image.NRGBA) { img.SetNRGBA(2, 3, gray) },
+			nil, 0, 1,
+		},
+		{
+			"counted anti-aliased edge",
+			func(img *image.NRGBA) { img.SetNRGBA(2, 3, gray) },
+			&Options{CountAntialiasing: true}, 1, 0,
+		},
+	} {
+		got := square()
+		tc.change(got)
+		res, err := Compare(got, square(), tc.opts)
+		if err != nil {
+			t.Errorf("%s: Compare() returned error: %v", tc.desc, err)
+			continue
+		}
+		if res.DiffPixels != tc.diff || res.AntialiasedPixels != tc.aliasing {
+			t.Errorf("%s: Compare() = %d different and %d anti-aliased pixels, want %d and %d",
+				tc.desc, res.DiffPixels, res.AntialiasedPixels, tc.diff, tc.aliasing)
+		}
+		if res.Diff.Bounds() != got.Bounds() {
+			t.Errorf("%s: diff image bounds = %v, want %v", tc.desc, res.Diff.Bounds(), got.Bounds())
+		}
+	}
+
+	if _, err := Compare(square(), image.NewNRGBA(image.Rect(0, 0, 5, 5)), nil); err == nil {
+		t.Errorf("Compare() of images with different sizes did not return an error")
+	}
+}
+
+func TestCompareDiffImage(t *testing.T) {
+	got := square()
+	got.SetNRGBA(0, 0, black)
+	res, err := Compare(got, square(), nil)
+	if err != nil {
+		t.Fatalf("Compare() returned error: %v", err)
+	}
+	if c := res.Diff.RGBAAt(0, 0); c != diffColor {
+		t.Errorf("diff image pixel (0, 0) = %v, want %v", c, diffColor)
+	}
+	if c := res.Diff.RGBAAt(9, 9); c == diffColor {
+		t.Errorf("diff image pixel (9, 9) is highlighted, want faded")
+	}
+}
+
+func TestCheckGolden(t *testing.T) {
+	dir, err := ioutil.TempDir("", "visual")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() returned error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	golden := filepath.Join(dir, "testdata", "square.png")
+
+	if err := CheckGolden(golden, square(), nil); err == nil {
+		t.Fatalf("CheckGolden() with a missing golden did not return an error")
+	}
+
+	Update = true
+	err = CheckGolden(golden, square(), nil)
+	Update = false
+	if err != nil {
+		t.Fatalf("CheckGolden() in update mode returned error: %v", err)
+	}
+	if err := CheckGolden(golden, square(), nil); err != nil {
+		t.Errorf("CheckGolden() of the same image returned error: %v", err)
+	}
+
+	changed := square()
+	changed.SetNRGBA(0, 0, black)
+	changed.SetNRGBA(9, 9, black)
+	if err := CheckGolden(golden, changed, &Options{MaxDiffPixels: 2}); err != nil {
+		t.Errorf("CheckGolden() with allowed differences returned error: %v", err)
+	}
+	err = CheckGolden(golden, changed, &Options{DiffDir: dir})
+	var merr *MismatchError
+	if !errors.As(err, &merr) {
+		t.Fatalf("CheckGolden() of a changed image returned error %v, want a *MismatchError", err)
+	}
+	if merr.DiffPixels != 2 {
+		t.Errorf("MismatchError.DiffPixels = %d, want 2", merr.DiffPixels)
+	}
+	if d, name := filepath.Split(merr.DiffPath); filepath.Clean(d) != dir || !strings.HasPrefix(name, "square.") || !strings.HasSuffix(name, ".diff.png") {
+		t.Errorf("MismatchError.DiffPath = %q, want a square.*.diff.png file in %s", merr.DiffPath, dir)
+	}
+	if _, err := os.Stat(merr.DiffPath); err != nil {
+		t.Errorf("diff image was not written: %v", err)
+	}
+
+	// The environment variable also updates the golden.
+	os.Setenv(UpdateEnv, "1")
+	err = CheckGolden(golden, changed, nil)
+	os.Unsetenv(UpdateEnv)
+	if err != nil {
+		t.Fatalf("CheckGolden() with %s=1 returned error: %v", UpdateEnv, err)
+	}
+	if err := CheckGolden(golden, changed, nil); err != nil {
+		t.Errorf("CheckGolden() of the updated image returned error: %v", err)
+	}
+	if err := CheckGolden(golden, square(), &Options{DiffDir: dir}); err == nil {
+		t.Errorf("CheckGolden() of the previous image returned no error")
+	}
+
+	// A golden with the same base name does not overwrite the diff image.
+	other := filepath.Join(dir, "other", "square.png")
+	Update = true
+	err = CheckGolden(other, square(), nil)
+	Update = false
+	if err != nil {
+		t.Fatalf("CheckGolden() in update mode returned error: %v", err)
+	}
+	err = CheckGolden(other, changed, &Options{DiffDir: dir})
+	var omerr *MismatchError
+	if !errors.As(err, &omerr) {
+		t.Fatalf("CheckGolden() of a changed image returned error %v, want a *MismatchError", err)
+	}
+	if omerr.DiffPath == merr.DiffPath {
+		t.Errorf("CheckGolden() of two goldens named square.png wrote both diffs to %s", merr.DiffPath)
+	}
+}
+
+func TestRegisterUpdateFlag(t *testing.T) {
+	RegisterUpdateFlag()
+	// Registering the flag again does not panic.
+	RegisterUpdateFlag()
+	f := flag.Lookup(UpdateFlag)
+	if f == nil {
+		t.Fatalf("RegisterUpdateFlag() did not register -%s", UpdateFlag)
+	}
+	defer func() { Update = false }()
+	if err := f.Value.Set("true"); err != nil {
+		t.Fatalf("setting -%s returned error: %v", UpdateFlag, err)
+	}
+	if !Update {
+		t.Errorf("-%s=true did not set Update", UpdateFlag)
+	}
+}
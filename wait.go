@@ -0,0 +1,182 @@
+package selenium
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// WaitOption configures a Waiter instance.
+type WaitOption func(*Waiter)
+
+// WaitTimeout sets the amount of time after which the Waiter gives up. The
+// default is DefaultWaitTimeout.
+func WaitTimeout(timeout time.Duration) WaitOption {
+	return func(w *Waiter) {
+		w.timeout = timeout
+	}
+}
+
+// WaitInterval sets the amount of time between evaluations of the condition.
+// The default is DefaultWaitInterval.
+func WaitInterval(interval time.Duration) WaitOption {
+	return func(w *Waiter) {
+		w.interval = interval
+	}
+}
+
+// ExponentialBackoff causes the polling interval to be multiplied by factor
+// after each unsatisfied evaluation of the condition, up to maxInterval. A
+// maxInterval of zero does not limit the interval. A factor smaller than 1
+// is treated as 1, so the interval never becomes shorter than the one set by
+// WaitInterval.
+func ExponentialBackoff(factor float64, maxInterval time.Duration) WaitOption {
+	return func(w *Waiter) {
+		if factor < 1 {
+			factor = 1
+		}
+		w.backoff = factor
+		w.maxInterval = maxInterval
+	}
+}
+
+// Jitter randomizes each polling interval by up to the given fraction of its
+// length in either direction. For example, a fraction of 0.1 causes an
+// interval of one second to last between 900 and 1100 milliseconds.
+func Jitter(fraction float64) WaitOption {
+	return func(w *Waiter) {
+		w.jitter = fraction
+	}
+}
+
+// IgnoreErrors causes errors with the given codes that are returned by the
+// condition to be treated as if the condition was not yet satisfied, rather
+// than aborting the wait. See HasErrorCode for how codes are matched.
+func IgnoreErrors(codes ...string) WaitOption {
+	return func(w *Waiter) {
+		w.ignored = append(w.ignored, codes...)
+	}
+}
+
+// WaitMessage sets a message, describing what is waited for, that is
+// included in the error returned upon timeout.
+func WaitMessage(msg string) WaitOption {
+	return func(w *Waiter) {
+		w.message = msg
+	}
+}
+
+// Waiter repeatedly evaluates a condition until it is satisfied, it fails, or
+// a timeout expires.
+type Waiter struct {
+	timeout, interval time.Duration
+	backoff           float64
+	maxInterval       time.Duration
+	jitter            float64
+	ignored           []string
+	message           string
+}
+
+// NewWaiter returns a Waiter configured by the given options.
+func NewWaiter(opts ...WaitOption) *Waiter {
+	w := &Waiter{
+		timeout:  DefaultWaitTimeout,
+		interval: DefaultWaitInterval,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// TimeoutError is returned by Waiter.Until when the condition was not
+// satisfied before the timeout expired.
+type TimeoutError struct {
+	// Message is the message set by WaitMessage, if any.
+	Message string
+	// Elapsed is the amount of time spent waiting.
+	Elapsed time.Duration
+	// Attempts is the number of times the condition was evaluated.
+	Attempts int
+	// LastErr is the last ignored error returned by the condition, if any.
+	LastErr error
+}
+
+// Error implements the error interface.
+func (e *TimeoutError) Error() string {
+	msg := fmt.Sprintf("timeout after %v (%d attempts)", e.Elapsed, e.Attempts)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.LastErr != nil {
+		msg += fmt.Sprintf(": last error: %v", e.LastErr)
+	}
+	return msg
+}
+
+// Unwrap returns the last error returned by the condition.
+func (e *TimeoutError) Unwrap() error {
+	return e.LastErr
+}
+
+// Until evaluates condition until it is satisfied or the timeout expires, in
+// which case a *TimeoutError is returned. Errors returned by the condition
+// abort the wait and are returned as is, unless they were configured to be
+// ignored by IgnoreErrors.
+func (w *Waiter) Until(wd WebDriver, condition Condition) error {
+	startTime := time.Now()
+	deadline := startTime.Add(w.timeout)
+	interval := w.interval
+
+	var lastErr error
+	for attempts := 1; ; attempts++ {
+		done, err := condition(wd)
+		if err != nil {
+			if !HasErrorCode(err, w.ignored...) {
+				return err
+			}
+			lastErr = err
+		} else if done {
+			return nil
+		}
+
+		if elapsed := time.Since(startTime); elapsed > w.timeout {
+			return &TimeoutError{
+				Message:  w.message,
+				Elapsed:  elapsed,
+				Attempts: attempts,
+				LastErr:  lastErr,
+			}
+		}
+		// The last evaluation happens at the deadline, not up to an interval
+		// after it.
+		sleep := w.jittered(interval)
+		if remaining := time.Until(deadline); sleep > remaining {
+			sleep = remaining
+		}
+		time.Sleep(sleep)
+		interval = w.nextInterval(interval)
+	}
+}
+
+func (w *Waiter) jittered(interval time.Duration) time.Duration {
+	if w.jitter <= 0 {
+		return interval
+	}
+	delta := (2*rand.Float64() - 1) * w.jitter * float64(interval)
+	return interval + time.Duration(delta)
+}
+
+func (w *Waiter) nextInterval(interval time.Duration) time.Duration {
+	if w.backoff <= 0 {
+		return interval
+	}
+	interval = time.Duration(float64(interval) * w.backoff)
+	if w.maxInterval > 0 && interval > w.maxInterval {
+		interval = w.maxInterval
+	}
+	if interval < w.interval {
+		interval = w.interval
+	}
+	return interval
+}
@@ -0,0 +1,121 @@
+package selenium
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWaiter(t *testing.T) {
+	staleErr := &Error{Err: CodeStaleElementReference, Message: "element is not attached"}
+
+	t.Run("Satisfied", func(t *testing.T) {
+		var calls int
+		cond := func(WebDriver) (bool, error) {
+			calls++
+			return calls == 3, nil
+		}
+		w := NewWaiter(WaitTimeout(time.Second), WaitInterval(time.Millisecond))
+		if err := w.Until(nil, cond); err != nil {
+			t.Fatalf("w.Until() returned error: %v", err)
+		}
+		if calls != 3 {
+			t.Fatalf("condition evaluated %d times, want 3", calls)
+		}
+	})
+
+	t.Run("Aborts on error", func(t *testing.T) {
+		cond := func(WebDriver) (bool, error) { return false, staleErr }
+		w := NewWaiter(WaitTimeout(time.Second), WaitInterval(time.Millisecond))
+		if err := w.Until(nil, cond); err != staleErr {
+			t.Fatalf("w.Until() returned error %v, want %v", err, staleErr)
+		}
+	})
+
+	t.Run("Ignored errors", func(t *testing.T) {
+		var calls int
+		cond := func(WebDriver) (bool, error) {
+			calls++
+			if calls < 3 {
+				return false, staleErr
+			}
+			return true, nil
+		}
+		w := NewWaiter(WaitTimeout(time.Second), WaitInterval(time.Millisecond), IgnoreErrors(CodeStaleElementReference))
+		if err := w.Until(nil, cond); err != nil {
+			t.Fatalf("w.Until() returned error: %v", err)
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		cond := func(WebDriver) (bool, error) { return false, staleErr }
+		w := NewWaiter(
+			WaitTimeout(20*time.Millisecond),
+			WaitInterval(time.Millisecond),
+			IgnoreErrors(CodeNoSuchElement, CodeStaleElementReference),
+			WaitMessage("waiting for the button"),
+		)
+		err := w.Until(nil, cond)
+		var timeoutErr *TimeoutError
+		if !errors.As(err, &timeoutErr) {
+			t.Fatalf("w.Until() returned error %v, want a *TimeoutError", err)
+		}
+		if timeoutErr.Attempts < 2 {
+			t.Errorf("timeoutErr.Attempts = %d, want at least 2", timeoutErr.Attempts)
+		}
+		if !errors.Is(err, staleErr) {
+			t.Errorf("w.Until() returned error %v, want it to wrap %v", err, staleErr)
+		}
+		for _, want := range []string{"timeout after", "waiting for the button", "element is not attached"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("err.Error() = %q, want it to contain %q", err.Error(), want)
+			}
+		}
+	})
+}
+
+func TestWaiterIntervals(t *testing.T) {
+	w := NewWaiter(WaitInterval(100*time.Millisecond), ExponentialBackoff(2, 300*time.Millisecond))
+	interval := w.interval
+	var got []time.Duration
+	for i := 0; i < 4; i++ {
+		interval = w.nextInterval(interval)
+		got = append(got, interval)
+	}
+	want := []time.Duration{200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("intervals = %v, want %v", got, want)
+		}
+	}
+
+	// Factors smaller than 1 do not shorten the interval.
+	w = NewWaiter(WaitInterval(100*time.Millisecond), ExponentialBackoff(0.5, 0))
+	if got := w.nextInterval(w.interval); got != 100*time.Millisecond {
+		t.Errorf("interval with a factor of 0.5 = %v, want %v", got, 100*time.Millisecond)
+	}
+
+	w = NewWaiter(Jitter(0.1))
+	for i := 0; i < 100; i++ {
+		if d := w.jittered(time.Second); d < 900*time.Millisecond || d > 1100*time.Millisecond {
+			t.Fatalf("w.jittered(1s) = %v, want within 10%%", d)
+		}
+	}
+}
+
+func TestWaiterDeadline(t *testing.T) {
+	w := NewWaiter(WaitTimeout(50*time.Millisecond), WaitInterval(10*time.Second), ExponentialBackoff(2, 0), Jitter(0.5))
+	start := time.Now()
+	err := w.Until(nil, func(WebDriver) (bool, error) { return false, nil })
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("w.Until() returned error %v, want a *TimeoutError", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("w.Until() with a 50ms timeout returned after %v", elapsed)
+	}
+	if timeoutErr.Attempts != 2 {
+		t.Errorf("timeoutErr.Attempts = %d, want 2: one at the start and one at the deadline", timeoutErr.Attempts)
+	}
+}
@@ -0,0 +1,133 @@
+// Package webdrivertest provides helpers for tests that drive browsers with
+// the selenium package.
+package webdrivertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/tebeka/selenium"
+	"github.com/tebeka/selenium/log"
+)
+
+// ArtifactsDirEnv is the environment variable that sets the default
+// directory in which CaptureOnFailure writes artifacts.
+const ArtifactsDirEnv = "SELENIUM_ARTIFACTS_DIR"
+
+// ArtifactLogTypes are the logs written by CaptureArtifacts.
+var ArtifactLogTypes = []log.Type{log.Browser, log.Driver}
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// artifactsDir returns the directory for the artifacts of the test named
+// name within root.
+func artifactsDir(root, name string) string {
+	if root == "" {
+		root = os.Getenv(ArtifactsDirEnv)
+	}
+	if root == "" {
+		root = filepath.Join(os.TempDir(), "selenium-artifacts")
+	}
+	return filepath.Join(root, unsafeChars.ReplaceAllString(name, "_"))
+}
+
+// CaptureOnFailure registers a cleanup function with t that, if the test
+// failed, writes the state of the session of wd to a directory named after
+// the test within dir, using CaptureArtifacts. If dir is empty, the directory
+// named by the SELENIUM_ARTIFACTS_DIR environment variable is used, or a
+// directory in the system's temporary directory if it is not set.
+//
+// Cleanup functions run in reverse order of registration, so
+// CaptureOnFailure must be called after registering the function that quits
+// the session.
+func CaptureOnFailure(t testing.TB, wd selenium.WebDriver, dir string) {
+	t.Helper()
+	t.Cleanup(func() {
+		if !t.Failed() {
+			return
+		}
+		path := artifactsDir(dir, t.Name())
+		if err := CaptureArtifacts(wd, path); err != nil {
+			t.Logf("Some artifacts could not be captured to %s: %v", path, err)
+			return
+		}
+		t.Logf("Artifacts captured to %s", path)
+	})
+}
+
+// CaptureArtifacts writes the state of the session of wd to files in dir:
+//
+//	screenshot.png  a screenshot of the browser window
+//	page.html       the page source
+//	url.txt         the current URL
+//	windows.txt     the window handles, with the current one marked by "*"
+//	cookies.json    the cookies
+//	<type>.log.json the logs of each of ArtifactLogTypes
+//
+// Artifacts that cannot be retrieved, e.g. because the session has ended,
+// are skipped. The returned error lists them.
+func CaptureArtifacts(wd selenium.WebDriver, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	var failures []string
+	write := func(name string, data []byte, err error) {
+		if err == nil {
+			err = ioutil.WriteFile(filepath.Join(dir, name), data, 0644)
+		}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	writeJSON := func(name string, v interface{}, err error) {
+		var data []byte
+		if err == nil {
+			data, err = json.MarshalIndent(v, "", "  ")
+		}
+		write(name, data, err)
+	}
+
+	screenshot, err := wd.Screenshot()
+	write("screenshot.png", screenshot, err)
+
+	source, err := wd.PageSource()
+	write("page.html", []byte(source), err)
+
+	url, err := wd.CurrentURL()
+	write("url.txt", []byte(url+"\n"), err)
+
+	handles, err := wd.WindowHandles()
+	if err == nil {
+		current, _ := wd.CurrentWindowHandle()
+		var lines []string
+		for _, h := range handles {
+			if h == current {
+				h = "* " + h
+			}
+			lines = append(lines, h)
+		}
+		write("windows.txt", []byte(strings.Join(lines, "\n")+"\n"), nil)
+	} else {
+		write("windows.txt", nil, err)
+	}
+
+	cookies, err := wd.GetCookies()
+	writeJSON("cookies.json", cookies, err)
+
+	for _, typ := range ArtifactLogTypes {
+		messages, err := wd.Log(typ)
+		writeJSON(string(typ)+".log.json", messages, err)
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%d artifacts not captured: %s", len(failures), strings.Join(failures, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package webdrivertest
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/tebeka/selenium"
+	"github.com/tebeka/selenium/log"
+)
+
+// fakeDriver is a WebDriver with a fixed state. If dead is set, all its
+// methods fail as if the session had ended.
+type fakeDriver struct {
+	selenium.WebDriver
+	dead bool
+}
+
+var errDead = &selenium.Error{Err: "invalid session id"}
+
+func (wd *fakeDriver) err() error {
+	if wd.dead {
+		return errDead
+	}
+	return nil
+}
+
+func (wd *fakeDriver) Screenshot() ([]byte, error) { return []byte("png"), wd.err() }
+func (wd *fakeDriver) PageSource() (string, error) { return "<html></html>", wd.err() }
+func (wd *fakeDriver) CurrentURL() (string, error) { return "http://example.com/", wd.err() }
+func (wd *fakeDriver) WindowHandles() ([]string, error) {
+	return []string{"w1", "w2"}, wd.err()
+}
+func (wd *fakeDriver) CurrentWindowHandle() (string, error) { return "w2", wd.err() }
+func (wd *fakeDriver) GetCookies() ([]selenium.Cookie, error) {
+	return []selenium.Cookie{{Name: "session", Value: "1"}}, wd.err()
+}
+func (wd *fakeDriver) Log(typ log.Type) ([]log.Message, error) {
+	if typ == log.Driver {
+		return nil, errors.New("log type not supported")
+	}
+	return []log.Message{{Level: log.Severe, Message: "oops"}}, wd.err()
+}
+func (wd *fakeDriver) WebSocketURL() string { return "" }
+
+// fakeT is a testing.TB whose failure state and cleanup functions are
+// controlled by the test.
+type fakeT struct {
+	testing.TB
+	name     string
+	failed   bool
+	skipped  bool
+	cleanups []func()
+	logs     []string
+}
+
+func (t *fakeT) Helper()                                   {}
+func (t *fakeT) Name() string                              { return t.name }
+func (t *fakeT) Failed() bool                              { return t.failed }
+func (t *fakeT) Cleanup(f func())                          { t.cleanups = append(t.cleanups, f) }
+func (t *fakeT) Logf(format string, args ...interface{})   { t.logs = append(t.logs, format) }
+func (t *fakeT) Skipf(format string, args ...interface{})  { t.skipped = true }
+func (t *fakeT) Fatalf(format string, args ...interface{}) { t.failed = true }
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.failed = true
+	t.logs = append(t.logs, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeT) cleanup() {
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		t.cleanups[i]()
+	}
+}
+
+func listDir(t *testing.T, dir string) []string {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ioutil.ReadDir(%q) returned error: %v", dir, err)
+	}
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestCaptureOnFailure(t *testing.T) {
+	root, err := ioutil.TempDir("", "webdrivertest")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() returned error: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	passed := &fakeT{name: "TestPassed"}
+	CaptureOnFailure(passed, &fakeDriver{}, root)
+	passed.cleanup()
+	if _, err := os.Stat(filepath.Join(root, "TestPassed")); !os.IsNotExist(err) {
+		t.Errorf("artifacts were captured for a passing test")
+	}
+
+	failed := &fakeT{name: "TestLogin/with spaces", failed: true}
+	CaptureOnFailure(failed, &fakeDriver{}, root)
+	failed.cleanup()
+	dir := filepath.Join(root, "TestLogin_with_spaces")
+	want := []string{"browser.log.json", "cookies.json", "page.html", "screenshot.png", "url.txt", "windows.txt"}
+	if got := listDir(t, dir); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("captured artifacts = %v, want %v", got, want)
+	}
+	windows, err := ioutil.ReadFile(filepath.Join(dir, "windows.txt"))
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile() returned error: %v", err)
+	}
+	if got, want := string(windows), "w1\n* w2\n"; got != want {
+		t.Errorf("windows.txt = %q, want %q", got, want)
+	}
+	// The driver log is not supported by the fake driver.
+	if len(failed.logs) != 1 || !strings.Contains(failed.logs[0], "could not be captured") {
+		t.Errorf("logs = %q, want a message about missing artifacts", failed.logs)
+	}
+}
+
+func TestCaptureArtifactsDeadSession(t *testing.T) {
+	dir, err := ioutil.TempDir("", "webdrivertest")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() returned error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = CaptureArtifacts(&fakeDriver{dead: true}, dir)
+	if err == nil || !strings.Contains(err.Error(), "7 artifacts not captured") {
+		t.Errorf("CaptureArtifacts() returned error %v, want 7 missing artifacts", err)
+	}
+	if got := listDir(t, dir); len(got) != 0 {
+		t.Errorf("captured artifacts = %v, want none", got)
+	}
+}
@@ -0,0 +1,53 @@
+package webdrivertest
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/tebeka/selenium"
+	"github.com/tebeka/selenium/log"
+	"github.com/tebeka/selenium/logstream"
+)
+
+// FailOnBrowserErrors streams the browser log of the session of wd, and
+// registers a cleanup function with t that fails the test if severe entries,
+// such as console errors and uncaught JavaScript exceptions, were logged,
+// unless their text matches one of the patterns in ignore. It returns the
+// stream, which the test may also read, or nil if the browser log cannot be
+// read, in which case the test is not checked.
+//
+// As with CaptureOnFailure, it must be called after registering the function
+// that quits the session.
+func FailOnBrowserErrors(t testing.TB, wd selenium.WebDriver, ignore ...*regexp.Regexp) *logstream.Stream {
+	t.Helper()
+	s, err := logstream.Start(wd, nil)
+	if err != nil {
+		t.Logf("Browser errors are not checked because the log cannot be read: %v", err)
+		return nil
+	}
+	t.Cleanup(func() {
+		if err := s.Stop(); err != nil {
+			t.Logf("Reading the browser log returned error: %v", err)
+		}
+		var errs []string
+		for _, e := range s.Entries() {
+			if e.Level == log.Severe && !matchesAny(e.Text, ignore) {
+				errs = append(errs, e.String())
+			}
+		}
+		if len(errs) > 0 {
+			t.Errorf("The browser logged %d unexpected errors:\n%s", len(errs), strings.Join(errs, "\n"))
+		}
+	})
+	return s
+}
+
+func matchesAny(s string, patterns []*regexp.Regexp) bool {
+	for _, p := range patterns {
+		if p.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
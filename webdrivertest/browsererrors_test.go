@@ -0,0 +1,30 @@
+package webdrivertest
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestFailOnBrowserErrors(t *testing.T) {
+	ft := &fakeT{name: "TestErrors"}
+	if s := FailOnBrowserErrors(ft, &fakeDriver{}); s == nil {
+		t.Fatalf("FailOnBrowserErrors() = nil, want a stream")
+	}
+	ft.cleanup()
+	if !ft.failed || len(ft.logs) != 1 || !strings.Contains(ft.logs[0], "SEVERE console: oops") {
+		t.Errorf("failed = %t, logs = %q; want a failure listing the error", ft.failed, ft.logs)
+	}
+
+	ft = &fakeT{name: "TestIgnoredErrors"}
+	FailOnBrowserErrors(ft, &fakeDriver{}, regexp.MustCompile("^oops$"))
+	ft.cleanup()
+	if ft.failed {
+		t.Errorf("the test failed because of an ignored error: %q", ft.logs)
+	}
+
+	ft = &fakeT{name: "TestNoLog"}
+	if s := FailOnBrowserErrors(ft, &fakeDriver{dead: true}); s != nil || ft.failed {
+		t.Errorf("FailOnBrowserErrors() without a log = %v, failed = %t; want nil", s, ft.failed)
+	}
+}
@@ -0,0 +1,150 @@
+package webdrivertest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tebeka/selenium"
+)
+
+// fakeRequest is a command received by a fakeServer.
+type fakeRequest struct {
+	Method string
+	// Session is the ID of the session of the command, or empty for the
+	// commands outside of a session, such as New Session.
+	Session string
+	// Path is the path of the command, relative to the session if it has one,
+	// such as "/url", or "/" for Delete Session.
+	Path string
+	Body []byte
+}
+
+// decode decodes the JSON body of the command into v.
+func (r *fakeRequest) decode(t *testing.T, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		t.Errorf("Decoding the body of %s %s returned error: %v", r.Method, r.Path, err)
+	}
+}
+
+// fakeHandler returns the value of the reply to a command, or an error. A
+// *selenium.Error is sent with its HTTPCode as the HTTP status, or 500 if it
+// is zero.
+type fakeHandler func(r *fakeRequest) (interface{}, error)
+
+type fakeRoute struct {
+	method, path string
+	handler      fakeHandler
+}
+
+// fakeServer is a fake W3C WebDriver server. Commands are replied to by the
+// handler of the first route they match, and with an unknown command error
+// if they match none. It is safe for concurrent use.
+type fakeServer struct {
+	t *testing.T
+	// URL is the URL of the server.
+	URL string
+	hs  *httptest.Server
+
+	mu       sync.Mutex
+	routes   []fakeRoute
+	requests []*fakeRequest
+}
+
+// newFakeServer starts a fakeServer, which must be closed by calling Close.
+func newFakeServer(t *testing.T) *fakeServer {
+	s := &fakeServer{t: t}
+	s.hs = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.hs.URL
+	return s
+}
+
+// Close stops the server.
+func (s *fakeServer) Close() {
+	s.hs.Close()
+}
+
+// handle routes the commands with the given path, relative to their session,
+// and whose method is method or any method if it is empty, to h. An empty
+// path matches all the commands.
+func (s *fakeServer) handle(method, path string, h fakeHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes = append(s.routes, fakeRoute{method, path, h})
+}
+
+// reply routes the commands as for handle to a handler that replies with
+// value.
+func (s *fakeServer) reply(method, path string, value interface{}) {
+	s.handle(method, path, func(*fakeRequest) (interface{}, error) { return value, nil })
+}
+
+// received returns the commands received so far by the given session, as
+// "METHOD path" with the path relative to the session.
+func (s *fakeServer) received(session string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var commands []string
+	for _, r := range s.requests {
+		if r.Session == session {
+			commands = append(commands, r.Method+" "+r.Path)
+		}
+	}
+	return commands
+}
+
+func (s *fakeServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.t.Errorf("Reading the body of %s %s returned error: %v", r.Method, r.URL.Path, err)
+	}
+	req := &fakeRequest{Method: r.Method, Path: r.URL.Path, Body: body}
+	if rest := strings.TrimPrefix(r.URL.Path, "/session/"); rest != r.URL.Path {
+		parts := strings.SplitN(rest, "/", 2)
+		req.Session, req.Path = parts[0], "/"
+		if len(parts) == 2 {
+			req.Path += parts[1]
+		}
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	var h fakeHandler
+	for _, route := range s.routes {
+		if (route.method == "" || route.method == req.Method) && (route.path == "" || route.path == req.Path) {
+			h = route.handler
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	if h == nil {
+		writeReply(w, http.StatusNotFound, &selenium.Error{Err: selenium.CodeUnknownCommand, Message: r.URL.Path})
+		return
+	}
+	value, err := h(req)
+	if err == nil {
+		writeReply(w, http.StatusOK, value)
+		return
+	}
+	e, ok := err.(*selenium.Error)
+	if !ok {
+		e = &selenium.Error{Err: "unknown error", Message: err.Error()}
+	}
+	status := e.HTTPCode
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	writeReply(w, status, e)
+}
+
+func writeReply(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"value": value})
+}
@@ -0,0 +1,183 @@
+package webdrivertest
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/tebeka/selenium"
+)
+
+// Launcher starts a WebDriver server and returns its URL prefix and a
+// function that stops it.
+type Launcher func() (urlPrefix string, stop func() error, err error)
+
+// unusedPort returns a TCP port that is not in use.
+func unusedPort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	if err := l.Close(); err != nil {
+		return 0, err
+	}
+	return port, nil
+}
+
+// serviceLauncher returns a Launcher that starts a service on an unused port
+// and serves the WebDriver protocol under path.
+func serviceLauncher(path string, start func(port int) (*selenium.Service, error)) Launcher {
+	return func() (string, func() error, error) {
+		port, err := unusedPort()
+		if err != nil {
+			return "", nil, err
+		}
+		s, err := start(port)
+		if err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("http://localhost:%d%s", port, path), s.Stop, nil
+	}
+}
+
+// ChromeDriverLauncher returns a Launcher that starts the ChromeDriver binary
+// at path.
+func ChromeDriverLauncher(path string, opts ...selenium.ServiceOption) Launcher {
+	return serviceLauncher("/wd/hub", func(port int) (*selenium.Service, error) {
+		return selenium.NewChromeDriverService(path, port, opts...)
+	})
+}
+
+// GeckoDriverLauncher returns a Launcher that starts the GeckoDriver binary
+// at path.
+func GeckoDriverLauncher(path string, opts ...selenium.ServiceOption) Launcher {
+	return serviceLauncher("", func(port int) (*selenium.Service, error) {
+		return selenium.NewGeckoDriverService(path, port, opts...)
+	})
+}
+
+// SeleniumLauncher returns a Launcher that starts the Selenium server JAR at
+// jarPath. Drivers are configured with options such as selenium.GeckoDriver
+// and selenium.HTMLUnit.
+func SeleniumLauncher(jarPath string, opts ...selenium.ServiceOption) Launcher {
+	return serviceLauncher("/wd/hub", func(port int) (*selenium.Service, error) {
+		return selenium.NewSeleniumService(jarPath, port, opts...)
+	})
+}
+
+// Browser is a browser configuration of a Matrix.
+type Browser struct {
+	// Name is the name of the subtest of the browser.
+	Name string
+	// Capabilities are the capabilities of the sessions of the browser.
+	Capabilities selenium.Capabilities
+	// Launch starts the WebDriver server of the browser, which is stopped
+	// after its tests. If nil, the server at URL is used.
+	Launch Launcher
+	// URL is the URL of the WebDriver server if Launch is nil. If both are
+	// unset, the default URL of NewSession is used.
+	URL string
+	// Skip maps the names of tests that must not run on the browser to the
+	// reason they are skipped.
+	Skip map[string]string
+	// MaxSessions is the maximum number of tests that run concurrently on the
+	// browser. If it is less than 2, the tests run sequentially.
+	MaxSessions int
+	// SessionOptions configure the sessions of the tests.
+	SessionOptions []SessionOption
+}
+
+// Test is a test run by a Matrix on each browser.
+type Test struct {
+	Name string
+	// F runs the test with a session of the browser, which is quit when the
+	// test ends.
+	F func(t *testing.T, wd selenium.WebDriver)
+}
+
+// Matrix runs tests on several browsers.
+type Matrix struct {
+	Browsers []Browser
+	// MaxBrowsers is the maximum number of browsers tested concurrently. If
+	// it is less than 2, the browsers are tested one after the other.
+	MaxBrowsers int
+}
+
+// Run runs each of tests on each of the browsers of m, as the subtest
+// <browser>/<test> of t. Each browser's WebDriver server is started before
+// its first test and stopped after its last one, and each test gets its own
+// session, as returned by NewSession. Browsers whose server cannot be started
+// because its binary is missing are skipped.
+//
+// When browsers or tests run concurrently, their subtests call t.Parallel,
+// so they start after the function calling Run returns, and the -parallel
+// flag also limits them.
+func (m *Matrix) Run(t *testing.T, tests ...Test) {
+	var browsers chan struct{}
+	if m.MaxBrowsers > 1 {
+		browsers = make(chan struct{}, m.MaxBrowsers)
+	}
+	for _, b := range m.Browsers {
+		b := b
+		t.Run(b.Name, func(t *testing.T) {
+			if browsers != nil {
+				t.Parallel()
+				browsers <- struct{}{}
+				t.Cleanup(func() { <-browsers })
+			}
+			runBrowser(t, b, tests)
+		})
+	}
+}
+
+// runBrowser runs tests on the browser b.
+func runBrowser(t *testing.T, b Browser, tests []Test) {
+	url := b.URL
+	if b.Launch != nil {
+		var (
+			stop func() error
+			err  error
+		)
+		url, stop, err = b.Launch()
+		if errors.Is(err, exec.ErrNotFound) || os.IsNotExist(err) {
+			t.Skipf("Skipping %s tests because the WebDriver server is not installed: %v", b.Name, err)
+		}
+		if err != nil {
+			t.Fatalf("Starting the WebDriver server returned error: %v", err)
+		}
+		t.Cleanup(func() {
+			if err := stop(); err != nil {
+				t.Errorf("Stopping the WebDriver server returned error: %v", err)
+			}
+		})
+	}
+	opts := b.SessionOptions
+	if url != "" {
+		opts = append([]SessionOption{URL(url)}, opts...)
+	}
+
+	var sessions chan struct{}
+	if b.MaxSessions > 1 {
+		sessions = make(chan struct{}, b.MaxSessions)
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			if reason, ok := b.Skip[test.Name]; ok {
+				t.Skipf("Skipping on %s: %s", b.Name, reason)
+			}
+			if sessions != nil {
+				t.Parallel()
+				sessions <- struct{}{}
+				// Registered first, so that it runs after the session is quit.
+				t.Cleanup(func() { <-sessions })
+			}
+			wd := NewSession(t, b.Capabilities, opts...)
+			test.F(t, wd)
+		})
+	}
+}
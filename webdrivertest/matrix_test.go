@@ -0,0 +1,95 @@
+package webdrivertest
+
+import (
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tebeka/selenium"
+)
+
+// fakeLauncher returns a Launcher that starts a poolServer and records
+// whether it was stopped.
+func fakeLauncher(t *testing.T, servers *[]*poolServer, stopped *int) Launcher {
+	return func() (string, func() error, error) {
+		s := newPoolServer(t)
+		*servers = append(*servers, s)
+		return s.URL, func() error {
+			s.Close()
+			*stopped++
+			return nil
+		}, nil
+	}
+}
+
+func TestMatrix(t *testing.T) {
+	var (
+		servers []*poolServer
+		stopped int
+
+		mu            sync.Mutex
+		ran           []string
+		running, peak int
+	)
+	test := func(name string) Test {
+		return Test{Name: name, F: func(t *testing.T, wd selenium.WebDriver) {
+			mu.Lock()
+			ran = append(ran, t.Name())
+			running++
+			if running > peak {
+				peak = running
+			}
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}}
+	}
+
+	m := &Matrix{Browsers: []Browser{
+		{
+			Name:         "chrome",
+			Capabilities: selenium.Capabilities{"browserName": "chrome"},
+			Launch:       fakeLauncher(t, &servers, &stopped),
+			MaxSessions:  2,
+		},
+		{
+			Name:         "htmlunit",
+			Capabilities: selenium.Capabilities{"browserName": "htmlunit"},
+			Launch:       fakeLauncher(t, &servers, &stopped),
+			Skip:         map[string]string{"Drag": "no mouse support"},
+		},
+		{
+			Name: "missing",
+			Launch: func() (string, func() error, error) {
+				return "", nil, &exec.Error{Name: "chromedriver", Err: exec.ErrNotFound}
+			},
+		},
+	}}
+	t.Run("Matrix", func(t *testing.T) {
+		m.Run(t, test("Login"), test("Search"), test("Drag"))
+	})
+
+	if len(servers) != 2 || stopped != 2 {
+		t.Fatalf("%d servers were started and %d stopped, want 2 of each", len(servers), stopped)
+	}
+	if n := servers[0].created(); n != 3 {
+		t.Errorf("%d chrome sessions were created, want 3", n)
+	}
+	if n := servers[1].created(); n != 2 {
+		t.Errorf("%d htmlunit sessions were created, want 2", n)
+	}
+	if len(ran) != 5 {
+		t.Errorf("ran tests %q, want 5 tests", ran)
+	}
+	for _, name := range ran {
+		if name == "TestMatrix/Matrix/htmlunit/Drag" {
+			t.Errorf("the skipped test %s ran", name)
+		}
+	}
+	if peak > 2 {
+		t.Errorf("%d tests ran concurrently, want at most 2", peak)
+	}
+}
@@ -0,0 +1,369 @@
+package webdrivertest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/tebeka/selenium"
+)
+
+// ErrPoolClosed is returned by the methods of a Pool after it is closed.
+var ErrPoolClosed = errors.New("the session pool is closed")
+
+// PoolOptions configures a Pool.
+type PoolOptions struct {
+	// Size is the maximum number of sessions per capabilities set. If zero,
+	// one session is kept per capabilities set.
+	Size int
+	// URL is the URL of the WebDriver server. If empty, the URL in the
+	// SELENIUM_URL environment variable is used, or selenium.DefaultURLPrefix
+	// if it is not set.
+	URL string
+	// RemoteOptions are passed to selenium.NewRemote.
+	RemoteOptions []selenium.RemoteOption
+}
+
+// Pool keeps warm WebDriver sessions for reuse by tests, to avoid starting a
+// browser for each test. Sessions are grouped by capabilities: Acquire hands
+// out an idle session with the requested capabilities, starting one if fewer
+// than Size exist, and Release returns it to the pool after resetting its
+// state.
+//
+// Resetting closes all windows but one, clears the local and session storage
+// of the current page, deletes the cookies visible from it and navigates to
+// about:blank. Storage and cookies of other origins are kept, so tests that
+// share a pool should not depend on their absence.
+//
+// A Pool is safe for concurrent use.
+type Pool struct {
+	opts PoolOptions
+
+	mu     sync.Mutex
+	sets   map[string]*poolSet
+	inUse  map[selenium.WebDriver]*poolSet
+	closed bool
+	// starting counts the sessions being started in the background.
+	starting sync.WaitGroup
+}
+
+// poolSet holds the sessions with the same capabilities.
+type poolSet struct {
+	caps selenium.Capabilities
+	idle []selenium.WebDriver
+	// size is the number of sessions that are idle, in use or being started.
+	size int
+	// wake is closed when a session is released or discarded.
+	wake chan struct{}
+}
+
+// NewPool returns an empty Pool. Sessions are started on demand by Acquire,
+// or in advance by Warm.
+func NewPool(opts PoolOptions) *Pool {
+	if opts.Size <= 0 {
+		opts.Size = 1
+	}
+	if opts.URL == "" {
+		opts.URL = os.Getenv(URLEnv)
+	}
+	return &Pool{
+		opts:  opts,
+		sets:  make(map[string]*poolSet),
+		inUse: make(map[selenium.WebDriver]*poolSet),
+	}
+}
+
+// set returns the set of sessions with the capabilities caps. p.mu must be
+// held.
+func (p *Pool) set(caps selenium.Capabilities) (*poolSet, error) {
+	key, err := json.Marshal(caps)
+	if err != nil {
+		return nil, fmt.Errorf("encoding the capabilities: %v", err)
+	}
+	s, ok := p.sets[string(key)]
+	if !ok {
+		s = &poolSet{caps: caps, wake: make(chan struct{})}
+		p.sets[string(key)] = s
+	}
+	return s, nil
+}
+
+// wakeUp wakes the callers of Acquire waiting for a session of s. p.mu must be
+// held.
+func (s *poolSet) wakeUp() {
+	close(s.wake)
+	s.wake = make(chan struct{})
+}
+
+// Warm starts sessions with the capabilities caps until the pool holds Size
+// of them. The sessions are started concurrently. If some of them cannot be
+// started, the first error is returned.
+func (p *Pool) Warm(caps selenium.Capabilities) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrPoolClosed
+	}
+	s, err := p.set(caps)
+	if err != nil {
+		p.mu.Unlock()
+		return err
+	}
+	n := p.opts.Size - s.size
+	s.size += n
+	p.starting.Add(n)
+	p.mu.Unlock()
+
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() { errs <- p.add(s) }()
+	}
+	var first error
+	for i := 0; i < n; i++ {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// add starts a session of s, which has already been counted in s.size, and
+// makes it idle. It must be called after p.starting.Add(1).
+func (p *Pool) add(s *poolSet) error {
+	defer p.starting.Done()
+	wd, err := selenium.NewRemote(s.caps, p.opts.URL, p.opts.RemoteOptions...)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	defer s.wakeUp()
+	if err != nil {
+		s.size--
+		return err
+	}
+	if p.closed {
+		s.size--
+		wd.Quit()
+		return ErrPoolClosed
+	}
+	s.idle = append(s.idle, wd)
+	return nil
+}
+
+// discard quits wd and removes it from s. If replace is set and the pool is
+// open, a new session is started in the background to take its place.
+func (p *Pool) discard(s *poolSet, wd selenium.WebDriver, replace bool) {
+	p.mu.Lock()
+	delete(p.inUse, wd)
+	if replace && !p.closed {
+		p.starting.Add(1)
+		go p.add(s)
+	} else {
+		s.size--
+		s.wakeUp()
+	}
+	p.mu.Unlock()
+	wd.Quit()
+}
+
+// healthCheck returns an error if the server of wd is unreachable or its
+// session has ended.
+func healthCheck(wd selenium.WebDriver) error {
+	if _, err := wd.Status(); err != nil {
+		return err
+	}
+	_, err := wd.CurrentURL()
+	return err
+}
+
+// clearStorageScript clears the storage of the current page. Pages such as
+// about:blank have no storage, and accessing it throws.
+const clearStorageScript = `
+try {
+  window.localStorage.clear();
+  window.sessionStorage.clear();
+} catch (e) {}
+`
+
+// reset restores the state of a session for its next use.
+func reset(wd selenium.WebDriver) error {
+	handles, err := wd.WindowHandles()
+	if err != nil {
+		return err
+	}
+	if len(handles) == 0 {
+		return errors.New("the session has no windows")
+	}
+	for _, h := range handles[1:] {
+		if err := wd.SwitchWindow(h); err != nil {
+			return err
+		}
+		// An empty name closes the current window without switching back to
+		// it afterwards.
+		if err := wd.CloseWindow(""); err != nil {
+			return err
+		}
+	}
+	if err := wd.SwitchWindow(handles[0]); err != nil {
+		return err
+	}
+	if _, err := wd.ExecuteScriptRaw(clearStorageScript, nil); err != nil {
+		return err
+	}
+	if err := wd.DeleteAllCookies(); err != nil {
+		return err
+	}
+	return wd.Get("about:blank")
+}
+
+// Acquire returns a session with the capabilities caps. It hands out an idle
+// session if one passes a health check, and starts a new session if fewer
+// than Size sessions with caps exist; otherwise it waits until one is
+// released or ctx is done. Sessions that fail the health check are quit and
+// replaced.
+//
+// The session must be returned to the pool with Release, and must not be
+// quit by the caller.
+func (p *Pool) Acquire(ctx context.Context, caps selenium.Capabilities) (selenium.WebDriver, error) {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, ErrPoolClosed
+		}
+		s, err := p.set(caps)
+		if err != nil {
+			p.mu.Unlock()
+			return nil, err
+		}
+
+		if n := len(s.idle); n > 0 {
+			wd := s.idle[n-1]
+			s.idle = s.idle[:n-1]
+			p.inUse[wd] = s
+			p.mu.Unlock()
+			if err := healthCheck(wd); err != nil {
+				p.discard(s, wd, false)
+				continue
+			}
+			return wd, nil
+		}
+
+		if s.size < p.opts.Size {
+			s.size++
+			p.mu.Unlock()
+			wd, err := selenium.NewRemote(s.caps, p.opts.URL, p.opts.RemoteOptions...)
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			if err != nil {
+				s.size--
+				s.wakeUp()
+				return nil, err
+			}
+			if p.closed {
+				s.size--
+				wd.Quit()
+				return nil, ErrPoolClosed
+			}
+			p.inUse[wd] = s
+			return wd, nil
+		}
+
+		wake := s.wake
+		p.mu.Unlock()
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Release resets the state of wd, which must have been returned by Acquire,
+// and returns it to the pool. If the reset fails, the session is quit and a
+// new one is started in the background to replace it, and the error is
+// returned. If the pool is closed, the session is quit.
+func (p *Pool) Release(wd selenium.WebDriver) error {
+	p.mu.Lock()
+	s, ok := p.inUse[wd]
+	closed := p.closed
+	p.mu.Unlock()
+	if !ok {
+		return errors.New("the session was not acquired from the pool")
+	}
+	if closed {
+		p.discard(s, wd, false)
+		return nil
+	}
+	if err := reset(wd); err != nil {
+		p.discard(s, wd, true)
+		return fmt.Errorf("resetting the session: %v", err)
+	}
+
+	p.mu.Lock()
+	delete(p.inUse, wd)
+	if p.closed {
+		s.size--
+		p.mu.Unlock()
+		wd.Quit()
+		return nil
+	}
+	s.idle = append(s.idle, wd)
+	s.wakeUp()
+	p.mu.Unlock()
+	return nil
+}
+
+// Session acquires a session with the capabilities caps for the test t, and
+// registers a cleanup function that releases it when the test ends. If the
+// WebDriver server cannot be reached or cannot create a session with the
+// requested browser, the test is skipped, as with NewSession.
+func (p *Pool) Session(t testing.TB, caps selenium.Capabilities) selenium.WebDriver {
+	t.Helper()
+	wd, err := p.Acquire(context.Background(), caps)
+	if unavailable(err) {
+		t.Skipf("Skipping test because the browser is not available: %v", err)
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("Acquiring a session returned error: %v", err)
+		return nil
+	}
+	t.Cleanup(func() {
+		if err := p.Release(wd); err != nil {
+			t.Logf("Releasing the session returned error: %v", err)
+		}
+	})
+	return wd
+}
+
+// Close quits the idle sessions and waits for the sessions being started to
+// be quit. Sessions in use are quit when they are released.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrPoolClosed
+	}
+	p.closed = true
+	var idle []selenium.WebDriver
+	for _, s := range p.sets {
+		idle = append(idle, s.idle...)
+		s.size -= len(s.idle)
+		s.idle = nil
+		s.wakeUp()
+	}
+	p.mu.Unlock()
+
+	var first error
+	for _, wd := range idle {
+		if err := wd.Quit(); err != nil && first == nil {
+			first = err
+		}
+	}
+	p.starting.Wait()
+	return first
+}
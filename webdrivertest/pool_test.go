@@ -0,0 +1,226 @@
+package webdrivertest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tebeka/selenium"
+)
+
+// poolServer is a fakeServer that creates sessions named "session-N",
+// whose commands succeed unless the session was killed.
+type poolServer struct {
+	*fakeServer
+
+	mu       sync.Mutex
+	sessions int
+	// dead sessions fail all commands.
+	dead map[string]bool
+	// failReset makes deleting the cookies of any session fail.
+	failReset bool
+}
+
+func newPoolServer(t *testing.T) *poolServer {
+	s := &poolServer{fakeServer: newFakeServer(t), dead: make(map[string]bool)}
+	s.reply("", "/status", map[string]interface{}{"ready": true})
+	s.handle("POST", "/session", func(*fakeRequest) (interface{}, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.sessions++
+		return map[string]interface{}{
+			"sessionId":    fmt.Sprintf("session-%d", s.sessions),
+			"capabilities": map[string]string{"browserName": "chrome"},
+		}, nil
+	})
+	s.handle("", "", func(r *fakeRequest) (interface{}, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		switch {
+		case s.dead[r.Session]:
+			return nil, &selenium.Error{Err: "invalid session id", HTTPCode: http.StatusNotFound}
+		case r.Path == "/window/handles":
+			return []string{"w1", "w2"}, nil
+		case r.Path == "/url" && r.Method == "GET":
+			return "http://example.com/", nil
+		case r.Path == "/cookie" && s.failReset:
+			return nil, &selenium.Error{Err: "unknown error"}
+		}
+		return nil, nil
+	})
+	return s
+}
+
+func (s *poolServer) kill(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dead[id] = true
+}
+
+func (s *poolServer) setFailReset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failReset = true
+}
+
+func (s *poolServer) created() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessions
+}
+
+func TestPool(t *testing.T) {
+	s := newPoolServer(t)
+	defer s.Close()
+
+	p := NewPool(PoolOptions{Size: 2, URL: s.URL})
+	caps := selenium.Capabilities{"browserName": "chrome"}
+	ctx := context.Background()
+
+	wd1, err := p.Acquire(ctx, caps)
+	if err != nil {
+		t.Fatalf("Acquire() returned error: %v", err)
+	}
+	wd2, err := p.Acquire(ctx, caps)
+	if err != nil {
+		t.Fatalf("Acquire() returned error: %v", err)
+	}
+	if wd1.SessionID() == wd2.SessionID() {
+		t.Fatalf("Acquire() returned the same session twice")
+	}
+
+	// The pool is full, so a third caller waits for a session.
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := p.Acquire(timeout, caps); err != context.DeadlineExceeded {
+		t.Errorf("Acquire() on a full pool returned error %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if err := p.Release(wd1); err != nil {
+		t.Fatalf("Release() returned error: %v", err)
+	}
+	want := []string{
+		"GET /window/handles",
+		"POST /window",
+		"DELETE /window",
+		"POST /window",
+		"POST /execute/sync",
+		"DELETE /cookie",
+		"POST /url",
+	}
+	if got := s.received(wd1.SessionID()); strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("Release() sent commands %q, want %q", got, want)
+	}
+	if err := p.Release(wd1); err == nil {
+		t.Errorf("Release() of a released session did not return an error")
+	}
+
+	got, err := p.Acquire(ctx, caps)
+	if err != nil {
+		t.Fatalf("Acquire() returned error: %v", err)
+	}
+	if got.SessionID() != wd1.SessionID() {
+		t.Errorf("Acquire() returned session %q, want the released session %q", got.SessionID(), wd1.SessionID())
+	}
+	if n := s.created(); n != 2 {
+		t.Errorf("%d sessions were created, want 2", n)
+	}
+
+	// Other capabilities have their own sessions.
+	firefox, err := p.Acquire(ctx, selenium.Capabilities{"browserName": "firefox"})
+	if err != nil {
+		t.Fatalf("Acquire() returned error: %v", err)
+	}
+	if n := s.created(); n != 3 {
+		t.Errorf("%d sessions were created, want 3", n)
+	}
+
+	for _, wd := range []selenium.WebDriver{got, firefox} {
+		if err := p.Release(wd); err != nil {
+			t.Fatalf("Release() returned error: %v", err)
+		}
+	}
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close() returned error: %v", err)
+	}
+	// The session in use when the pool was closed is quit when released.
+	if err := p.Release(wd2); err != nil {
+		t.Fatalf("Release() after Close() returned error: %v", err)
+	}
+	for _, id := range []string{"session-1", "session-2", "session-3"} {
+		cmds := s.received(id)
+		if len(cmds) == 0 || cmds[len(cmds)-1] != "DELETE /" {
+			t.Errorf("session %s was not quit; commands = %q", id, cmds)
+		}
+	}
+	if _, err := p.Acquire(ctx, caps); err != ErrPoolClosed {
+		t.Errorf("Acquire() after Close() returned error %v, want %v", err, ErrPoolClosed)
+	}
+}
+
+func TestPoolReplacesSessions(t *testing.T) {
+	s := newPoolServer(t)
+	defer s.Close()
+
+	p := NewPool(PoolOptions{URL: s.URL})
+	defer p.Close()
+	caps := selenium.Capabilities{"browserName": "chrome"}
+	if err := p.Warm(caps); err != nil {
+		t.Fatalf("Warm() returned error: %v", err)
+	}
+	if n := s.created(); n != 1 {
+		t.Fatalf("Warm() created %d sessions, want 1", n)
+	}
+
+	// A session that ended while idle fails the health check.
+	s.kill("session-1")
+	wd, err := p.Acquire(context.Background(), caps)
+	if err != nil {
+		t.Fatalf("Acquire() returned error: %v", err)
+	}
+	if got := wd.SessionID(); got != "session-2" {
+		t.Errorf("Acquire() returned session %q, want a new session", got)
+	}
+
+	// A session that fails the reset is replaced in the background.
+	s.setFailReset()
+	if err := p.Release(wd); err == nil {
+		t.Errorf("Release() with a failing reset did not return an error")
+	}
+	wd, err = p.Acquire(context.Background(), caps)
+	if err != nil {
+		t.Fatalf("Acquire() returned error: %v", err)
+	}
+	if got := wd.SessionID(); got != "session-3" {
+		t.Errorf("Acquire() returned session %q, want the replacement session", got)
+	}
+}
+
+func TestPoolSession(t *testing.T) {
+	s := newPoolServer(t)
+	defer s.Close()
+
+	p := NewPool(PoolOptions{URL: s.URL})
+	defer p.Close()
+	caps := selenium.Capabilities{"browserName": "chrome"}
+	ft := &fakeT{name: "TestPooled"}
+	wd := p.Session(ft, caps)
+	if wd == nil || ft.failed || ft.skipped {
+		t.Fatalf("Session() = %v, failed = %t, skipped = %t; want a session", wd, ft.failed, ft.skipped)
+	}
+	ft.cleanup()
+	if len(ft.logs) != 0 {
+		t.Errorf("releasing the session logged %q", ft.logs)
+	}
+	got, err := p.Acquire(context.Background(), caps)
+	if err != nil {
+		t.Fatalf("Acquire() returned error: %v", err)
+	}
+	if got.SessionID() != wd.SessionID() {
+		t.Errorf("Acquire() after the test returned session %q, want the released session %q", got.SessionID(), wd.SessionID())
+	}
+}
@@ -0,0 +1,130 @@
+package webdrivertest
+
+import (
+	"errors"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/tebeka/selenium"
+)
+
+// URLEnv is the environment variable that sets the default URL of the
+// WebDriver server used by NewSession.
+const URLEnv = "SELENIUM_URL"
+
+// SessionOption configures NewSession.
+type SessionOption func(*sessionConfig)
+
+type sessionConfig struct {
+	url          string
+	remoteOpts   []selenium.RemoteOption
+	artifacts    bool
+	artifactsDir string
+}
+
+// URL sets the URL of the WebDriver server. By default, the URL in the
+// SELENIUM_URL environment variable is used, or selenium.DefaultURLPrefix if
+// it is not set.
+func URL(url string) SessionOption {
+	return func(c *sessionConfig) {
+		c.url = url
+	}
+}
+
+// RemoteOptions sets options passed to selenium.NewRemote.
+func RemoteOptions(opts ...selenium.RemoteOption) SessionOption {
+	return func(c *sessionConfig) {
+		c.remoteOpts = append(c.remoteOpts, opts...)
+	}
+}
+
+// Artifacts makes the session capture artifacts to dir if the test fails,
+// as described by CaptureOnFailure.
+func Artifacts(dir string) SessionOption {
+	return func(c *sessionConfig) {
+		c.artifacts = true
+		c.artifactsDir = dir
+	}
+}
+
+// vendorNameKeys are the keys of the session name in the vendor-specific
+// capabilities of cloud providers.
+var vendorNameKeys = map[string]string{
+	"sauce:options":  "name",
+	"bstack:options": "sessionName",
+	"LT:Options":     "name",
+}
+
+// sessionCapabilities returns a copy of caps in which the session is named
+// name, unless it is already named.
+func sessionCapabilities(caps selenium.Capabilities, name string) selenium.Capabilities {
+	c := make(selenium.Capabilities, len(caps)+1)
+	for k, v := range caps {
+		c[k] = v
+	}
+	if _, ok := c["name"]; !ok {
+		c["name"] = name
+	}
+	for key, nameKey := range vendorNameKeys {
+		opts, ok := c[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		o := make(map[string]interface{}, len(opts)+1)
+		for k, v := range opts {
+			o[k] = v
+		}
+		if _, ok := o[nameKey]; !ok {
+			o[nameKey] = name
+		}
+		c[key] = o
+	}
+	return c
+}
+
+// unavailable reports whether err means that the WebDriver server is not
+// running or cannot provide the requested browser.
+func unavailable(err error) bool {
+	if selenium.HasErrorCode(err, "session not created") {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// NewSession starts a WebDriver session with the capabilities caps for the
+// test t, and registers a cleanup function that quits it when the test ends.
+// The session is named after the test, which cloud providers display.
+//
+// If the WebDriver server cannot be reached or cannot create a session with
+// the requested browser, the test is skipped. Other errors fail the test.
+//
+// Each call creates its own session and copy of caps, so it can be used by
+// parallel tests.
+func NewSession(t testing.TB, caps selenium.Capabilities, opts ...SessionOption) selenium.WebDriver {
+	t.Helper()
+	c := &sessionConfig{url: os.Getenv(URLEnv)}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	wd, err := selenium.NewRemote(sessionCapabilities(caps, t.Name()), c.url, c.remoteOpts...)
+	if unavailable(err) {
+		t.Skipf("Skipping test because the browser is not available: %v", err)
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("selenium.NewRemote() returned error: %v", err)
+		return nil
+	}
+	t.Cleanup(func() {
+		if err := wd.Quit(); err != nil {
+			t.Logf("Quitting the session returned error: %v", err)
+		}
+	})
+	if c.artifacts {
+		CaptureOnFailure(t, wd, c.artifactsDir)
+	}
+	return wd
+}
@@ -0,0 +1,113 @@
+package webdrivertest
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/tebeka/selenium"
+)
+
+// sessionServer is a fakeServer that records the capabilities of new
+// sessions.
+type sessionServer struct {
+	*fakeServer
+	// notCreated makes session creation fail.
+	notCreated bool
+
+	mu sync.Mutex
+	// caps and desired are the W3C and legacy capabilities of the sessions.
+	caps, desired []map[string]interface{}
+}
+
+func newSessionServer(t *testing.T, notCreated bool) *sessionServer {
+	s := &sessionServer{fakeServer: newFakeServer(t), notCreated: notCreated}
+	s.handle("POST", "/session", func(r *fakeRequest) (interface{}, error) {
+		var body struct {
+			Capabilities struct {
+				AlwaysMatch map[string]interface{}
+			}
+			DesiredCapabilities map[string]interface{}
+		}
+		r.decode(t, &body)
+		if s.notCreated {
+			return nil, &selenium.Error{Err: "session not created", Message: "cannot find Chrome binary"}
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.caps = append(s.caps, body.Capabilities.AlwaysMatch)
+		s.desired = append(s.desired, body.DesiredCapabilities)
+		return map[string]interface{}{
+			"sessionId":    "session-1",
+			"capabilities": map[string]string{"browserName": "chrome", "browserVersion": "80.0.1"},
+		}, nil
+	})
+	s.reply("DELETE", "/", nil)
+	return s
+}
+
+// deleted reports whether session-1 was deleted.
+func (s *sessionServer) deleted() bool {
+	for _, c := range s.received("session-1") {
+		if c == "DELETE /" {
+			return true
+		}
+	}
+	return false
+}
+
+func TestNewSession(t *testing.T) {
+	s := newSessionServer(t, false)
+	defer s.Close()
+
+	caps := selenium.Capabilities{
+		"browserName":   "chrome",
+		"sauce:options": map[string]interface{}{"build": "42"},
+	}
+	ft := &fakeT{name: "TestSearch/query"}
+	wd := NewSession(ft, caps, URL(s.URL))
+	if wd == nil || ft.failed || ft.skipped {
+		t.Fatalf("NewSession() = %v, failed = %t, skipped = %t; want a session", wd, ft.failed, ft.skipped)
+	}
+	if len(s.caps) != 1 {
+		t.Fatalf("%d sessions created, want 1", len(s.caps))
+	}
+	if got := s.desired[0]["name"]; got != "TestSearch/query" {
+		t.Errorf("session name = %v, want %q", got, "TestSearch/query")
+	}
+	sauce, _ := s.caps[0]["sauce:options"].(map[string]interface{})
+	if sauce["name"] != "TestSearch/query" || sauce["build"] != "42" {
+		t.Errorf("sauce:options = %v, want the build and the session name", sauce)
+	}
+	if _, ok := caps["name"]; ok {
+		t.Errorf("NewSession() modified the capabilities")
+	}
+	if _, ok := caps["sauce:options"].(map[string]interface{})["name"]; ok {
+		t.Errorf("NewSession() modified the vendor options")
+	}
+
+	if s.deleted() {
+		t.Fatalf("the session was quit before the test ended")
+	}
+	ft.cleanup()
+	if !s.deleted() {
+		t.Errorf("the session was not quit when the test ended")
+	}
+}
+
+func TestNewSessionSkips(t *testing.T) {
+	s := newSessionServer(t, true)
+	defer s.Close()
+
+	ft := &fakeT{name: "TestNotCreated"}
+	if wd := NewSession(ft, selenium.Capabilities{"browserName": "chrome"}, URL(s.URL)); wd != nil || !ft.skipped {
+		t.Errorf("NewSession() when the session cannot be created = %v, skipped = %t; want a skipped test", wd, ft.skipped)
+	}
+
+	// Nothing listens on the address of a closed server.
+	closed := newFakeServer(t)
+	closed.Close()
+	ft = &fakeT{name: "TestNoServer"}
+	if wd := NewSession(ft, selenium.Capabilities{"browserName": "chrome"}, URL(closed.URL)); wd != nil || !ft.skipped {
+		t.Errorf("NewSession() without a server = %v, skipped = %t; want a skipped test", wd, ft.skipped)
+	}
+}